@@ -0,0 +1,67 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"demo/coffeeshop/auth"
+)
+
+// MARK: Audit Trail
+
+// Entry is one change to the shop, who made it and when. The trail is a file of these, one JSON object per line,
+// so it can only be added to and is easy to grep
+type Entry struct {
+	Time      time.Time `json:"time"`
+	User      auth.User `json:"user"`
+	Action    string    `json:"action"` // menu.add, menu.price...
+	Detail    string    `json:"detail"`
+	RequestID string    `json:"requestId,omitempty"` // Set when the change came in over HTTP
+}
+
+var (
+	mu  sync.Mutex
+	out io.Writer = io.Discard // Nothing is kept until Open is called
+)
+
+// Open appends the trail to the file at path from now on. The returned func closes it
+func Open(path string) (func() error, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	SetOutput(f)
+	return func() error {
+		SetOutput(io.Discard)
+		return f.Close()
+	}, nil
+}
+
+// SetOutput sends the trail to w, tests use a bytes.Buffer
+func SetOutput(w io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+	out = w
+}
+
+// Record adds an entry to the trail. The change has already happened by now, so a failure here is for the caller
+// to log, not a reason to undo it
+func Record(e Entry) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false) // Keep "->" readable, this never goes near a browser
+	if err := enc.Encode(e); err != nil {
+		return err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	_, err := out.Write(b.Bytes())
+	return err
+}
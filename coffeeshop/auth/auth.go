@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// MARK: Roles
+
+// Role is what a member of staff does in the shop, which decides what they're allowed to change
+type Role string
+
+const (
+	Barista   Role = "barista"
+	ShiftLead Role = "shift-lead"
+	Manager   Role = "manager"
+)
+
+var roles = []Role{Barista, ShiftLead, Manager}
+
+// ParseRole accepts the role names with or without the dash ("shift lead" works too)
+func ParseRole(s string) (Role, error) {
+	r := Role(strings.ReplaceAll(strings.ToLower(strings.TrimSpace(s)), " ", "-"))
+	if !slices.Contains(roles, r) {
+		return "", fmt.Errorf("unknown role %q, expected barista, shift-lead or manager", s)
+	}
+	return r, nil
+}
+
+// Permission is something only some roles can do
+type Permission int
+
+const (
+	EditMenu      Permission = iota // Add and change items
+	ChangePrices                    // Change what an item costs
+	Refund                          // Give money back
+	ViewReports                     // Sales figures
+	ManageStaff                     // Add and remove accounts
+	CountDrawer                     // Open and close the cash drawer, and pay in and out of it
+	GiveDiscounts                   // Take money off an order
+	MakeDrinks                      // Mark orders ready
+)
+
+var permissionNames = []string{"edit the menu", "change prices", "give refunds", "view reports", "manage staff", "count the drawer", "give discounts", "make drinks"}
+
+func (p Permission) String() string {
+	if int(p) < len(permissionNames) {
+		return permissionNames[p]
+	}
+	return fmt.Sprintf("permission %d", int(p))
+}
+
+// What each role can do on top of taking orders, which everyone can
+var grants = map[Role][]Permission{
+	Barista:   {MakeDrinks},
+	ShiftLead: {Refund, ViewReports, CountDrawer, GiveDiscounts, MakeDrinks},
+	Manager:   {EditMenu, ChangePrices, Refund, ViewReports, ManageStaff, CountDrawer, GiveDiscounts, MakeDrinks},
+}
+
+// User is a member of staff, the part of the account that's safe to pass around (no password hash)
+type User struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+	Role     Role   `json:"role"`
+}
+
+// Can reports whether the user's role allows p
+func (u User) Can(p Permission) bool {
+	return slices.Contains(grants[u.Role], p)
+}
+
+func (u User) String() string {
+	return fmt.Sprintf("%v [%v]", u.Username, u.ID) // Same look as the user type in main.go
+}
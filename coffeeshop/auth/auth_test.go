@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPBKDF2(t *testing.T) {
+	// From RFC 7914 section 11
+	got := hex.EncodeToString(pbkdf2([]byte("passwd"), []byte("salt"), 1, 64))
+	expect := "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"
+	if got != expect {
+		t.Errorf("Got %v, expected %v", got, expect)
+	}
+}
+
+func TestPassword(t *testing.T) {
+	hash, err := HashPassword("flat white")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := CheckPassword(hash, "flat white"); !ok || err != nil {
+		t.Errorf("Right password: got %v, %v", ok, err)
+	}
+	if ok, _ := CheckPassword(hash, "long black"); ok {
+		t.Error("Wrong password was accepted")
+	}
+	if _, err := CheckPassword("plain text", "plain text"); err == nil {
+		t.Error("Expected an error for a hash we can't read")
+	}
+}
+
+func TestSessions(t *testing.T) {
+	s := NewStore(nil)
+	lead, err := s.Add("sam", "correct horse", ShiftLead)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Add("SAM", "another one", Barista); err == nil {
+		t.Error("Usernames should be unique whatever the case")
+	}
+	if _, err := s.Login("sam", "wrong horse"); err != ErrBadLogin {
+		t.Errorf("Wrong password: got %v, expected ErrBadLogin", err)
+	}
+	if _, err := s.Login("nobody", "wrong horse"); err != ErrBadLogin {
+		t.Errorf("No such user: got %v, expected ErrBadLogin", err)
+	}
+
+	token, _ := s.Issue(lead, time.Hour)
+	if u, err := s.Verify(token); err != nil || u != lead {
+		t.Errorf("Got %v, %v, expected %v", u, err, lead)
+	}
+
+	// Changing the ID to someone else's has to break the signature
+	forged := "2" + strings.TrimPrefix(token, "1")
+	if _, err := s.Verify(forged); err != ErrNoSession {
+		t.Errorf("Forged token: got %v, expected ErrNoSession", err)
+	}
+	expired, _ := s.Issue(lead, -time.Minute)
+	if _, err := s.Verify(expired); err != ErrNoSession {
+		t.Errorf("Expired token: got %v, expected ErrNoSession", err)
+	}
+	if _, err := NewStore(nil).Verify(token); err != ErrNoSession {
+		t.Errorf("Token from another key: got %v, expected ErrNoSession", err)
+	}
+}
+
+func TestCan(t *testing.T) {
+	tests := []struct {
+		role   Role
+		perm   Permission
+		expect bool
+	}{
+		{Barista, EditMenu, false},
+		{Barista, Refund, false},
+		{ShiftLead, Refund, true},
+		{ShiftLead, ChangePrices, false},
+		{Manager, ChangePrices, true},
+		{Manager, ViewReports, true},
+	}
+	for _, test := range tests {
+		if got := (User{Role: test.role}).Can(test.perm); got != test.expect {
+			t.Errorf("%v can %v: got %v, expected %v", test.role, test.perm, got, test.expect)
+		}
+	}
+}
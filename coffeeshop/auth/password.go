@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MARK: Passwords
+
+// Passwords are stored as PBKDF2-SHA256. The point is to be slow, so a stolen users file takes ages to crack
+const (
+	iterations = 210_000 // OWASP's 2023 advice for PBKDF2-SHA256
+	saltSize   = 16
+	keySize    = 32
+)
+
+var errBadHash = errors.New("stored password hash is not in a format we understand")
+
+// HashPassword returns the string to store for password, in the form pbkdf2-sha256$iterations$salt$key
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := pbkdf2([]byte(password), salt, iterations, keySize)
+	enc := base64.RawStdEncoding
+	return fmt.Sprintf("pbkdf2-sha256$%d$%v$%v", iterations, enc.EncodeToString(salt), enc.EncodeToString(key)), nil
+}
+
+// CheckPassword reports whether password matches a hash from HashPassword
+func CheckPassword(hash, password string) (bool, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 4 || parts[0] != "pbkdf2-sha256" {
+		return false, errBadHash
+	}
+	iter, err := strconv.Atoi(parts[1])
+	if err != nil || iter < 1 {
+		return false, errBadHash
+	}
+	enc := base64.RawStdEncoding
+	salt, err := enc.DecodeString(parts[2])
+	if err != nil {
+		return false, errBadHash
+	}
+	want, err := enc.DecodeString(parts[3])
+	if err != nil {
+		return false, errBadHash
+	}
+	got := pbkdf2([]byte(password), salt, iter, len(want))
+	return hmac.Equal(got, want), nil // Constant time, so the comparison doesn't leak how much matched
+}
+
+// pbkdf2 is PBKDF2 with HMAC-SHA256 from RFC 8018. Newer Go versions have crypto/pbkdf2, but our go.mod says 1.22
+func pbkdf2(password, salt []byte, iter, size int) []byte {
+	prf := hmac.New(sha256.New, password)
+	var out []byte
+	for block := uint32(1); len(out) < size; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write(binary.BigEndian.AppendUint32(nil, block))
+		u := prf.Sum(nil)
+		t := append([]byte(nil), u...)
+		for i := 1; i < iter; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		out = append(out, t...)
+	}
+	return out[:size]
+}
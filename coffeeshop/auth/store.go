@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// MARK: Accounts
+
+// account is a user as it's saved, with the password hash
+type account struct {
+	User
+	Hash string `json:"hash"`
+}
+
+// Store holds the staff accounts and the key that signs their sessions
+type Store struct {
+	mu       sync.RWMutex
+	path     string // Where Add saves to, empty for a store that only lives in memory
+	accounts []account
+	key      []byte
+}
+
+// ErrBadLogin doesn't say whether it was the name or the password, so it can't be used to find out who works here
+var ErrBadLogin = errors.New("wrong username or password")
+
+// NewStore makes an empty store that isn't saved anywhere. A nil key gets a random one, so sessions only last as
+// long as the program
+func NewStore(key []byte) *Store {
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			panic(err) // crypto/rand doesn't fail on any system we run on
+		}
+	}
+	return &Store{key: key}
+}
+
+// Open loads the accounts from a JSON file, a missing file is an empty store that Add will create
+func Open(path string, key []byte) (*Store, error) {
+	s := NewStore(key)
+	s.path = path
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &s.accounts); err != nil {
+		return nil, fmt.Errorf("reading %v: %w", path, err)
+	}
+	return s, nil
+}
+
+// Add makes a new account and saves the store
+func (s *Store) Add(username, password string, role Role) (User, error) {
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return User{}, errors.New("a username is needed")
+	}
+	if len(password) < 8 {
+		return User{}, errors.New("passwords need at least 8 characters")
+	}
+	hash, err := HashPassword(password)
+	if err != nil {
+		return User{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.find(username) >= 0 {
+		return User{}, fmt.Errorf("there's already an account called %q", username)
+	}
+	id := 1
+	for _, a := range s.accounts {
+		id = max(id, a.ID+1)
+	}
+	u := User{ID: id, Username: username, Role: role}
+	s.accounts = append(s.accounts, account{u, hash})
+	return u, s.save()
+}
+
+// dummyHash is what Login checks a password against when there's no such user. Made once, the first time
+var dummyHash = sync.OnceValue(func() string {
+	h, _ := HashPassword("no such user")
+	return h
+})
+
+// Login checks a username and password
+func (s *Store) Login(username, password string) (User, error) {
+	s.mu.RLock()
+	i := s.find(strings.TrimSpace(username))
+	var a account
+	if i >= 0 {
+		a = s.accounts[i]
+	}
+	s.mu.RUnlock()
+	if i < 0 {
+		// Check against something anyway, so no such user takes as long as a wrong password and the time
+		// doesn't give away who has an account
+		CheckPassword(dummyHash(), password)
+		return User{}, ErrBadLogin
+	}
+	ok, err := CheckPassword(a.Hash, password)
+	if err != nil {
+		return User{}, fmt.Errorf("account %v: %w", a.User, err)
+	}
+	if !ok {
+		return User{}, ErrBadLogin
+	}
+	return a.User, nil
+}
+
+// Users lists the accounts
+func (s *Store) Users() []User {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]User, len(s.accounts))
+	for i, a := range s.accounts {
+		out[i] = a.User
+	}
+	return out
+}
+
+// find is the index of the account, usernames don't care about case. Call with mu held
+func (s *Store) find(username string) int {
+	return slices.IndexFunc(s.accounts, func(a account) bool { return strings.EqualFold(a.Username, username) })
+}
+
+func (s *Store) byID(id int) (User, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, a := range s.accounts {
+		if a.ID == id {
+			return a.User, true
+		}
+	}
+	return User{}, false
+}
+
+// save writes the accounts with mu held. The file has password hashes in it, so only we can read it
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+	b, err := json.MarshalIndent(s.accounts, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, append(b, '\n'), 0o600)
+}
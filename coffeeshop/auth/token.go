@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MARK: Sessions
+
+// A session token is "id.expiry.signature". The signature is an HMAC of the rest with the store's key, so nobody
+// can make one up or change the id without the key. The role isn't in the token, it's looked up every time, so
+// demoting someone takes effect straight away
+
+// ErrNoSession is for a request without a valid token, it's a 401 rather than a 403
+var ErrNoSession = errors.New("not signed in")
+
+// Issue makes a session token for u that's good for ttl
+func (s *Store) Issue(u User, ttl time.Duration) (token string, expires time.Time) {
+	expires = time.Now().Add(ttl).Truncate(time.Second)
+	payload := fmt.Sprintf("%d.%d", u.ID, expires.Unix())
+	return payload + "." + s.sign(payload), expires
+}
+
+// Verify checks a token and returns who it belongs to, as they are now
+func (s *Store) Verify(token string) (User, error) {
+	payload, sig, ok := cut(token)
+	if !ok || !hmac.Equal([]byte(sig), []byte(s.sign(payload))) {
+		return User{}, ErrNoSession
+	}
+	idText, expText, _ := strings.Cut(payload, ".")
+	id, err1 := strconv.Atoi(idText)
+	exp, err2 := strconv.ParseInt(expText, 10, 64)
+	if err1 != nil || err2 != nil || time.Now().After(time.Unix(exp, 0)) {
+		return User{}, ErrNoSession
+	}
+	u, ok := s.byID(id)
+	if !ok {
+		return User{}, ErrNoSession // The account's gone
+	}
+	return u, nil
+}
+
+func (s *Store) sign(payload string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// cut splits the signature off the end
+func cut(token string) (payload, sig string, ok bool) {
+	i := strings.LastIndexByte(token, '.')
+	if i < 0 {
+		return "", "", false
+	}
+	return token[:i], token[i+1:], true
+}
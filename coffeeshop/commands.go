@@ -0,0 +1,839 @@
+package coffeeshop
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"demo/coffeeshop/audit"
+	"demo/coffeeshop/auth"
+	"demo/coffeeshop/logging"
+	menu "demo/coffeeshop/menu"
+	"demo/coffeeshop/money"
+	"demo/coffeeshop/order"
+	"demo/coffeeshop/payment"
+	"demo/coffeeshop/render"
+	"demo/coffeeshop/report"
+	"demo/coffeeshop/web"
+)
+
+// MARK: Subcommands
+
+// Exit codes, so scripts can tell a bad invocation apart from something going wrong
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+// errUsage means the command line itself was wrong, the FlagSet has already told the user why
+var errUsage = errors.New("usage")
+
+// signalError means we were asked to stop, the exit code follows the shell convention of 128 + the signal number
+type signalError struct {
+	sig os.Signal
+}
+
+func (e signalError) Error() string {
+	return "stopped by " + e.sig.String()
+}
+
+func (e signalError) code() int {
+	if s, ok := e.sig.(syscall.Signal); ok {
+		return 128 + int(s)
+	}
+	return exitError
+}
+
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+// Filled in by init because the help command needs to read the list it's part of
+var commands []command
+
+func init() {
+	commands = []command{
+		{"shell", "Start the interactive menu (the default)", runShell},
+		{"menu", "Work with the menu (menu list, menu search, menu add, menu rename, menu translate, menu settings)", runMenu},
+		{"serve", "Serve the menu over HTTP", runServe},
+		{"order", "Price an order and print its receipt", runOrder},
+		{"import", "Add or update items from a menu.txt, CSV or .menu file", runImport},
+		{"export", "Write the menu to stdout", runExport},
+		{"staff", "Manage staff accounts (staff list, staff add)", runStaff},
+		{"store", "Manage the stores and their prices (store list, store add, store price...)", runStore},
+		{"report", "Add up the orders for each store (report sales for what sold)", runReport},
+		{"refund", "Give back some or all of what an order was paid", runRefund},
+		{"drawer", "Open, count and close the cash drawer (drawer open, drawer close...)", runDrawer},
+		{"help", "Show this help", runHelp},
+	}
+}
+
+// Run picks the subcommand from args (usually os.Args[1:]) and returns the exit code for the process
+func Run(args []string) int {
+	var logCfg logging.Config
+	global := newGlobalFlags(&logCfg)
+	if err := global.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	args = global.Args()
+	closeLog, err := logging.Setup(logCfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "demo: %v\n", err)
+		return exitUsage
+	}
+	defer closeLog()
+
+	if len(args) == 0 {
+		args = []string{"shell"} // Plain 'go run .' still gets the interactive loop
+	}
+
+	i := slices.IndexFunc(commands, func(c command) bool { return c.name == args[0] })
+	if i < 0 {
+		fmt.Fprintf(os.Stderr, "demo: unknown command %q\n\n", args[0])
+		usage(os.Stderr)
+		return exitUsage
+	}
+
+	err = commands[i].run(args[1:])
+	var sigErr signalError
+	switch {
+	case errors.As(err, &sigErr):
+		return sigErr.code()
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.Is(err, errUsage):
+		return exitUsage
+	default:
+		// The menu's own errors get the same wording as in the shell, the rest are shown in full since whoever
+		// runs a command is the person who can fix a missing file or a bad flag
+		if msg, known := userMessage(err); known {
+			fmt.Fprintf(os.Stderr, "demo %v: %v\n", args[0], msg)
+		} else {
+			fmt.Fprintf(os.Stderr, "demo %v: %v\n", args[0], err)
+		}
+		return exitError
+	}
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: demo [global flags] <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-8s %v\n", c.name, c.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Global flags:")
+	fs := newGlobalFlags(&logging.Config{})
+	fs.SetOutput(w)
+	fs.PrintDefaults()
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Use 'demo <command> -h' for the flags of a command")
+}
+
+// newGlobalFlags are the flags that go before the command, for things every command does, like logging
+func newGlobalFlags(cfg *logging.Config) *flag.FlagSet {
+	fs := flag.NewFlagSet("demo", flag.ContinueOnError)
+	fs.Usage = func() { usage(fs.Output()) }
+	fs.Func("log-level", "least important log messages to keep: debug, info, warn or error (default info)", func(s string) (err error) {
+		cfg.Level, err = logging.ParseLevel(s)
+		return err
+	})
+	fs.StringVar(&cfg.Format, "log-format", "text", "log format: text or json")
+	fs.StringVar(&cfg.File, "log-file", "", "file to log to instead of stderr")
+	cfg.MaxSize = 10 << 20
+	fs.Func("log-max-size", "megabytes the log file can grow to before it's rotated, 0 to never rotate (default 10)", func(s string) error {
+		mb, err := strconv.ParseFloat(s, 64)
+		if err != nil || mb < 0 {
+			return fmt.Errorf("%q is not a size in megabytes", s)
+		}
+		cfg.MaxSize = int64(mb * (1 << 20))
+		return nil
+	})
+	fs.IntVar(&cfg.MaxBackups, "log-backups", 3, "rotated log files to keep")
+	return fs
+}
+
+// newFlags makes a FlagSet that reports errors instead of exiting, so Run stays in charge of the exit code
+func newFlags(name, args, about string) *flag.FlagSet {
+	fs := flag.NewFlagSet("demo "+name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: demo %v %v\n\n%v\n\nFlags:\n", name, args, about)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse wraps FlagSet.Parse so that bad flags come back as errUsage
+func parse(fs *flag.FlagSet, args []string) error {
+	err := fs.Parse(args)
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		return errUsage
+	}
+	return err
+}
+
+// Every command that touches the menu shares the same file flag
+func menuFile(fs *flag.FlagSet) *string {
+	return fs.String("file", "menu.json", "menu file to load and save")
+}
+
+// isSet reports whether the flag was given on the command line, rather than left at its default
+func isSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// formatFlag adds --format and points at the renderer it picks, text unless told otherwise
+func formatFlag(fs *flag.FlagSet) *render.Renderer {
+	r := render.Renderer(render.Text{})
+	fs.Func("format", "output format: "+strings.Join(render.Formats(), ", ")+" (default text)", func(s string) (err error) {
+		r, err = render.Format(s)
+		return err
+	})
+	return &r
+}
+
+// priceFlag collects repeated --price size=cost flags
+type priceFlag map[string]float64
+
+func (p priceFlag) String() string {
+	var b strings.Builder
+	for size, cost := range p {
+		fmt.Fprintf(&b, "%v=%.2f ", size, cost)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func (p priceFlag) Set(s string) error {
+	size, cost, ok := strings.Cut(s, "=")
+	size = strings.TrimSpace(size)
+	if !ok || size == "" {
+		return fmt.Errorf("%q should look like size=price", s)
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(cost), 64)
+	if err != nil {
+		return fmt.Errorf("%q is not a price", cost)
+	}
+	p[size] = f
+	return nil
+}
+
+// nutritionFlag collects repeated --nutrition size=calories,fat,carbs,sugar,protein flags
+type nutritionFlag map[string]menu.Nutrition
+
+func (n nutritionFlag) String() string {
+	var b strings.Builder
+	for size, facts := range n {
+		fmt.Fprintf(&b, "%v=%v ", size, facts)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func (n nutritionFlag) Set(s string) error {
+	size, facts, ok := strings.Cut(s, "=")
+	size = strings.TrimSpace(size)
+	if !ok || size == "" {
+		return fmt.Errorf("%q should look like size=calories,fat,carbs,sugar,protein", s)
+	}
+	f, err := menu.ParseNutrition(facts)
+	if err != nil {
+		return err
+	}
+	n[size] = f
+	return nil
+}
+
+// listFlag collects a repeated string flag, like --tag hot --tag vegan
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+func runHelp(args []string) error {
+	usage(os.Stdout)
+	return nil
+}
+
+func runShell(args []string) error {
+	fs := newFlags("shell", "[flags]", "Starts the interactive menu. Changes are saved when you quit.")
+	file := menuFile(fs)
+	store := storeFlag(fs)
+	lang := langFlag(fs)
+	trail := auditFile(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := menu.Load(*file); err != nil {
+		return err
+	}
+	if err := checkStore(*store); err != nil {
+		return err
+	}
+	shopStore, shopLang, shopAudit = *store, *lang, *trail
+
+	// Catch Ctrl-C and kill so we still get to save. Edits hold the menu lock, so Save waits for one that's halfway through
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	println("Hello, Gophers!")
+	fmt.Println("Hello, Gophers!")
+	done := make(chan error, 1)
+	go func() {
+		done <- Operate()
+	}()
+
+	select {
+	case err := <-done:
+		// Save even if reading stdin failed, whatever was added before that is still good
+		return errors.Join(err, menu.Save(*file))
+	case sig := <-sigs:
+		fmt.Println("\nSaving and quitting")
+		if err := menu.Save(*file); err != nil {
+			return err
+		}
+		return signalError{sig}
+	}
+}
+
+func runMenu(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: demo menu <list|search|add|rename|describe|translate|settings> [flags]")
+		return errUsage
+	}
+
+	switch args[0] {
+	case "list":
+		fs := newFlags("menu list", "[flags] [ITEM]", "Prints every item on the menu, or just ITEM.")
+		file := menuFile(fs)
+		store := storeFlag(fs)
+		lang := langFlag(fs)
+		r := formatFlag(fs)
+		if err := parse(fs, args[1:]); err != nil {
+			return err
+		}
+		if err := menu.Load(*file); err != nil {
+			return err
+		}
+		rr, err := inStore(*r, *store)
+		if err != nil {
+			return err
+		}
+		if fs.NArg() > 0 {
+			item, err := menu.LookupAt(*store, strings.Join(fs.Args(), " "))
+			if err != nil {
+				return err
+			}
+			return rr.Item(os.Stdout, menu.Localize([]menu.Item{item}, *lang)[0])
+		}
+		items, err := menu.StoreItems(*store)
+		if err != nil {
+			return err
+		}
+		return rr.Menu(os.Stdout, menu.Localize(items, *lang))
+
+	case "search":
+		fs := newFlags("menu search", "[flags] [TEXT]", "Prints the items matching TEXT and the filters, best match first.")
+		file := menuFile(fs)
+		store := storeFlag(fs)
+		lang := langFlag(fs)
+		r := formatFlag(fs)
+		var q menu.Query
+		var tags listFlag
+		fs.Float64Var(&q.MinPrice, "min", 0, "lowest price")
+		fs.Float64Var(&q.MaxPrice, "max", 0, "highest price")
+		fs.StringVar(&q.Size, "size", "", "only items sold in this size")
+		fs.BoolVar(&q.AvailableOnly, "available", false, "leave out sold out items")
+		fs.Var(&tags, "tag", "only items with this tag or diet, e.g. vegan (repeatable)")
+		fs.Func("exclude", "leave out items with these allergens, e.g. milk,nuts", func(s string) error {
+			a, err := menu.ParseAllergens(s)
+			q.Exclude |= a
+			return err
+		})
+		if err := parse(fs, args[1:]); err != nil {
+			return err
+		}
+		q.Text = strings.Join(fs.Args(), " ")
+		q.Tags = tags
+		q.Store = *store
+		if err := menu.Load(*file); err != nil {
+			return err
+		}
+		rr, err := inStore(*r, *store)
+		if err != nil {
+			return err
+		}
+		found := menu.Search(q)
+		if len(found) == 0 {
+			fmt.Fprintln(os.Stderr, "No items found")
+		}
+		return rr.Menu(os.Stdout, menu.Localize(found, *lang))
+
+	case "add":
+		fs := newFlags("menu add", "--name NAME [--price size=cost ...]", "Adds a new item to the menu.")
+		file := menuFile(fs)
+		trail := auditFile(fs)
+		name := fs.String("name", "", "name of the new item (required)")
+		prices := priceFlag{}
+		fs.Var(prices, "price", "a size and its price, e.g. small=3.10 (repeatable)")
+		var d menu.Details
+		var tags listFlag
+		fs.StringVar(&d.Category, "category", "", "category, e.g. coffee or tea")
+		fs.Var(&tags, "tag", "a tag, e.g. hot or vegan (repeatable)")
+		fs.BoolVar(&d.SoldOut, "sold-out", false, "add the item as sold out")
+		fs.Func("allergens", "allergens in the item, e.g. milk,nuts", func(s string) (err error) {
+			d.Allergens, err = menu.ParseAllergens(s)
+			return err
+		})
+		fs.Func("diet", "diets the item fits, e.g. vegan or vegetarian", func(s string) (err error) {
+			d.Diet, err = menu.ParseDiets(s)
+			return err
+		})
+		nutrition := nutritionFlag{}
+		fs.Var(nutrition, "nutrition", "a size and its nutrition, e.g. small=120,4.5,15,12,3 for kcal,fat,carbs,sugar,protein (repeatable)")
+		if err := parse(fs, args[1:]); err != nil {
+			return err
+		}
+		if strings.TrimSpace(*name) == "" {
+			fmt.Fprintln(fs.Output(), "--name is required")
+			fs.Usage()
+			return errUsage
+		}
+		if err := menu.Load(*file); err != nil {
+			return err
+		}
+		d.Tags = tags
+		d.Nutrition = nutrition
+		if err := menu.Add(*name, prices, d); err != nil {
+			return err
+		}
+		if err := menu.Save(*file); err != nil {
+			return err
+		}
+		item, _ := menu.Lookup(*name)
+		return recordChange(*trail, "menu.add", fmt.Sprintf("%v (%v)", item.Name, item.ID))
+
+	case "rename":
+		fs := newFlags("menu rename", "[flags] ITEM NEW-NAME", "Renames ITEM (its name or ID). Its ID stays the same, so past orders still find it.")
+		file := menuFile(fs)
+		trail := auditFile(fs)
+		if err := parse(fs, args[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() != 2 {
+			fs.Usage()
+			return errUsage
+		}
+		if err := menu.Load(*file); err != nil {
+			return err
+		}
+		old, err := menu.Rename(fs.Arg(0), fs.Arg(1))
+		if err != nil {
+			return err
+		}
+		if err := menu.Save(*file); err != nil {
+			return err
+		}
+		item, _ := menu.Lookup(fs.Arg(1))
+		return recordChange(*trail, "menu.rename", fmt.Sprintf("%v: %v -> %v", item.ID, old, item.Name))
+
+	case "describe":
+		return runDescribe(args[1:])
+
+	case "translate":
+		return runTranslate(args[1:])
+
+	case "settings":
+		fs := newFlags("menu settings", "[flags]", "Changes the shop's menu settings.")
+		file := menuFile(fs)
+		trail := auditFile(fs)
+		require := fs.Bool("require-nutrition", false, "every priced size needs nutrition facts")
+		cur := fs.String("currency", "", "what the menu's prices are in, one of "+strings.Join(money.Currencies(), ", ")+", or none for plain numbers")
+		loc := fs.String("locale", "", "how prices are written, one of "+strings.Join(money.Locales(), ", ")+" (default the currency's usual)")
+		tax := fs.Float64("tax-rate", 0, "sales tax the prices include, 0.2 for 20%")
+		sizes := map[string][]string{}
+		fs.Func("require-sizes", "sizes every item in a category needs, e.g. coffee=small,medium,large, or coffee= to lift it (repeatable)", func(s string) error {
+			category, list, ok := strings.Cut(s, "=")
+			if !ok || strings.TrimSpace(category) == "" {
+				return fmt.Errorf("%q should look like category=size,size", s)
+			}
+			sizes[category] = nil
+			for _, size := range strings.Split(list, ",") {
+				if size = strings.TrimSpace(size); size != "" {
+					sizes[category] = append(sizes[category], size)
+				}
+			}
+			return nil
+		})
+		if err := parse(fs, args[1:]); err != nil {
+			return err
+		}
+		if err := menu.Load(*file); err != nil {
+			return err
+		}
+
+		// Only the settings that were given change
+		var changes []string
+		if isSet(fs, "require-nutrition") {
+			if err := menu.SetRequireNutrition(*require); err != nil {
+				return err
+			}
+			changes = append(changes, fmt.Sprintf("require nutrition %v", *require))
+		}
+		if isSet(fs, "currency") || isSet(fs, "locale") {
+			if !isSet(fs, "currency") {
+				*cur, _ = menu.Currency() // Just the locale changes
+			}
+			if strings.EqualFold(*cur, "none") {
+				*cur = ""
+			}
+			if err := menu.SetCurrency(*cur, *loc); err != nil {
+				return err
+			}
+			f, _ := menu.Money("")
+			changes = append(changes, fmt.Sprintf("prices in %v, like %v", cmp.Or(f.String(), "plain numbers"), f.Amount(1234.5)))
+		}
+		if isSet(fs, "tax-rate") {
+			if err := menu.SetTaxRate(*tax); err != nil {
+				return err
+			}
+			changes = append(changes, fmt.Sprintf("tax rate %v%%", *tax*100))
+		}
+		for category, list := range sizes {
+			if err := menu.SetRequiredSizes(category, list); err != nil {
+				return err
+			}
+			changes = append(changes, fmt.Sprintf("require %v sizes %v", category, strings.Join(list, ",")))
+		}
+		if len(changes) == 0 {
+			fs.Usage()
+			return errUsage
+		}
+		if err := menu.Save(*file); err != nil {
+			return err
+		}
+		return recordChange(*trail, "menu.settings", strings.Join(changes, "; "))
+
+	default:
+		fmt.Fprintf(os.Stderr, "demo menu: unknown command %q\n", args[0])
+		return errUsage
+	}
+}
+
+func runServe(args []string) error {
+	fs := newFlags("serve", "[flags]",
+		"Serves the menu over HTTP until the process gets SIGINT or SIGTERM, then finishes the requests in flight.\n"+
+			"Settings come from the defaults, then the --config file, then any flags given.")
+	file := menuFile(fs)
+	users := usersFile(fs)
+	trail := auditFile(fs)
+	orders := ordersFile(fs)
+	payments := paymentsFile(fs)
+	config := fs.String("config", "", "JSON config file for the server")
+	watch := fs.Duration("watch", 2*time.Second, "how often to check the menu file for changes, 0 to never")
+	closeAt := fs.String("close-at", "", "time of day the shop closes, like 18:30, to write the day's sales report then")
+	reports := fs.String("reports", "reports", "directory the closing sales reports go in")
+	cfg := web.DefaultConfig()
+	var flags web.Config // Only the flags that were actually given get copied over
+	fs.StringVar(&flags.Addr, "addr", cfg.Addr, "address to listen on")
+	fs.DurationVar(&flags.ReadTimeout, "read-timeout", cfg.ReadTimeout, "time allowed to read a whole request")
+	fs.DurationVar(&flags.ReadHeaderTimeout, "read-header-timeout", cfg.ReadHeaderTimeout, "time allowed to read the request headers")
+	fs.DurationVar(&flags.WriteTimeout, "write-timeout", cfg.WriteTimeout, "time allowed to write a response")
+	fs.DurationVar(&flags.IdleTimeout, "idle-timeout", cfg.IdleTimeout, "how long an idle keep-alive connection stays open")
+	fs.IntVar(&flags.MaxHeaderBytes, "max-header-bytes", cfg.MaxHeaderBytes, "largest request headers accepted")
+	fs.DurationVar(&flags.ShutdownTimeout, "shutdown-timeout", cfg.ShutdownTimeout, "time in-flight requests get to finish when stopping")
+	fs.DurationVar(&flags.SessionTTL, "session-ttl", cfg.SessionTTL, "how long a staff sign in lasts")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	var at time.Duration
+	if *closeAt != "" {
+		var err error
+		if at, err = report.ParseClock(*closeAt); err != nil {
+			fmt.Fprintln(fs.Output(), err)
+			return errUsage
+		}
+	}
+	if *config != "" {
+		if err := web.LoadConfig(*config, &cfg); err != nil {
+			return err
+		}
+	}
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "addr":
+			cfg.Addr = flags.Addr
+		case "read-timeout":
+			cfg.ReadTimeout = flags.ReadTimeout
+		case "read-header-timeout":
+			cfg.ReadHeaderTimeout = flags.ReadHeaderTimeout
+		case "write-timeout":
+			cfg.WriteTimeout = flags.WriteTimeout
+		case "idle-timeout":
+			cfg.IdleTimeout = flags.IdleTimeout
+		case "max-header-bytes":
+			cfg.MaxHeaderBytes = flags.MaxHeaderBytes
+		case "shutdown-timeout":
+			cfg.ShutdownTimeout = flags.ShutdownTimeout
+		case "session-ttl":
+			cfg.SessionTTL = flags.SessionTTL
+		}
+	})
+	if err := menu.Load(*file); err != nil {
+		return err
+	}
+	store, err := auth.Open(*users, sessionKey())
+	if err != nil {
+		return err
+	}
+	cfg.Users = store
+	cfg.MenuFile = *file
+	closeAudit, err := audit.Open(*trail)
+	if err != nil {
+		return fmt.Errorf("opening the audit trail: %w", err)
+	}
+	defer closeAudit()
+	cfg.OrdersFile = *orders
+	closeLedger, err := order.OpenLedger(*orders)
+	if err != nil {
+		return fmt.Errorf("opening the order ledger: %w", err)
+	}
+	defer closeLedger()
+	if cfg.Till, err = payment.OpenTill(*payments); err != nil {
+		return fmt.Errorf("opening the till: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if *watch > 0 {
+		go menu.Watch(ctx, *file, *watch)
+	}
+	if *closeAt != "" {
+		go report.Daily(ctx, at, func(close time.Time) {
+			if err := closeDay(*orders, *reports, close); err != nil {
+				slog.Error("writing the closing sales report", "error", err)
+			}
+		})
+	}
+	fmt.Fprintf(os.Stderr, "Serving the menu on %v\n", cfg.Addr)
+	if err := web.Serve(ctx, cfg); err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, "Stopped")
+	return nil
+}
+
+func runOrder(args []string) error {
+	fs := newFlags("order", "[flags] ITEM:SIZE[:MODIFIER...] ...",
+		"Prices each line and prints the receipt, e.g. demo order Coffee:large:'oat milk':'extra shot'\nModifiers: "+strings.Join(menu.Modifiers(), ", ")+
+			"\nPay with --pay, splitting it over several if need be: demo order --pay cash:5 --pay card:4242424242424242 Latte:large")
+	file := menuFile(fs)
+	store := storeFlag(fs)
+	orders := ordersFile(fs)
+	payments := paymentsFile(fs)
+	r := formatFlag(fs)
+	ticket := fs.Bool("ticket", false, "print the barista's ticket instead of the receipt")
+	discount := fs.Float64("discount", 0, "take this much off the whole order")
+	var pay listFlag
+	fs.Var(&pay, "pay", "a payment: cash, cash:TENDERED, card:NUMBER or card:NUMBER:AMOUNT (repeatable, in order)")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errUsage
+	}
+	var tenders []order.Payment
+	for _, p := range pay {
+		t, err := parseTender(p)
+		if err != nil {
+			fmt.Fprintln(fs.Output(), err)
+			return errUsage
+		}
+		tenders = append(tenders, t)
+	}
+	if err := menu.Load(*file); err != nil {
+		return err
+	}
+	rr, err := inStore(*r, *store)
+	if err != nil {
+		return err
+	}
+	closeLedger, err := order.OpenLedger(*orders)
+	if err != nil {
+		return fmt.Errorf("opening the order ledger: %w", err)
+	}
+	defer closeLedger()
+
+	o := order.Order{Store: *store}
+	for _, arg := range fs.Args() {
+		parts := strings.Split(arg, ":")
+		if len(parts) < 2 {
+			fmt.Fprintf(fs.Output(), "%q should look like ITEM:SIZE\n", arg)
+			return errUsage
+		}
+		if err := o.Add(parts[0], parts[1], parts[2:], 1); err != nil {
+			return err
+		}
+	}
+	if *discount != 0 {
+		if err := o.SetDiscount(*discount); err != nil {
+			return err
+		}
+	}
+	ctx := context.Background()
+	if len(tenders) > 0 {
+		till, err := payment.OpenTill(*payments)
+		if err != nil {
+			return err
+		}
+		f, _ := menu.Money(o.Store) // The store was checked by inStore
+		err = o.PayInFull(ctx, tenders, func(name string) (payment.PaymentProvider, error) {
+			return till.Provider(name, o.Store, f.Currency)
+		})
+		if err := errors.Join(err, till.Save()); err != nil {
+			return err
+		}
+		giveChange(o, f)
+	}
+	o.Place(ctx)
+	if *ticket {
+		return rr.Order(os.Stdout, o)
+	}
+	return rr.Receipt(os.Stdout, o)
+}
+
+func runImport(args []string) error {
+	fs := newFlags("import", "[flags] FILE", "Adds the items listed in FILE (one name per line) to the menu. A CSV or .menu file, like\n"+
+		"the ones export writes, sets everything about every item in it.")
+	file := menuFile(fs)
+	trail := auditFile(fs)
+	format := fs.String("format", "", "text, csv or menu (default from the file name)")
+	dryRun := fs.Bool("dry-run", false, "show what would change without changing anything")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errUsage
+	}
+	if *format == "" {
+		*format = "text"
+		if ext := strings.ToLower(filepath.Ext(fs.Arg(0))); ext == ".csv" || ext == ".menu" {
+			*format = ext[1:]
+		}
+	}
+	read, ok := map[string]func(io.Reader) (*menu.Import, error){"csv": menu.ReadCSV, "menu": menu.ReadMenuFile}[*format]
+	if !ok && *format != "text" {
+		fmt.Fprintf(fs.Output(), "Unknown format %q, expected text, csv or menu\n", *format)
+		return errUsage
+	}
+	if err := menu.Load(*file); err != nil {
+		return err
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if ok {
+		return importFile(read, f, *file, *trail, fs.Arg(0), *dryRun)
+	}
+	if *dryRun {
+		fmt.Fprintln(fs.Output(), "--dry-run only works with CSV and .menu files")
+		return errUsage
+	}
+	n, err := menu.ImportText(f)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Imported %v new items\n", n)
+	if err := menu.Save(*file); err != nil {
+		return err
+	}
+	return recordChange(*trail, "menu.import", fmt.Sprintf("%v new items from %v", n, fs.Arg(0)))
+}
+
+// importFile checks a file with read, shows what it changes and puts it on the menu unless it's a dry run
+func importFile(read func(io.Reader) (*menu.Import, error), r io.Reader, file, trail, name string, dryRun bool) error {
+	imp, err := read(r)
+	if err != nil {
+		return err
+	}
+	if len(imp.Changes) == 0 {
+		fmt.Println("Nothing to change, the menu already matches")
+		return nil
+	}
+	for _, c := range imp.Changes {
+		fmt.Println(c)
+	}
+	if dryRun {
+		fmt.Printf("%v items would change, run it again without --dry-run to apply\n", len(imp.Changes))
+		return nil
+	}
+	if err := imp.Apply(); err != nil {
+		return err
+	}
+	fmt.Printf("Changed %v items\n", len(imp.Changes))
+	if err := menu.Save(file); err != nil {
+		return err
+	}
+	return recordChange(trail, "menu.import", fmt.Sprintf("%v items changed from %v", len(imp.Changes), name))
+}
+
+func runExport(args []string) error {
+	fs := newFlags("export", "[flags]", "Writes the whole menu to stdout.")
+	file := menuFile(fs)
+	format := fs.String("format", "text", "output format: csv, menu (to edit and import again), "+strings.Join(render.Formats(), ", "))
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := menu.Load(*file); err != nil {
+		return err
+	}
+
+	switch *format {
+	case "csv":
+		return menu.WriteCSV(os.Stdout)
+	case "menu":
+		return menu.WriteMenuFile(os.Stdout)
+	}
+	r, err := render.Format(*format)
+	if err != nil {
+		fmt.Fprintln(fs.Output(), err)
+		return errUsage
+	}
+	return r.Menu(os.Stdout, menu.Items())
+}
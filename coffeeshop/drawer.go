@@ -0,0 +1,223 @@
+package coffeeshop
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	menu "demo/coffeeshop/menu"
+	"demo/coffeeshop/money"
+	"demo/coffeeshop/payment"
+	"demo/coffeeshop/report"
+)
+
+// MARK: Drawer
+
+func runDrawer(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: demo drawer <open|pay-in|pay-out|status|close|report> [flags]")
+		return errUsage
+	}
+
+	// Every command is the same shape: load the menu for the store's currency, open the till, do it, save it
+	var format *string // status, close and report
+	var all *bool      // report
+	run := func(name, usage, about string, minArgs, maxArgs int, do func(fs *flag.FlagSet, cash *payment.Cash, store menu.Store) (string, error)) error {
+		fs := newFlags("drawer "+name, usage, about)
+		file := menuFile(fs)
+		store := storeFlag(fs)
+		payments := paymentsFile(fs)
+		trail := auditFile(fs)
+		switch name {
+		case "status", "close", "report":
+			format = fs.String("format", "text", "text (for the receipt printer), json or csv")
+		}
+		if name == "report" {
+			all = fs.Bool("all", false, "every closed session, not just the last")
+		}
+		if err := parse(fs, args[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() < minArgs || maxArgs >= 0 && fs.NArg() > maxArgs {
+			fs.Usage()
+			return errUsage
+		}
+		if format != nil && *format != "text" && *format != "json" && *format != "csv" {
+			fmt.Fprintf(fs.Output(), "Unknown format %q, expected text, json or csv\n", *format)
+			return errUsage
+		}
+		if err := menu.Load(*file); err != nil {
+			return err
+		}
+		s, err := menu.LookupStore(*store)
+		if err != nil {
+			return err
+		}
+		f, _ := menu.Money(*store)
+		till, err := payment.OpenTill(*payments)
+		if err != nil {
+			return err
+		}
+		detail, err := do(fs, till.Cash(*store, f.Currency), s)
+		if err != nil || detail == "" {
+			return err // Nothing changed, or nothing that needs keeping
+		}
+		if err := till.Save(); err != nil {
+			return err
+		}
+		return recordChange(*trail, "drawer."+name, strings.TrimSpace(*store+" "+detail))
+	}
+	amountArg := func(fs *flag.FlagSet) (float64, error) {
+		v, err := strconv.ParseFloat(fs.Arg(0), 64)
+		if err != nil || v < 0 || math.IsNaN(v) || math.IsInf(v, 0) { // Nothing goes in or out backwards
+			fmt.Fprintf(fs.Output(), "%q is not an amount\n", fs.Arg(0))
+			return 0, errUsage
+		}
+		return v, nil
+	}
+	by := localUser().Username
+
+	switch args[0] {
+	case "open":
+		return run("open", "[flags] FLOAT", "Opens the drawer for the day with FLOAT in it, cash can't be taken until it's open.", 1, 1,
+			func(fs *flag.FlagSet, cash *payment.Cash, _ menu.Store) (string, error) {
+				float, err := amountArg(fs)
+				if err != nil {
+					return "", err
+				}
+				s, err := cash.Open(float, by)
+				if err != nil {
+					return "", err
+				}
+				fmt.Printf("Drawer open with %v (%v)\n", amount(s, float), s.ID)
+				return fmt.Sprintf("%v with %v", s.ID, amount(s, float)), nil
+			})
+
+	case "pay-in", "pay-out":
+		about := map[string]string{
+			"pay-in":  "Puts cash in the drawer that isn't a sale, like change from the bank.",
+			"pay-out": "Takes cash out of the drawer that isn't a refund, like paying for milk.",
+		}[args[0]]
+		return run(args[0], "[flags] AMOUNT REASON", about, 2, 2, func(fs *flag.FlagSet, cash *payment.Cash, _ menu.Store) (string, error) {
+			v, err := amountArg(fs)
+			if err != nil {
+				return "", err
+			}
+			if args[0] == "pay-in" {
+				err = cash.PayIn(v, fs.Arg(1), by)
+			} else {
+				err = cash.PayOut(v, fs.Arg(1), by)
+			}
+			if err != nil {
+				return "", err
+			}
+			s, _ := cash.Session()
+			fmt.Printf("%v in the drawer\n", amount(s, s.Expected()))
+			return fmt.Sprintf("%v: %v", amount(s, v), fs.Arg(1)), nil
+		})
+
+	case "status":
+		return run("status", "[flags]", "Shows what should be in the drawer right now.", 0, 0,
+			func(fs *flag.FlagSet, cash *payment.Cash, store menu.Store) (string, error) {
+				s, open := cash.Session()
+				if !open {
+					return "", payment.ErrDrawerClosed
+				}
+				return "", writeVariance(*format, storeName(store), report.Drawer(s))
+			})
+
+	case "close":
+		return run("close", "[flags] NOTE=COUNT...", "Closes the drawer with what was counted in it, like 20=3 5=2 0.25=11, and prints\n"+
+			"the variance against what should be there.", 1, -1,
+			func(fs *flag.FlagSet, cash *payment.Cash, store menu.Store) (string, error) {
+				var counted []payment.Piece
+				for _, arg := range fs.Args() {
+					value, count, ok := strings.Cut(arg, "=")
+					v, err := strconv.ParseFloat(value, 64)
+					n, err2 := strconv.Atoi(count)
+					if !ok || err != nil || err2 != nil {
+						fmt.Fprintf(fs.Output(), "%q should look like NOTE=COUNT, like 20=3\n", arg)
+						return "", errUsage
+					}
+					counted = append(counted, payment.Piece{Value: v, Count: n})
+				}
+				s, err := cash.Close(counted, by)
+				if err != nil {
+					return "", err
+				}
+				v := report.Drawer(s)
+				if err := writeVariance(*format, storeName(store), v); err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("%v counted %v, %v %v", s.ID, amount(s, v.Counted), v.Status(), amount(s, v.Variance)), nil
+			})
+
+	case "report":
+		return run("report", "[flags] [SESSION]", "Prints a closed session's variance again, the last one unless SESSION says.", 0, 1,
+			func(fs *flag.FlagSet, cash *payment.Cash, store menu.Store) (string, error) {
+				history := cash.History()
+				if fs.NArg() > 0 {
+					i := slices.IndexFunc(history, func(s payment.Session) bool { return s.ID == fs.Arg(0) })
+					if i < 0 {
+						return "", fmt.Errorf("no closed session %v at this store", fs.Arg(0))
+					}
+					history = history[i : i+1]
+				} else if !*all && len(history) > 0 {
+					history = history[len(history)-1:]
+				}
+				if len(history) == 0 {
+					return "", errors.New("the drawer hasn't been closed yet")
+				}
+				vs := make([]report.Variance, len(history))
+				for i, s := range history {
+					vs[i] = report.Drawer(s)
+				}
+				return "", writeVariance(*format, storeName(store), vs...)
+			})
+
+	default:
+		fmt.Fprintf(os.Stderr, "demo drawer: unknown command %q\n", args[0])
+		return errUsage
+	}
+}
+
+// writeVariance prints sessions the way --format asks
+func writeVariance(format, name string, vs ...report.Variance) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if len(vs) == 1 {
+			return enc.Encode(vs[0])
+		}
+		return enc.Encode(vs)
+	case "csv":
+		return report.WriteDrawersCSV(os.Stdout, vs)
+	}
+	for i, v := range vs {
+		if i > 0 {
+			fmt.Println()
+		}
+		if err := v.WriteText(os.Stdout, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// amount writes v in the session's currency
+func amount(s payment.Session, v float64) string {
+	f, _ := money.New(s.Currency, "")
+	return f.Amount(v)
+}
+
+func storeName(s menu.Store) string {
+	return cmp.Or(s.Name, "(no store)")
+}
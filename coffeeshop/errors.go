@@ -0,0 +1,52 @@
+package coffeeshop
+
+import (
+	"errors"
+	"strings"
+
+	menu "demo/coffeeshop/menu"
+	"demo/coffeeshop/order"
+	"demo/coffeeshop/payment"
+)
+
+// MARK: Errors
+
+// userMessage is what to tell someone at the shell about err. The menu's own errors are written for people, so
+// they're shown as they are. Anything else is probably our fault and means nothing to them, so it should go in
+// the log and they get an apology instead
+func userMessage(err error) (msg string, known bool) {
+	var ve *menu.ValidationError
+	var ie *menu.ImportError
+	switch {
+	case errors.As(err, &ve) && err == error(ve):
+		var b strings.Builder
+		b.WriteString("That item isn't right:")
+		for _, p := range ve.Problems {
+			b.WriteString("\n  - " + p.Error())
+		}
+		return b.String(), true
+	case errors.As(err, &ve), errors.As(err, &ie):
+		// Problems with several items, or lines of an import, one per line
+		return "Please fix these and try again:\n  - " + strings.ReplaceAll(err.Error(), "\n", "\n  - "), true
+	case errors.Is(err, menu.ErrDuplicateItem), errors.Is(err, menu.ErrItemNotFound), errors.Is(err, menu.ErrStoreNotFound), errors.Is(err, menu.ErrUnavailable), errors.Is(err, menu.ErrMenuChanged):
+		return "Sorry, " + err.Error(), true
+	case paymentProblem(err):
+		// The customer is standing there, so say what happened with their money
+		return "Sorry, " + err.Error(), true
+	default:
+		return "Sorry, something went wrong. The details are in the log", false
+	}
+}
+
+// paymentProblem is a payment that didn't go through for a reason the till can do something about
+func paymentProblem(err error) bool {
+	for _, target := range []error{payment.ErrDeclined, payment.ErrTimeout, payment.ErrInvalidCard, payment.ErrNotEnough,
+		payment.ErrNoChange, payment.ErrOverRefund, payment.ErrKeyReused, payment.ErrUnknownMethod, payment.ErrBadAmount,
+		payment.ErrDrawerClosed, payment.ErrDrawerOpen, payment.ErrNoReason, payment.ErrNotCash, order.ErrOverpaid,
+		order.ErrUnderpaid, order.ErrBadDiscount} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+package i18n
+
+import (
+	"cmp"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// MARK: Languages
+
+// Default is the language the shop is run in, and what every lookup falls back to
+const Default = "en"
+
+// Normalize tidies a language tag: lower case, - not _, and no encoding, so fr_CA.UTF-8 is fr-ca
+func Normalize(tag string) string {
+	tag, _, _ = strings.Cut(strings.TrimSpace(tag), ".")
+	return strings.ToLower(strings.ReplaceAll(tag, "_", "-"))
+}
+
+// Fallbacks are the tags to try for a language, most specific first: fr-ca, then fr
+func Fallbacks(tag string) []string {
+	tag = Normalize(tag)
+	var out []string
+	for tag != "" {
+		out = append(out, tag)
+		i := strings.LastIndex(tag, "-")
+		if i < 0 {
+			break
+		}
+		tag = tag[:i]
+	}
+	return out
+}
+
+// Valid is whether tag looks like a language tag: letters, digits and -, starting with a 2 or 3 letter language
+func Valid(tag string) bool {
+	parts := strings.Split(Normalize(tag), "-")
+	if n := len(parts[0]); n < 2 || n > 3 || strings.ContainsFunc(parts[0], func(r rune) bool { return r < 'a' || r > 'z' }) {
+		return false
+	}
+	for _, p := range parts[1:] {
+		if p == "" || len(p) > 8 || strings.ContainsFunc(p, func(r rune) bool { return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9') }) {
+			return false
+		}
+	}
+	return true
+}
+
+// Match picks the language to answer in from an Accept-Language header, out of the ones we have. A header asking
+// for fr-CA gets fr if that's all we have. It's "" when nothing the header asks for is there
+func Match(accept string, have []string) string {
+	type option struct {
+		tag   string
+		q     float64
+		order int
+	}
+	var best []option
+	for i, part := range strings.Split(accept, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if k, v, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(k) == "q" {
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				q = f
+			}
+		}
+		if tag = Normalize(tag); tag == "" || q <= 0 {
+			continue
+		}
+		if tag == "*" {
+			best = append(best, option{Default, q, i})
+			continue
+		}
+		for _, t := range Fallbacks(tag) {
+			if j := slices.IndexFunc(have, func(h string) bool { return Normalize(h) == t }); j >= 0 {
+				best = append(best, option{have[j], q, i})
+				break
+			}
+		}
+	}
+	if len(best) == 0 {
+		return ""
+	}
+	slices.SortStableFunc(best, func(a, b option) int { return cmp.Or(cmp.Compare(b.q, a.q), cmp.Compare(a.order, b.order)) })
+	return best[0].tag
+}
+
+// MARK: Message Catalogs
+
+// The catalogs are JSON files, one per language, of message keys to fmt formats. A missing message falls back to
+// the language it's a dialect of and then to English, so a catalog can be started with just a few messages
+//
+//go:embed catalogs/*.json
+var files embed.FS
+
+var catalogs = map[string]map[string]string{}
+
+func init() {
+	entries, _ := files.ReadDir("catalogs")
+	for _, e := range entries {
+		b, err := files.ReadFile(path.Join("catalogs", e.Name()))
+		if err != nil {
+			panic(err)
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(b, &messages); err != nil {
+			panic(fmt.Sprintf("i18n: %v: %v", e.Name(), err)) // Only a bad build gets here, the tests read every catalog
+		}
+		catalogs[Normalize(strings.TrimSuffix(e.Name(), ".json"))] = messages
+	}
+}
+
+// Languages lists the languages there are catalogs for, sorted
+func Languages() []string {
+	out := make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		out = append(out, lang)
+	}
+	slices.Sort(out)
+	return out
+}
+
+// Printer gets messages in one language
+type Printer struct {
+	lang string
+}
+
+// For is the printer for a language. Languages we don't have get English
+func For(lang string) Printer {
+	return Printer{Normalize(lang)}
+}
+
+// T is the message for key, formatted with args. A key no catalog has comes back as it is, so a missing message
+// shows up rather than disappearing
+func (p Printer) T(key string, args ...any) string {
+	for _, lang := range append(Fallbacks(p.lang), Default) {
+		if format, ok := catalogs[lang][key]; ok {
+			return fmt.Sprintf(format, args...)
+		}
+	}
+	return key
+}
+
+// Lang is the language asked for, which the messages might have fallen back from
+func (p Printer) Lang() string {
+	return p.lang
+}
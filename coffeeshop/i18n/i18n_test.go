@@ -0,0 +1,56 @@
+package i18n
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	have := []string{"en", "fr", "ja", "pt-BR"}
+	tests := []struct {
+		accept, expect string
+	}{
+		{"", ""},
+		{"fr", "fr"},
+		{"fr-CA, en;q=0.5", "fr"},
+		{"de, ja;q=0.8, en;q=0.7", "ja"},
+		{"en;q=0.3, ja", "ja"},
+		{"pt-br", "pt-BR"},
+		{"pt", ""}, // We only have the Brazilian one, that's not the same thing
+		{"de", ""},
+		{"de, *;q=0.1", "en"},
+		{"fr;q=0, en", "en"},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.accept, have); got != tt.expect {
+			t.Errorf("%q: got %q, expected %q", tt.accept, got, tt.expect)
+		}
+	}
+}
+
+func TestCatalogs(t *testing.T) {
+	// Every catalog only has messages English has, so a typo in a key doesn't go unnoticed
+	for _, lang := range Languages() {
+		for key := range catalogs[lang] {
+			if _, ok := catalogs[Default][key]; !ok {
+				t.Errorf("%v has %q, which English doesn't", lang, key)
+			}
+		}
+	}
+	if !slices.Contains(Languages(), "ja") {
+		t.Errorf("Got languages %v", Languages())
+	}
+
+	if got := For("fr_CA.UTF-8").T("operate.quit"); got != "q) Quitter" {
+		t.Errorf("Falling back to fr: got %q", got)
+	}
+	if got := For("tlh").T("operate.quit"); got != "q) Quit" {
+		t.Errorf("Falling back to English: got %q", got)
+	}
+	if got := For("fr").T("no.such.message"); got != "no.such.message" {
+		t.Errorf("Missing message: got %q", got)
+	}
+	if !Valid("zh-Hant-TW") || Valid("x") || Valid("en--us") || Valid("<script>") {
+		t.Error("Valid is wrong")
+	}
+}
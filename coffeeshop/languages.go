@@ -0,0 +1,108 @@
+package coffeeshop
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"demo/coffeeshop/i18n"
+	menu "demo/coffeeshop/menu"
+)
+
+// MARK: Languages
+
+// langFlag picks the language to show the menu in. The default comes from the environment, like other programs
+func langFlag(fs *flag.FlagSet) *string {
+	return fs.String("lang", envLanguage(), "language to show the menu in, e.g. fr or ja (default from $LC_ALL, $LC_MESSAGES or $LANG)")
+}
+
+// envLanguage is the language the environment asks for. C and POSIX mean no preference, so the shop's own
+func envLanguage() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if v := os.Getenv(name); v != "" {
+			if lang := i18n.Normalize(v); lang != "c" && lang != "posix" && i18n.Valid(lang) {
+				return lang
+			}
+			return i18n.Default
+		}
+	}
+	return i18n.Default
+}
+
+// runDescribe is 'menu describe', the description in the shop's own language
+func runDescribe(args []string) error {
+	fs := newFlags("menu describe", "[flags] ITEM [DESCRIPTION]", "Sets the line about ITEM shown under its name, or takes it off with no DESCRIPTION.")
+	file := menuFile(fs)
+	trail := auditFile(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 {
+		fs.Usage()
+		return errUsage
+	}
+	if err := menu.Load(*file); err != nil {
+		return err
+	}
+	desc := strings.Join(fs.Args()[1:], " ")
+	if err := menu.SetDescription(fs.Arg(0), desc); err != nil {
+		return err
+	}
+	if err := menu.Save(*file); err != nil {
+		return err
+	}
+	item, _ := menu.Lookup(fs.Arg(0))
+	return recordChange(*trail, "menu.describe", fmt.Sprintf("%v: %q", item.ID, desc))
+}
+
+// runTranslate is 'menu translate', an item's name and description or the size labels in another language
+func runTranslate(args []string) error {
+	fs := newFlags("menu translate", "--lang LANG [flags] [ITEM]",
+		"Sets what ITEM is called and how it's described in LANG. Giving neither takes its translation off.\n"+
+			"--size sets what the sizes are called, with or without an ITEM.")
+	file := menuFile(fs)
+	trail := auditFile(fs)
+	lang := fs.String("lang", "", "language tag, like fr or pt-br (required)")
+	name := fs.String("name", "", "the name in LANG")
+	desc := fs.String("description", "", "the description in LANG")
+	labels := map[string]string{}
+	fs.Func("size", "what a size is called in LANG, e.g. small=petit, or small= to go back to its own name (repeatable)", func(s string) error {
+		size, label, ok := strings.Cut(s, "=")
+		if !ok || strings.TrimSpace(size) == "" {
+			return fmt.Errorf("%q should look like size=label", s)
+		}
+		labels[strings.TrimSpace(size)] = label
+		return nil
+	})
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *lang == "" || fs.NArg() > 1 || fs.NArg() == 0 && len(labels) == 0 {
+		fs.Usage()
+		return errUsage
+	}
+	if err := menu.Load(*file); err != nil {
+		return err
+	}
+
+	var changes []string
+	if fs.NArg() == 1 {
+		if err := menu.SetTranslation(fs.Arg(0), *lang, *name, *desc); err != nil {
+			return err
+		}
+		item, _ := menu.Lookup(fs.Arg(0))
+		changes = append(changes, fmt.Sprintf("%v in %v: %v", item.ID, i18n.Normalize(*lang), cmp.Or(strings.TrimSpace(*name+" "+*desc), "removed")))
+	}
+	for size, label := range labels {
+		if err := menu.SetSizeLabel(*lang, size, label); err != nil {
+			return err
+		}
+		changes = append(changes, fmt.Sprintf("%v in %v: %v", size, i18n.Normalize(*lang), cmp.Or(label, "removed")))
+	}
+	if err := menu.Save(*file); err != nil {
+		return err
+	}
+	return recordChange(*trail, "menu.translate", strings.Join(changes, "; "))
+}
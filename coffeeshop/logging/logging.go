@@ -0,0 +1,119 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// MARK: Logging
+
+// Config is where the logs go and how much of them. The zero Config is info and up, as text, to stderr
+type Config struct {
+	Level      slog.Level
+	Format     string // text or json
+	File       string // Empty for stderr
+	MaxSize    int64  // Bytes before the file is rotated, 0 to never rotate
+	MaxBackups int    // Rotated files to keep (app.log.1, app.log.2...)
+}
+
+// ParseLevel reads debug, info, warn or error
+func ParseLevel(s string) (slog.Level, error) {
+	var l slog.Level
+	err := l.UnmarshalText([]byte(strings.TrimSpace(s)))
+	return l, err
+}
+
+// Setup makes the logger described by cfg the default for slog and the old log package. The returned func closes
+// the log file, if there is one
+func Setup(cfg Config) (func() error, error) {
+	var w io.Writer = os.Stderr
+	closeFile := func() error { return nil }
+	if cfg.File != "" {
+		f, err := OpenRotating(cfg.File, cfg.MaxSize, cfg.MaxBackups)
+		if err != nil {
+			return nil, err
+		}
+		w, closeFile = f, f.Close
+	}
+
+	opts := &slog.HandlerOptions{Level: cfg.Level}
+	var h slog.Handler
+	switch strings.ToLower(cfg.Format) {
+	case "", "text":
+		h = slog.NewTextHandler(w, opts)
+	case "json":
+		h = slog.NewJSONHandler(w, opts)
+	default:
+		closeFile()
+		return nil, fmt.Errorf("unknown log format %q, expected text or json", cfg.Format)
+	}
+	slog.SetDefault(slog.New(Correlate(h)))
+	return closeFile, nil
+}
+
+// MARK: Correlation
+
+type ctxKey int
+
+const (
+	requestIDKey ctxKey = iota
+	orderIDKey
+)
+
+// WithRequestID tags ctx with the ID of the HTTP request it belongs to
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID is the request ID in ctx, or ""
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// WithOrderID tags ctx with the order it's about
+func WithOrderID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, orderIDKey, id)
+}
+
+// OrderID is the order ID in ctx, or ""
+func OrderID(ctx context.Context) string {
+	id, _ := ctx.Value(orderIDKey).(string)
+	return id
+}
+
+// correlator adds the request and order IDs from the context to every record, so everything that happened for
+// one request or one order can be found with a single grep. Log with the ...Context methods to get them
+type correlator struct {
+	slog.Handler
+}
+
+// Correlate wraps h so records pick up request_id and order_id from their context
+func Correlate(h slog.Handler) slog.Handler {
+	if _, ok := h.(correlator); ok {
+		return h // Already done, the IDs would be there twice
+	}
+	return correlator{h}
+}
+
+func (c correlator) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if id := OrderID(ctx); id != "" {
+		r.AddAttrs(slog.String("order_id", id))
+	}
+	return c.Handler.Handle(ctx, r)
+}
+
+func (c correlator) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return correlator{c.Handler.WithAttrs(attrs)}
+}
+
+func (c correlator) WithGroup(name string) slog.Handler {
+	return correlator{c.Handler.WithGroup(name)}
+}
@@ -0,0 +1,55 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCorrelate(t *testing.T) {
+	var b bytes.Buffer
+	logger := slog.New(Correlate(Correlate(slog.NewTextHandler(&b, nil))))
+	ctx := WithOrderID(WithRequestID(context.Background(), "req-1"), "ORDER1")
+	logger.InfoContext(ctx, "order placed")
+	logger.Info("no context")
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if !strings.HasSuffix(lines[0], "request_id=req-1 order_id=ORDER1") {
+		t.Errorf("Got %q, expected the IDs once each on the end", lines[0])
+	}
+	if strings.Contains(lines[1], "_id=") {
+		t.Errorf("Got %q, expected no IDs without a context", lines[1])
+	}
+}
+
+func TestRotating(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	r, err := OpenRotating(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := r.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each line goes over 10 bytes with the one before it, so every line gets a file and only 2 old ones are kept
+	expect := map[string]string{"app.log": "fourth\n", "app.log.1": "third\n", "app.log.2": "second\n"}
+	for name, content := range expect {
+		b, err := os.ReadFile(filepath.Join(filepath.Dir(path), name))
+		if err != nil || string(b) != content {
+			t.Errorf("%v: got %q, %v, expected %q", name, b, err, content)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); err == nil {
+		t.Error("Expected no more than 2 backups")
+	}
+}
@@ -0,0 +1,232 @@
+package menu
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// MARK: Allergens and Diets
+
+// Allergen is one of the 14 major allergens. They're bit flags, so one Allergen value can also hold a set of them
+// by combining them with | (Milk | Soya)
+type Allergen uint16
+
+const (
+	Celery Allergen = 1 << iota // 1, 2, 4, 8... iota counts up and the shift turns each one into its own bit
+	Gluten                      // Cereals containing gluten (wheat, rye, barley, oats)
+	Crustaceans
+	Eggs
+	Fish
+	Lupin
+	Milk
+	Molluscs
+	Mustard
+	Nuts // Tree nuts
+	Peanuts
+	Sesame
+	Soya
+	Sulphites
+)
+
+// Same order as the constants above
+var allergenNames = []string{"celery", "gluten", "crustaceans", "eggs", "fish", "lupin", "milk", "molluscs", "mustard", "nuts", "peanuts", "sesame", "soya", "sulphites"}
+
+// Other names people use for the same thing
+var allergenAliases = map[string]Allergen{
+	"wheat": Gluten, "egg": Eggs, "dairy": Milk, "tree nuts": Nuts, "tree-nuts": Nuts, "nut": Nuts,
+	"peanut": Peanuts, "soy": Soya, "sulfites": Sulphites, "shellfish": Crustaceans,
+}
+
+// Has reports whether every allergen in other is also in a
+func (a Allergen) Has(other Allergen) bool {
+	return a&other == other
+}
+
+// Names lists the allergens in the set
+func (a Allergen) Names() []string {
+	var out []string
+	for i, name := range allergenNames {
+		if a&(1<<i) != 0 {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+func (a Allergen) String() string {
+	if a == 0 {
+		return "none"
+	}
+	return strings.Join(a.Names(), ", ")
+}
+
+// ParseAllergens reads a comma separated list like "milk, nuts"
+func ParseAllergens(s string) (Allergen, error) {
+	var a Allergen
+	for _, name := range strings.Split(s, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || name == "none" {
+			continue
+		}
+		if alias, ok := allergenAliases[name]; ok {
+			a |= alias
+			continue
+		}
+		i := slices.Index(allergenNames, name)
+		if i < 0 {
+			return 0, fmt.Errorf("unknown allergen %q", name)
+		}
+		a |= 1 << i
+	}
+	return a, nil
+}
+
+// Diet is a dietary label. Vegan and vegetarian have to be declared, gluten and dairy free are worked out from
+// the allergens so they can't disagree with them
+type Diet uint8
+
+const (
+	Vegan Diet = 1 << iota
+	Vegetarian
+	GlutenFree
+	DairyFree
+)
+
+var dietNames = []string{"vegan", "vegetarian", "gluten-free", "dairy-free"}
+
+// Names lists the diets in the set
+func (d Diet) Names() []string {
+	var out []string
+	for i, name := range dietNames {
+		if d&(1<<i) != 0 {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+func (d Diet) String() string {
+	return strings.Join(d.Names(), ", ")
+}
+
+// ParseDiets reads a comma separated list like "vegan, gluten-free"
+func ParseDiets(s string) (Diet, error) {
+	var d Diet
+	for _, name := range strings.Split(s, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		i := slices.Index(dietNames, strings.ReplaceAll(name, " ", "-"))
+		if i < 0 {
+			return 0, fmt.Errorf("unknown diet %q", name)
+		}
+		d |= 1 << i
+	}
+	return d, nil
+}
+
+// withDerived adds the diets that follow from the allergens
+func (d Diet) withDerived(a Allergen) Diet {
+	d &^= GlutenFree | DairyFree // &^ is "and not", it clears those bits
+	if !a.Has(Gluten) {
+		d |= GlutenFree
+	}
+	if !a.Has(Milk) {
+		d |= DairyFree
+	}
+	if d&Vegan != 0 {
+		d |= Vegetarian // Anything vegan is vegetarian too
+	}
+	return d
+}
+
+// MARK: Modifiers
+
+// A modifier is something added to or swapped into a drink, which can change what's in it
+type modifier struct {
+	name    string
+	price   float64
+	adds    Allergen
+	removes Allergen  // Swapping the milk takes the milk away
+	breaks  Diet      // Diets the item no longer fits once this is added (a shot of whole milk isn't vegan)
+	extra   Nutrition // What it adds on top of the drink (whole milk vs skim)
+}
+
+func findModifier(name string) (modifier, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, m := range modifiers {
+		if m.name == name {
+			return m, true
+		}
+	}
+	return modifier{}, false
+}
+
+// Modifiers lists the names of the modifiers that can go on an order line
+func Modifiers() []string {
+	out := make([]string, len(modifiers))
+	for i, m := range modifiers {
+		out[i] = m.name
+	}
+	return out
+}
+
+// MARK: Quotes
+
+// Quote is everything an order line needs to know about an item once the size and modifiers are chosen
+type Quote struct {
+	ItemID    string    `json:"itemId"` // Still finds the item if it's renamed later
+	Item      string    `json:"item"`   // The name when the quote was made, that's what the receipt says
+	Size      string    `json:"size"`
+	Price     float64   `json:"price"` // Includes the modifiers
+	Allergens Allergen  `json:"allergens"`
+	Diet      Diet      `json:"diet"`
+	Nutrition Nutrition `json:"nutrition"` // Includes the modifiers
+	HasFacts  bool      `json:"hasFacts"`  // False when we don't have nutrition facts for this size, Nutrition is then just the modifiers
+	Currency  string    `json:"currency,omitempty"`
+}
+
+// QuoteLine looks up the item and works out its price and effective allergens with the modifiers applied
+func QuoteLine(item, size string, modifiers []string) (Quote, error) {
+	return QuoteAt("", item, size, modifiers)
+}
+
+// QuoteAt is QuoteLine at one store, with its prices. Items the store doesn't sell can't be quoted
+func QuoteAt(store, item, size string, modifiers []string) (Quote, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	s, err := findStore(store)
+	if err != nil {
+		return Quote{}, err
+	}
+	m := data.at(s)
+	i := m.find(item)
+	if i < 0 {
+		return Quote{}, notFound(item)
+	}
+	mi := m[i]
+	price, ok := mi.prices[size]
+	if !ok {
+		return Quote{}, invalid(mi.name, "size", "doesn't come in %q", size)
+	}
+
+	cur, rate := moneyAt(s).Currency, rateAt(s)
+	facts, hasFacts := mi.nutrition[size]
+	q := Quote{ItemID: mi.id, Item: mi.name, Size: size, Price: price, Allergens: mi.allergens, Diet: mi.diets(), Nutrition: facts, HasFacts: hasFacts, Currency: cur.Code}
+	for _, name := range modifiers {
+		m, ok := findModifier(name)
+		if !ok {
+			return Quote{}, invalid(mi.name, "modifiers", "unknown modifier %q", name)
+		}
+		q.Price += cur.Round(m.price * rate) // Modifiers are priced in the base currency
+		q.Allergens = q.Allergens&^m.removes | m.adds
+		q.Diet &^= m.breaks
+		q.Nutrition = q.Nutrition.Plus(m.extra)
+	}
+	q.Diet = q.Diet.withDerived(q.Allergens)
+	q.Price = cur.Round(q.Price) // 3.5 + 0.6 shouldn't come out as 4.1000000000000005
+	return q, nil
+}
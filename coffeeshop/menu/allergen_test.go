@@ -0,0 +1,45 @@
+package menu
+
+import "testing"
+
+func TestQuoteLine(t *testing.T) {
+	data = testMenu()
+
+	// Oat milk swaps the milk out for gluten
+	q, err := QuoteLine("Latte", "small", []string{"oat milk"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Allergens != Gluten {
+		t.Errorf("Got allergens %v, expected gluten", q.Allergens)
+	}
+	if q.Price != 3.50 {
+		t.Errorf("Got price %v, expected 3.50", q.Price)
+	}
+
+	// Whole milk stops a vegan item being vegan, but it's still vegetarian
+	q, err = QuoteLine("Iced Tea", "large", []string{"whole milk"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := Vegetarian | GlutenFree; q.Diet != expect {
+		t.Errorf("Got diet %v, expected %v", q.Diet, expect)
+	}
+
+	if _, err := QuoteLine("Latte", "small", []string{"bacon"}); err == nil {
+		t.Error("Expected an unknown modifier to fail")
+	}
+}
+
+func TestParseAllergens(t *testing.T) {
+	a, err := ParseAllergens("Dairy, tree nuts,soya")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := Milk | Nuts | Soya; a != expect {
+		t.Errorf("Got %v, expected %v", a, expect)
+	}
+	if _, err := ParseAllergens("glitter"); err == nil {
+		t.Error("Expected an unknown allergen to fail")
+	}
+}
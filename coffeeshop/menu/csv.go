@@ -0,0 +1,260 @@
+package menu
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"demo/coffeeshop/money"
+)
+
+// MARK: CSV
+
+// The columns, in the order WriteCSV writes them. Reading matches them by name, so a spreadsheet can move them
+// around or leave the optional ones out
+var csvColumns = []string{"id", "name", "category", "size", "price", "available", "allergens"}
+
+// WriteCSV writes one row per item and size, the file managers keep the prices in
+func WriteCSV(w io.Writer) error {
+	mu.RLock()
+	defer mu.RUnlock()
+	cw := csv.NewWriter(w)
+	cw.Write(csvColumns)
+	for _, item := range data {
+		sizes := sortedSizes(item.prices) // Maps aren't ordered, so sort to keep the output the same between runs
+		row := func(size, price string) []string {
+			return []string{item.id, item.name, item.category, size, price, yesNo(!item.soldOut), strings.Join(item.allergens.Names(), ";")}
+		}
+		if len(sizes) == 0 {
+			cw.Write(row("", "")) // Still list items that don't have a price yet
+		}
+		for _, size := range sizes {
+			cw.Write(row(size, strconv.FormatFloat(item.prices[size], 'f', 2, 64)))
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// csvRow is one line of the file
+type csvRow struct {
+	line                      int
+	id, name, category, size  string
+	price                     float64
+	hasPrice                  bool
+	available                 bool
+	allergens                 Allergen
+	hasCategory, hasAvailable bool
+	hasAllergens              bool
+	bad                       bool // Already reported, so the item it's for isn't checked any further
+}
+
+// ReadCSV checks a CSV file against the menu and works out what it would change. Every item in the file ends up
+// the way the file says, sizes it leaves out included. Items it doesn't mention are left alone. Problems come back
+// together as an *ImportError, with the line each one is on
+func ReadCSV(r io.Reader) (*Import, error) {
+	mu.RLock()
+	f := moneyAt(nil)
+	mu.RUnlock()
+	rows, errs := readRows(r, f)
+	if rows == nil && len(errs) > 0 {
+		return nil, &ImportError{errs}
+	}
+
+	// Rows for the same item go together, in the order the items first come up
+	var order []string
+	groups := map[string][]csvRow{}
+	for _, row := range rows {
+		key := row.id
+		if key == "" {
+			key = nameKey(row.name)
+		}
+		if groups[key] == nil {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], row)
+	}
+
+	im := newImporter()
+	im.errs = errs
+	for _, key := range order {
+		if rows := groups[key]; !slices.ContainsFunc(rows, func(row csvRow) bool { return row.bad }) {
+			im.csvItem(rows)
+		}
+	}
+	return im.done()
+}
+
+// readRows parses the lines of the file without looking at the menu. Prices can be written the way f writes them
+func readRows(r io.Reader, f money.Format) ([]csvRow, []LineError) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, []LineError{{Line: 1, Err: fmt.Errorf("reading the header: %w", err)}}
+	}
+	col := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !slices.Contains(csvColumns, name) {
+			return nil, []LineError{{Line: 1, Err: fmt.Errorf("unknown column %q, expected some of %v", name, strings.Join(csvColumns, ", "))}}
+		}
+		col[name] = i
+	}
+	for _, need := range []string{"name", "size", "price"} {
+		if _, ok := col[need]; !ok {
+			return nil, []LineError{{Line: 1, Err: fmt.Errorf("the %q column is missing", need)}}
+		}
+	}
+
+	var rows []csvRow
+	var errs []LineError
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var pe *csv.ParseError
+			if errors.As(err, &pe) {
+				errs = append(errs, LineError{Line: pe.Line, Col: pe.Column, Err: pe.Err})
+				continue
+			}
+			return nil, append(errs, LineError{Err: err})
+		}
+		line, _ := cr.FieldPos(0)
+		get := func(name string) (string, bool) {
+			i, ok := col[name]
+			if !ok {
+				return "", false
+			}
+			return strings.TrimSpace(record[i]), true
+		}
+
+		row := csvRow{line: line, available: true}
+		row.id, _ = get("id")
+		row.name, _ = get("name")
+		row.size, _ = get("size")
+		var category, available, allergens, price string
+		category, row.hasCategory = get("category")
+		available, row.hasAvailable = get("available")
+		allergens, row.hasAllergens = get("allergens")
+		price, _ = get("price")
+		row.category = strings.ToLower(category)
+
+		var problems []FieldError
+		if row.name == "" && row.id == "" {
+			problems = append(problems, FieldError{Field: "name", Message: "a name or ID is needed"})
+		}
+		if price != "" {
+			p, err := parsePrice(price, f)
+			if err != nil {
+				problems = append(problems, FieldError{Field: "prices", Message: fmt.Sprintf("%q is not a price", price)})
+			}
+			row.price, row.hasPrice = p, true
+		}
+		if row.hasPrice != (row.size != "") {
+			problems = append(problems, FieldError{Field: "prices", Message: "a size and a price go together, give both or neither"})
+		}
+		if row.hasAvailable && available != "" {
+			switch strings.ToLower(available) {
+			case "yes", "y", "true", "1":
+			case "no", "n", "false", "0", "sold out":
+				row.available = false
+			default:
+				problems = append(problems, FieldError{Field: "available", Message: fmt.Sprintf("%q should be yes or no", available)})
+			}
+		}
+		if row.allergens, err = ParseAllergens(strings.ReplaceAll(allergens, ";", ",")); err != nil {
+			problems = append(problems, FieldError{Field: "allergens", Message: err.Error()})
+		}
+		if len(problems) > 0 {
+			errs = append(errs, LineError{Line: line, Err: &ValidationError{Item: row.name, Problems: problems}})
+			row.bad = true
+		}
+		rows = append(rows, row)
+	}
+	return rows, errs
+}
+
+// csvItem makes one item the way its rows describe it
+func (im *importer) csvItem(rows []csvRow) {
+	first := rows[0]
+	i, err := im.existing(first.id, first.name)
+	if err != nil {
+		im.fail(first.line, err)
+		return
+	}
+
+	var item menuItem
+	if i >= 0 {
+		item = im.m[i]
+		item.prices = map[string]float64{} // The sizes in the file are the sizes it comes in
+	} else {
+		item = newItem(first.name, nil, Details{})
+	}
+	if first.id != "" && first.name != "" && first.name != item.name {
+		item.name, item.key = first.name, nameKey(first.name) // A new name for an item given by ID. By name, it's just spelled differently
+	}
+
+	for n, row := range rows {
+		fail := func(field, format string, args ...any) {
+			im.fail(row.line, invalid(item.name, field, format, args...))
+		}
+		if row.hasCategory {
+			if n > 0 && row.category != first.category {
+				fail("category", "%q doesn't match %q on line %v", row.category, first.category, first.line)
+				return
+			}
+			item.category = row.category
+		}
+		if row.hasAvailable {
+			if row.available != first.available {
+				fail("available", "doesn't match line %v", first.line)
+				return
+			}
+			item.soldOut = !row.available
+		}
+		if row.hasAllergens {
+			if row.allergens != first.allergens {
+				fail("allergens", "don't match line %v", first.line)
+				return
+			}
+			item.allergens = row.allergens
+		}
+		if row.size != "" {
+			if _, dup := item.prices[row.size]; dup {
+				fail("size", "%v is on more than one line", row.size)
+				return
+			}
+			item.prices[row.size] = row.price
+		}
+	}
+	item.nutrition = keepNutrition(item.nutrition, item.prices)
+	im.put(first.line, i, item)
+}
+
+// parsePrice reads a price from a file, as a plain number like export writes or the way f writes it, with the
+// menu's currency symbol and its decimal comma. Another currency's symbol isn't a price on this menu
+func parsePrice(s string, f money.Format) (float64, error) {
+	s = strings.TrimSpace(s)
+	if sym := f.Currency.Symbol; sym != "" {
+		s = strings.TrimPrefix(strings.TrimSuffix(s, sym), sym)
+		s = strings.Trim(s, " \u00a0\u202f") // The locale's spaces between the symbol and the number
+	}
+	if f.Locale.Decimal == "," && !strings.Contains(s, ".") {
+		s = strings.Replace(s, ",", ".", 1)
+	}
+	return strconv.ParseFloat(s, 64)
+}
@@ -0,0 +1,92 @@
+package menu
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"demo/coffeeshop/money"
+)
+
+func TestCSV(t *testing.T) {
+	useTestMenu(t)
+
+	// What we write we can read back, and it changes nothing
+	var b bytes.Buffer
+	if err := WriteCSV(&b); err != nil {
+		t.Fatal(err)
+	}
+	imp, err := ReadCSV(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imp.Changes) != 0 {
+		t.Errorf("Round trip: got changes %v, expected none", imp.Changes)
+	}
+
+	// Columns in any order, items by name, and a new one
+	file := "Name,Size,Price,Available\n" +
+		"latte,small,3.10,yes\n" +
+		"Latte,large,3.50,yes\n" +
+		"Cafe Mocha,small,3.40,no\n" + // Nothing changes, it's already sold out
+		"Flat White,small,3.20,yes\n"
+	imp, err = ReadCSV(strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imp.Changes) != 2 || imp.Changes[0].String() != "~ Latte: small 3.00 -> 3.10" || !imp.Changes[1].New {
+		t.Fatalf("Got changes %q", imp.Changes)
+	}
+	if price := data[data.find("Latte")].prices["small"]; price != 3 {
+		t.Error("A preview changed the menu")
+	}
+	if err := imp.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	if price := data[data.find("Latte")].prices["small"]; price != 3.10 {
+		t.Errorf("Got %v, expected 3.10 after applying", price)
+	}
+	if err := imp.Apply(); !errors.Is(err, ErrMenuChanged) {
+		t.Errorf("Applying twice: got %v, expected ErrMenuChanged", err)
+	}
+
+	// Every bad line is reported, and nothing from the file goes in
+	file = "name,size,price\n" +
+		"Chai Latte,small,2.00\n" +
+		"Latte,small,lots\n" +
+		"Iced Tea,large,-1\n"
+	_, err = ReadCSV(strings.NewReader(file))
+	var ie *ImportError
+	if !errors.As(err, &ie) || len(ie.Lines) != 2 || ie.Lines[0].Line != 3 || ie.Lines[1].Line != 4 {
+		t.Fatalf("Got %v, expected errors on lines 3 and 4", err)
+	}
+	if price := data[data.find("Chai Latte")].prices["small"]; price != 3.10 {
+		t.Error("A file with errors changed the menu")
+	}
+}
+
+func TestParsePrice(t *testing.T) {
+	usd, _ := money.New("USD", "")
+	eur, _ := money.New("EUR", "de-DE")
+	yen, _ := money.New("JPY", "")
+	for _, test := range []struct {
+		price string
+		f     money.Format
+		want  float64
+		ok    bool
+	}{
+		{"3.10", money.Format{}, 3.1, true},
+		{"$3.10", usd, 3.1, true},
+		{"3.10", eur, 3.1, true}, // How export writes it
+		{"3,10 €", eur, 3.1, true},
+		{"480", yen, 480, true},
+		{"£3", yen, 0, false},
+		{"£3.10", money.Format{}, 0, false},
+	} {
+		got, err := parsePrice(test.price, test.f)
+		if (err == nil) != test.ok || got != test.want {
+			t.Errorf("%q in %v: got %v, %v", test.price, test.f, got, err)
+		}
+	}
+}
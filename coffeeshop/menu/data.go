@@ -2,6 +2,20 @@ package menu
 
 // Menu is a slice
 var data = menu{
-	{name: "Coffee", prices: map[string]float64{"small": 1.65, "medium": 1.80, "large": 1.95}},
-	{name: "Espresso", prices: map[string]float64{"single": 1.90, "double": 2.25, "triple": 2.55}},
-}
\ No newline at end of file
+	{name: "Coffee", prices: map[string]float64{"small": 1.65, "medium": 1.80, "large": 1.95}, category: "coffee", tags: []string{"hot"}, diet: Vegan,
+		nutrition: map[string]Nutrition{"small": {Calories: 2, Protein: 0.3}, "medium": {Calories: 3, Protein: 0.4}, "large": {Calories: 4, Protein: 0.5}}},
+	{name: "Espresso", prices: map[string]float64{"single": 1.90, "double": 2.25, "triple": 2.55}, category: "coffee", tags: []string{"hot"}, diet: Vegan,
+		nutrition: map[string]Nutrition{"single": {Calories: 1, Protein: 0.1}, "double": {Calories: 2, Protein: 0.2}, "triple": {Calories: 3, Protein: 0.3}}},
+}
+
+// Things that can be added to a drink. The milk swaps take the dairy milk out and put their own allergens in
+var modifiers = []modifier{
+	{name: "whole milk", price: 0, adds: Milk, breaks: Vegan, extra: Nutrition{Calories: 37, Fat: 2, Carbs: 2.9, Sugar: 2.9, Protein: 2}},
+	{name: "skim milk", price: 0, adds: Milk, breaks: Vegan, extra: Nutrition{Calories: 21, Fat: 0.1, Carbs: 3, Sugar: 3, Protein: 2.1}},
+	{name: "oat milk", price: 0.50, removes: Milk, adds: Gluten, extra: Nutrition{Calories: 30, Fat: 1.5, Carbs: 4, Sugar: 2, Protein: 0.3}},
+	{name: "soy milk", price: 0.50, removes: Milk, adds: Soya, extra: Nutrition{Calories: 20, Fat: 1, Carbs: 1, Sugar: 1, Protein: 1.8}},
+	{name: "almond milk", price: 0.50, removes: Milk, adds: Nuts, extra: Nutrition{Calories: 8, Fat: 0.6, Carbs: 0.2, Protein: 0.2}},
+	{name: "extra shot", price: 0.75, extra: Nutrition{Calories: 1, Protein: 0.1}},
+	{name: "vanilla syrup", price: 0.40, extra: Nutrition{Calories: 40, Carbs: 10, Sugar: 10}},
+	{name: "whipped cream", price: 0.50, adds: Milk, breaks: Vegan, extra: Nutrition{Calories: 52, Fat: 5.5, Carbs: 0.6, Sugar: 0.6, Protein: 0.3}},
+}
@@ -0,0 +1,71 @@
+package menu
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MARK: Errors
+
+// These are the kinds of things that go wrong with the menu. Errors from this package wrap one of them where
+// they can, so callers can use errors.Is to decide what to tell people without reading the message
+var (
+	ErrDuplicateItem = errors.New("there's already an item with that name")
+	ErrItemNotFound  = errors.New("not on the menu")
+	ErrUnavailable   = errors.New("sold out")                                             // On the menu, but can't be ordered right now
+	ErrMenuChanged   = errors.New("the menu changed in the meantime, please check again") // Something checked against an older menu
+	ErrStoreNotFound = errors.New("not one of our stores")
+)
+
+// FieldError is one problem with one part of an item
+type FieldError struct {
+	Field   string `json:"field"` // name, prices, size, modifiers, nutrition...
+	Message string `json:"message"`
+	Err     error  `json:"-"` // One of the errors above when the problem is one of those, like ErrDuplicateItem
+}
+
+func (f FieldError) Error() string {
+	return f.Field + ": " + f.Message
+}
+
+func (f FieldError) Unwrap() error {
+	return f.Err
+}
+
+// ValidationError is everything wrong with an item someone tried to add or change. Use errors.As to get at the
+// individual problems
+type ValidationError struct {
+	Item     string // Empty when the item itself has no name yet
+	Problems []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		msgs[i] = p.Error()
+	}
+	if e.Item == "" {
+		return "invalid item: " + strings.Join(msgs, "; ")
+	}
+	return fmt.Sprintf("invalid item %q: %v", e.Item, strings.Join(msgs, "; "))
+}
+
+// Unwrap lets errors.Is see through to the problems, so a duplicate name is still ErrDuplicateItem
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, len(e.Problems))
+	for i, p := range e.Problems {
+		errs[i] = p
+	}
+	return errs
+}
+
+// invalid is a ValidationError with a single problem
+func invalid(item, field, format string, args ...any) *ValidationError {
+	return &ValidationError{Item: item, Problems: []FieldError{{Field: field, Message: fmt.Sprintf(format, args...)}}}
+}
+
+// notFound is the error for a name that isn't on the menu
+func notFound(name string) error {
+	return fmt.Errorf("%q is %w", strings.TrimSpace(name), ErrItemNotFound)
+}
@@ -0,0 +1,27 @@
+package menu
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestErrorKinds(t *testing.T) {
+	data = testMenu()
+
+	if _, err := Lookup("Flat White"); !errors.Is(err, ErrItemNotFound) {
+		t.Errorf("Lookup: got %v, expected ErrItemNotFound", err)
+	}
+	if err := Add("Latte", map[string]float64{"small": 3}, Details{}); !errors.Is(err, ErrDuplicateItem) {
+		t.Errorf("Add: got %v, expected ErrDuplicateItem", err)
+	}
+	file := storedMenu{Items: []storedItem{{Name: "Latte", Prices: map[string]float64{"small": 3}}, {Name: "latte ", Prices: map[string]float64{"large": 3.5}}}}
+	if _, err := file.menu(); !errors.Is(err, ErrDuplicateItem) {
+		t.Errorf("Loading a file with Latte twice: got %v, expected ErrDuplicateItem", err)
+	}
+
+	_, err := QuoteLine("Latte", "huge", nil)
+	var ve *ValidationError
+	if !errors.As(err, &ve) || len(ve.Problems) != 1 || ve.Problems[0].Field != "size" {
+		t.Errorf("QuoteLine: got %v, expected a ValidationError about the size", err)
+	}
+}
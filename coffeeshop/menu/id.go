@@ -0,0 +1,77 @@
+package menu
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"strings"
+	"time"
+)
+
+// MARK: Item IDs
+
+// Every item has an ID that never changes, even when the item is renamed, so orders and links can keep pointing
+// at it. It's a hash of the name the item first had, so an old menu file without IDs gets the same ones every time
+// it's loaded. A counter goes into the hash when two items would get the same one (an item renamed away from
+// "Mocha" keeps its ID, and a new "Mocha" gets a different one)
+
+const idPrefix = "item-"
+
+// newID picks an ID for an item called name that nothing on m has yet
+func (m menu) newID(name string) string {
+	for n := 0; ; n++ {
+		sum := sha256.Sum256([]byte(fmt.Sprintf("%v#%d", nameKey(name), n)))
+		id := idPrefix + hex.EncodeToString(sum[:4])
+		if !m.hasID(id) {
+			return id
+		}
+	}
+}
+
+func (m menu) hasID(id string) bool {
+	for _, item := range m {
+		if item.id == id {
+			return true
+		}
+	}
+	return false
+}
+
+// fillIDs gives an ID to every item that doesn't have one, in menu order so it comes out the same every time
+func (m menu) fillIDs() {
+	for i := range m {
+		if m[i].id == "" {
+			m[i].id = m.newID(m[i].name)
+		}
+	}
+}
+
+// The built in menu is written out by hand in data.go, so it needs its keys and IDs filled in
+func init() {
+	for i := range data {
+		data[i].key = nameKey(data[i].name)
+	}
+	data.fillIDs()
+}
+
+// Rename changes an item's name, its ID stays the same. ref is the item's ID or current name
+func Rename(ref, name string) (oldName string, err error) {
+	mu.Lock()
+	defer mu.Unlock()
+	i := data.find(ref)
+	if i < 0 {
+		return "", notFound(ref)
+	}
+	item := data[i]
+	item.name = strings.TrimSpace(name)
+	item.key = nameKey(name)
+	item.prices = maps.Clone(item.prices)
+	if err := data.validate(item, i); err != nil {
+		return "", err
+	}
+	oldName = data[i].name
+	data[i] = item
+	changed(time.Now())
+	return oldName, nil
+}
@@ -0,0 +1,57 @@
+package menu
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIDs(t *testing.T) {
+	// A file from before there were IDs gets the same ones every time it's loaded
+	old := []byte(`[{"name":"Latte","prices":{"small":3}},{"name":"Mocha","prices":{"small":3.4}}]`)
+	if err := load(old, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	first, _ := Lookup("Latte")
+	if err := load(old, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := Lookup("Latte"); again.ID != first.ID || !strings.HasPrefix(first.ID, idPrefix) {
+		t.Errorf("Got IDs %q and %q, expected the same one twice", first.ID, again.ID)
+	}
+
+	// Names don't care about case or spaces, IDs work anywhere a name does
+	for _, ref := range []string{"latte", "  LATTE ", first.ID} {
+		if item, err := Lookup(ref); err != nil || item.ID != first.ID {
+			t.Errorf("Lookup(%q): got %v, %v", ref, item.ID, err)
+		}
+	}
+	defer func(before *bufio.Reader) { in = before }(in)
+	in = bufio.NewReader(strings.NewReader("latte \n"))
+	if err := data.add(); !errors.Is(err, ErrDuplicateItem) {
+		t.Errorf("Adding \"latte \": got %v, expected ErrDuplicateItem", err)
+	}
+
+	// An order taken before a rename still finds the item, and a new item can have the old name
+	q, err := QuoteLine("Latte", "small", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Rename(first.ID, "Caffè Latte"); err != nil {
+		t.Fatal(err)
+	}
+	if item, err := Lookup(q.ItemID); err != nil || item.Name != "Caffè Latte" {
+		t.Errorf("Got %v, %v, expected the renamed item", item.Name, err)
+	}
+	if err := Add("Latte", map[string]float64{"small": 3.2}, Details{}); err != nil {
+		t.Fatal(err)
+	}
+	if item, _ := Lookup("Latte"); item.ID == first.ID || item.ID == "" {
+		t.Errorf("Got ID %q for the new Latte, expected a new one", item.ID)
+	}
+	if _, err := Rename("Mocha", "caffe latte"); !errors.Is(err, ErrDuplicateItem) {
+		t.Errorf("Renaming onto another item: got %v, expected ErrDuplicateItem", err)
+	}
+}
@@ -0,0 +1,244 @@
+package menu
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// MARK: Importing
+
+// LineError is a problem with one line of an imported file. Col is 0 when it's about the whole line
+type LineError struct {
+	Line, Col int
+	Err       error
+}
+
+func (e LineError) Error() string {
+	if e.Col > 0 {
+		return fmt.Sprintf("line %v, column %v: %v", e.Line, e.Col, e.Err)
+	}
+	return fmt.Sprintf("line %v: %v", e.Line, e.Err)
+}
+
+func (e LineError) Unwrap() error { return e.Err }
+
+// ImportError is every problem found in an imported file. Nothing from the file is applied when there is one
+type ImportError struct {
+	Lines []LineError
+}
+
+func (e *ImportError) Error() string {
+	msgs := make([]string, len(e.Lines))
+	for i, l := range e.Lines {
+		msgs[i] = l.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap lets errors.As find the ValidationErrors inside
+func (e *ImportError) Unwrap() []error {
+	errs := make([]error, len(e.Lines))
+	for i, l := range e.Lines {
+		errs[i] = l
+	}
+	return errs
+}
+
+// Change is what an import would do to one item
+type Change struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	New     bool     `json:"new,omitempty"`
+	Changes []string `json:"changes"` // "large 1.95 -> 2.05", "sold out"...
+}
+
+func (c Change) String() string {
+	mark := "~"
+	if c.New {
+		mark = "+"
+	}
+	return fmt.Sprintf("%v %v: %v", mark, c.Name, strings.Join(c.Changes, "; "))
+}
+
+// Import is a checked file that's ready to go on the menu. Look at Changes for the preview, then Apply it
+type Import struct {
+	Changes []Change
+	result  menu
+	base    time.Time // When the menu it was checked against last changed
+}
+
+// Apply puts the import on the menu. If the menu has changed since the file was checked the preview might be
+// wrong, so nothing happens and it has to be checked again
+func (imp *Import) Apply() error {
+	mu.Lock()
+	defer mu.Unlock()
+	if !modified.Equal(imp.base) {
+		return ErrMenuChanged
+	}
+	if len(imp.Changes) == 0 {
+		return nil
+	}
+	data = imp.result
+	changed(time.Now())
+	return nil
+}
+
+// importer checks the items from a file one at a time against a copy of the menu. Every format reads its file
+// into menu items and hands them to put
+type importer struct {
+	m    menu
+	base time.Time
+	errs []LineError
+	seen map[string]int // ID to the line it was first on, one by name and one by ID could be the same item
+
+	changes []Change
+	amount  func(float64) string // Writes prices for the changes, in the base menu's currency
+}
+
+func newImporter() *importer {
+	mu.RLock()
+	defer mu.RUnlock()
+	return &importer{m: slices.Clone(data), base: modified, seen: map[string]int{}, amount: moneyAt(nil).Number}
+}
+
+// existing finds the item a file means, by its ID when the file has one. It's -1 for a new item
+func (im *importer) existing(id, name string) (int, error) {
+	if id == "" {
+		return im.m.find(name), nil
+	}
+	if i := im.m.find(id); i >= 0 && im.m[i].id == id {
+		return i, nil
+	}
+	return -1, fmt.Errorf("no item has the ID %v: %w", id, ErrItemNotFound)
+}
+
+// put checks item and puts it in place of the item at i, or on the end for a new one
+func (im *importer) put(line, i int, item menuItem) {
+	if i >= 0 {
+		if first, dup := im.seen[im.m[i].id]; dup {
+			im.fail(line, fmt.Errorf("%v is already on line %v", im.m[i].name, first))
+			return
+		}
+	}
+	if err := im.m.validate(item, i); err != nil {
+		im.fail(line, err)
+		return
+	}
+
+	var change Change
+	if i < 0 {
+		item.id = im.m.newID(item.name)
+		im.m = append(im.m, item)
+		change = Change{ID: item.id, Name: item.name, New: true, Changes: describeNew(item, im.amount)}
+	} else {
+		change = Change{ID: item.id, Name: item.name, Changes: diff(im.m[i], item, im.amount)}
+		im.m[i] = item
+	}
+	im.seen[item.id] = line
+	if len(change.Changes) > 0 {
+		im.changes = append(im.changes, change)
+	}
+}
+
+// fail notes a problem. Errors that already know their line keep it
+func (im *importer) fail(line int, err error) {
+	var le LineError
+	if !errors.As(err, &le) {
+		le = LineError{Line: line, Err: err}
+	}
+	im.errs = append(im.errs, le)
+}
+
+// done is the finished Import, or every problem there was
+func (im *importer) done() (*Import, error) {
+	if len(im.errs) > 0 {
+		slices.SortStableFunc(im.errs, func(a, b LineError) int { return a.Line - b.Line }) // Checking items found some of them later
+		return nil, &ImportError{im.errs}
+	}
+	return &Import{Changes: im.changes, result: im.m, base: im.base}, nil
+}
+
+// describeNew lists what a new item comes with, for the preview
+func describeNew(item menuItem, amount func(float64) string) []string {
+	out := []string{"new"}
+	if item.category != "" {
+		out = append(out, "category "+item.category)
+	}
+	for _, size := range sortedSizes(item.prices) {
+		out = append(out, fmt.Sprintf("%v %v", size, amount(item.prices[size])))
+	}
+	if item.soldOut {
+		out = append(out, "sold out")
+	}
+	if item.allergens != 0 {
+		out = append(out, "allergens "+item.allergens.String())
+	}
+	return out
+}
+
+// diff lists the differences between two versions of an item
+func diff(before, after menuItem, amount func(float64) string) []string {
+	var out []string
+	if before.name != after.name {
+		out = append(out, fmt.Sprintf("renamed from %v", before.name))
+	}
+	if before.category != after.category {
+		out = append(out, fmt.Sprintf("category %q -> %q", before.category, after.category))
+	}
+	if !slices.Equal(before.tags, after.tags) {
+		out = append(out, fmt.Sprintf("tags %v -> %v", strings.Join(before.tags, ", "), strings.Join(after.tags, ", ")))
+	}
+	sizes := sortedSizes(before.prices)
+	for _, size := range sortedSizes(after.prices) {
+		if !slices.Contains(sizes, size) {
+			sizes = append(sizes, size)
+		}
+	}
+	for _, size := range sizes {
+		was, had := before.prices[size]
+		now, has := after.prices[size]
+		switch {
+		case !had:
+			out = append(out, fmt.Sprintf("%v added at %v", size, amount(now)))
+		case !has:
+			out = append(out, fmt.Sprintf("%v removed", size))
+		case was != now:
+			out = append(out, fmt.Sprintf("%v %v -> %v", size, amount(was), amount(now)))
+		}
+	}
+	if before.soldOut != after.soldOut {
+		if after.soldOut {
+			out = append(out, "sold out")
+		} else {
+			out = append(out, "back in stock")
+		}
+	}
+	if before.allergens != after.allergens {
+		out = append(out, fmt.Sprintf("allergens %v -> %v", before.allergens, after.allergens))
+	}
+	if before.diet != after.diet {
+		out = append(out, fmt.Sprintf("diet %v -> %v", before.diet, after.diet))
+	}
+	return out
+}
+
+// keepNutrition is the nutrition for the sizes an item still comes in. The rest would fail validation, the facts
+// go with the size
+func keepNutrition(nutrition map[string]Nutrition, prices map[string]float64) map[string]Nutrition {
+	nutrition = maps.Clone(nutrition)
+	maps.DeleteFunc(nutrition, func(size string, _ Nutrition) bool { _, ok := prices[size]; return !ok })
+	return nutrition
+}
+
+func sortedSizes(prices map[string]float64) []string {
+	sizes := make([]string, 0, len(prices))
+	for size := range prices {
+		sizes = append(sizes, size)
+	}
+	slices.Sort(sizes)
+	return sizes
+}
@@ -0,0 +1,157 @@
+package menu
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// MARK: Lexer
+
+// tokenKind is what sort of thing a token is in a .menu file
+type tokenKind int
+
+const (
+	tokEOF     tokenKind = iota
+	tokNewline           // Lines matter, every item and every size is on its own
+	tokText              // Anything else, with the spaces inside it kept: Chai Latte, small, 3.50
+	tokString            // "Quoted", for text with one of the characters below in it
+	tokLBracket
+	tokRBracket
+	tokEquals
+	tokAt
+	tokLParen
+	tokRParen
+	tokComma
+	tokError // text is the message
+)
+
+func (k tokenKind) String() string {
+	switch k {
+	case tokEOF:
+		return "the end of the file"
+	case tokNewline:
+		return "the end of the line"
+	case tokText, tokString:
+		return "text"
+	case tokError:
+		return "an error"
+	}
+	return strconv.Quote(string(punctuation[k-tokLBracket]))
+}
+
+// punctuation are the characters that mean something, in the same order as the tokens for them. Text stops at
+// any of them, along with # for comments and " for strings
+const punctuation = "[]=@(),"
+
+// token is one piece of the file. line and col are where it starts, counting from 1, with col in characters
+type token struct {
+	kind      tokenKind
+	text      string
+	line, col int
+}
+
+// lexer splits a .menu file into tokens, one at a time as the parser asks for them
+type lexer struct {
+	src       string
+	pos       int
+	line, col int
+}
+
+func newLexer(src string) *lexer {
+	return &lexer{src: src, line: 1, col: 1}
+}
+
+// peek is the next character without using it up, or -1 at the end
+func (l *lexer) peek() rune {
+	if l.pos >= len(l.src) {
+		return -1
+	}
+	r, _ := utf8.DecodeRuneInString(l.src[l.pos:])
+	return r
+}
+
+// step uses up one character
+func (l *lexer) step() rune {
+	r, n := utf8.DecodeRuneInString(l.src[l.pos:])
+	l.pos += n
+	if r == '\n' {
+		l.line++
+		l.col = 1
+	} else {
+		l.col++
+	}
+	return r
+}
+
+// next reads the next token
+func (l *lexer) next() token {
+	// Spaces and comments don't count for anything
+	for {
+		switch l.peek() {
+		case ' ', '\t', '\r', '\uFEFF': // A byte order mark from editors that add one
+			l.step()
+			continue
+		case '#':
+			for r := l.peek(); r != '\n' && r != -1; r = l.peek() {
+				l.step()
+			}
+			continue
+		}
+		break
+	}
+
+	tok := token{line: l.line, col: l.col}
+	r := l.peek()
+	switch {
+	case r == -1:
+		tok.kind = tokEOF
+	case r == '\n':
+		l.step()
+		tok.kind = tokNewline
+	case strings.ContainsRune(punctuation, r):
+		l.step()
+		tok.kind = tokLBracket + tokenKind(strings.IndexRune(punctuation, r))
+	case r == '"':
+		l.str(&tok)
+	default:
+		start, end := l.pos, l.pos
+		for r := l.peek(); r != -1 && r != '\n' && r != '#' && r != '"' && !strings.ContainsRune(punctuation, r); r = l.peek() {
+			l.step()
+			if r != ' ' && r != '\t' && r != '\r' {
+				end = l.pos // So the spaces at the end aren't part of it
+			}
+		}
+		tok.kind, tok.text = tokText, l.src[start:end]
+	}
+	return tok
+}
+
+// str reads a quoted string, with the same escapes as Go
+func (l *lexer) str(tok *token) {
+	start := l.pos
+	l.step() // The opening quote
+	for {
+		switch l.peek() {
+		case -1, '\n':
+			tok.kind, tok.text = tokError, "the quotes aren't closed"
+			return
+		case '\\':
+			l.step()
+			if r := l.peek(); r == -1 || r == '\n' {
+				continue // Reported as not closed above
+			}
+		case '"':
+			l.step()
+			s, err := strconv.Unquote(l.src[start:l.pos])
+			if err != nil {
+				tok.kind, tok.text = tokError, fmt.Sprintf("%v isn't a proper quoted string", l.src[start:l.pos])
+				return
+			}
+			tok.kind, tok.text = tokString, s
+			return
+		}
+		l.step()
+	}
+}
@@ -4,48 +4,187 @@ import (
 	"bufio"
 	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"os"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 )
 
 type menuItem struct {
-	name   string
-	prices map[string]float64
+	id        string // Stays the same for the life of the item, see id.go
+	key       string // The name folded for comparing, so "latte" and "Latte " are the same item
+	name      string
+	prices    map[string]float64
+	category  string               // coffee, tea, ...
+	tags      []string             // Anything else people might look for (hot, iced, vegan...)
+	soldOut   bool                 // Still on the menu, but can't be ordered right now
+	allergens Allergen             // What's in it as it comes, before any modifiers
+	diet      Diet                 // Only vegan and vegetarian are stored, the rest come from the allergens
+	nutrition map[string]Nutrition // Per size, same keys as prices
+
+	description  string                 // A line for the board, in the shop's language
+	translations map[string]Translation // By language tag, see translate.go
+}
+
+// Details are the optional parts of a new item
+type Details struct {
+	Category    string
+	Tags        []string
+	SoldOut     bool
+	Allergens   Allergen
+	Diet        Diet
+	Nutrition   map[string]Nutrition // Per size
+	Description string
+}
+
+// diets is the full set of diets the item fits as it comes
+func (item menuItem) diets() Diet {
+	return item.diet.withDerived(item.allergens)
 }
 
 type menu []menuItem
 
+// mu guards data, so an edit is either all the way in or not there at all when something else (like Save) looks at it
+var mu sync.RWMutex
+
 // Method
-func (m menu) print() {
-	for _, item := range m {
-		fmt.Println(item.name)
-		fmt.Println(strings.Repeat("-", 10))
-		for size, cost := range item.prices {
-			fmt.Printf("\t%10s%10.2f\n", size, cost)
+// find returns the index of the item with the ID or name ref, or -1. Names don't care about case, accents or
+// extra spaces
+func (m menu) find(ref string) int {
+	ref = strings.TrimSpace(ref)
+	if strings.HasPrefix(ref, idPrefix) {
+		if i := slices.IndexFunc(m, func(item menuItem) bool { return item.id == ref }); i >= 0 {
+			return i
 		}
 	}
+	key := nameKey(ref)
+	return slices.IndexFunc(m, func(item menuItem) bool { return item.key == key })
 }
 
+// add reads a new item's name from the input. The caller has asked for it, in whatever language it's talking
 func (m *menu) add() error {
-	fmt.Println("Please enter the name of the new item")
-	name, _ := in.ReadString('\n')
+	name, err := in.ReadString('\n')
 	name = strings.TrimSpace(name)
-	for _, item := range data {
-		if item.name == name {
-			return errors.New("menu item already exists")
-		}
+	if err != nil && (!errors.Is(err, io.EOF) || name == "") { // A last line without a newline is still a name
+		return fmt.Errorf("reading item name: %w", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	item := newItem(name, nil, Details{})
+	if err := m.validate(item, -1); err != nil { // Against m, the menu we're adding to
+		return err
 	}
-	*m = append(*m, menuItem{name: name, prices: make(map[string]float64)})
+	item.id = m.newID(item.name)
+	*m = append(*m, item)
+	changed(time.Now())
 	return nil // Returned with no error
 }
 
+// insert puts an item that was saved before back on the menu. It doesn't go through validate, so a menu file
+// from before a rule was added still loads, and it leaves the ID for the caller
+func (m *menu) insert(name string, prices map[string]float64, d Details) error {
+	item := newItem(name, prices, d)
+	for _, other := range *m {
+		if nameKey(other.name) == item.key { // The same item to find, however it was written
+			return fmt.Errorf("%q: %w", item.name, ErrDuplicateItem)
+		}
+	}
+	if requireNutrition {
+		if err := item.checkNutrition(); err != nil {
+			return err
+		}
+	}
+	*m = append(*m, item)
+	return nil
+}
+
+// newItem makes an item from its parts, with its own copies of the maps and the tags tidied up
+func newItem(name string, prices map[string]float64, d Details) menuItem {
+	p := make(map[string]float64, len(prices))
+	for size, cost := range prices {
+		p[size] = cost
+	}
+	n := make(map[string]Nutrition, len(d.Nutrition))
+	for size, facts := range d.Nutrition {
+		n[size] = facts
+	}
+	return menuItem{
+		key:       nameKey(name),
+		name:      strings.TrimSpace(name),
+		prices:    p,
+		category:  strings.ToLower(strings.TrimSpace(d.Category)),
+		tags:      cleanTags(d.Tags),
+		soldOut:   d.SoldOut,
+		allergens: d.Allergens,
+		diet:      d.Diet &^ (GlutenFree | DairyFree), // Those two always come from the allergens
+		nutrition: n,
+
+		description: strings.TrimSpace(d.Description),
+	}
+}
+
+// cleanTags lower cases the tags and drops blanks and repeats
+func cleanTags(tags []string) []string {
+	var out []string
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t != "" && !slices.Contains(out, t) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
 var in = bufio.NewReader(os.Stdin)
 
+// SetInput lets the caller share its reader with us. Two bufio readers on stdin would each buffer part of the input
+func SetInput(r *bufio.Reader) {
+	in = r
+}
+
 // Functions
 func AddItem() error {
 	return data.add()
 }
 
-func PrintMenu() {
-	data.print()
+// Add puts a new item on the menu without prompting for anything. A *ValidationError lists everything wrong with it
+func Add(name string, prices map[string]float64, d Details) error {
+	mu.Lock()
+	defer mu.Unlock()
+	item := newItem(name, prices, d)
+	if err := data.validate(item, -1); err != nil {
+		return err
+	}
+	item.id = data.newID(item.name)
+	data = append(data, item)
+	changed(time.Now())
+	return nil
+}
+
+// SetPrice changes what one size of an item costs, adding the size if it's new. It returns the old price, 0 for a
+// new size
+func SetPrice(name, size string, price float64) (float64, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	i := data.find(name)
+	if i < 0 {
+		return 0, notFound(name)
+	}
+
+	// Check a copy with the new price, so the menu is untouched if it's no good
+	size = strings.TrimSpace(size)
+	item := data[i]
+	item.prices = maps.Clone(item.prices)
+	item.prices[size] = price
+	if err := data.validate(item, i); err != nil {
+		return 0, err
+	}
+	old := data[i].prices[size]
+	data[i] = item
+	changed(time.Now())
+	return old, nil
 }
@@ -0,0 +1,448 @@
+package menu
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// MARK: .menu Files
+
+// A .menu file is the menu written for people to edit by hand. Each item has its sizes underneath it, a
+// [category] heading starts a new category and anything after a # is a comment:
+//
+//	Espresso @id(item-434e3539) @hot
+//	  single = 1.75
+//	  double = 2.25 @nutrition(calories = 10)
+//
+//	[tea]
+//
+//	Chai Latte @hot @vegetarian @contains(milk)
+//	  small = 3.10
+//	  large = 3.60
+//
+// After an item's name come @ annotations. @id, @sold-out, @vegan, @vegetarian, @contains(allergens...) mean what
+// they say and anything else is a tag. Names with any of []=@(),#" in them go in quotes, like "Tea (Green)".
+// Indenting is only for looks
+
+// fileItem is an item as it was in the file, with where it was for errors
+type fileItem struct {
+	line, col int
+	item      storedItem
+}
+
+// parser reads a .menu file a token at a time, carrying on at the next line after a mistake so it can report
+// every one of them
+type parser struct {
+	lex      *lexer
+	tok      token
+	category string
+	items    []fileItem
+	errs     []LineError
+}
+
+// parseMenuFile reads a .menu file into items, or returns every mistake in it
+func parseMenuFile(src string) ([]fileItem, []LineError) {
+	p := &parser{lex: newLexer(src)}
+	p.advance()
+	for p.tok.kind != tokEOF {
+		p.line()
+	}
+	return p.items, p.errs
+}
+
+func (p *parser) advance() {
+	p.tok = p.lex.next()
+}
+
+// syntaxError is thrown by fail and caught by line, so each line can give up at its first mistake
+type syntaxError struct{}
+
+// fail records a mistake at tok and gives up on the line
+func (p *parser) fail(tok token, format string, args ...any) {
+	if tok.kind == tokError {
+		format, args = "%v", []any{tok.text} // Whatever the lexer said is more use than what we expected
+	}
+	p.errs = append(p.errs, LineError{Line: tok.line, Col: tok.col, Err: fmt.Errorf(format, args...)})
+	panic(syntaxError{})
+}
+
+// expect uses up a token of the given kind or fails
+func (p *parser) expect(kind tokenKind, what string) token {
+	tok := p.tok
+	if tok.kind != kind {
+		p.fail(tok, "expected %v, found %v", what, describe(tok))
+	}
+	p.advance()
+	return tok
+}
+
+// describe says what a token is, for errors
+func describe(tok token) string {
+	if tok.kind == tokText || tok.kind == tokString {
+		return strconv.Quote(tok.text)
+	}
+	return tok.kind.String()
+}
+
+// line reads one line: blank, a [category], an item or a size
+func (p *parser) line() {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(syntaxError); !ok {
+				panic(r)
+			}
+			for p.tok.kind != tokNewline && p.tok.kind != tokEOF {
+				p.advance() // Skip the rest of the line and carry on with the next one
+			}
+		}
+	}()
+
+	switch p.tok.kind {
+	case tokNewline:
+	case tokLBracket:
+		p.advance()
+		name := p.tok
+		if name.kind != tokText && name.kind != tokString || strings.TrimSpace(name.text) == "" {
+			p.fail(name, "expected a category name after [, found %v", describe(name))
+		}
+		p.advance()
+		p.expect(tokRBracket, "]")
+		p.category = strings.ToLower(strings.TrimSpace(name.text))
+	case tokText, tokString:
+		name := p.tok
+		p.advance()
+		if p.tok.kind == tokEquals {
+			p.size(name)
+		} else {
+			p.item(name)
+		}
+	default:
+		p.fail(p.tok, "expected an item, a size or a [category], found %v", describe(p.tok))
+	}
+	if p.tok.kind != tokEOF {
+		p.expect(tokNewline, "the end of the line")
+	}
+}
+
+// item reads the rest of an item's line, its annotations
+func (p *parser) item(name token) {
+	// In before the annotations are read, so the sizes below still have somewhere to go if one is wrong
+	p.items = append(p.items, fileItem{line: name.line, col: name.col, item: storedItem{Name: name.text, Category: p.category, Prices: map[string]float64{}}})
+	item := &p.items[len(p.items)-1].item
+	for p.tok.kind == tokAt {
+		p.advance()
+		tag := p.tok
+		switch {
+		case tag.kind == tokString:
+			p.advance()
+			item.Tags = append(item.Tags, tag.text) // Quoted is always a tag, even one called vegan
+			continue
+		case tag.kind != tokText:
+			p.fail(tag, "expected a tag after @, found %v", describe(tag))
+		case strings.ContainsAny(tag.text, " \t"):
+			p.fail(tag, "tags can't have spaces in them, put quotes round %q if it's one tag", tag.text)
+		}
+		p.advance()
+		args := p.args()
+		takes := func(n int) {
+			if n >= 0 && len(args) != n || n < 0 && len(args) == 0 {
+				p.fail(tag, "@%v needs %v", tag.text, map[int]string{-1: "at least one argument", 0: "no arguments", 1: "one argument"}[n])
+			}
+			for _, arg := range args {
+				if arg.value != nil {
+					p.fail(*arg.value, "@%v doesn't take name = value arguments", tag.text)
+				}
+			}
+		}
+		switch tag.text {
+		case "id":
+			takes(1)
+			if item.ID != "" {
+				p.fail(tag, "the item already has an @id")
+			}
+			item.ID = args[0].key.text
+		case "sold-out":
+			takes(0)
+			item.SoldOut = true
+		case "vegan", "vegetarian":
+			takes(0)
+			item.Diet = append(item.Diet, tag.text)
+		case "contains":
+			takes(-1)
+			for _, arg := range args {
+				if _, err := ParseAllergens(arg.key.text); err != nil {
+					p.fail(arg.key, "%v", err)
+				}
+				item.Allergens = append(item.Allergens, arg.key.text)
+			}
+		default:
+			takes(0)
+			item.Tags = append(item.Tags, tag.text)
+		}
+	}
+	if p.tok.kind != tokNewline && p.tok.kind != tokEOF {
+		p.fail(p.tok, "expected @ or the end of the line, found %v. Names with any of %v#\" in them need quotes", describe(p.tok), punctuation)
+	}
+}
+
+// size reads a size = price line, which belongs to the item above it
+func (p *parser) size(name token) {
+	if len(p.items) == 0 {
+		p.fail(name, "%q needs an item above it", name.text)
+	}
+	item := &p.items[len(p.items)-1].item
+	if _, dup := item.Prices[name.text]; dup {
+		p.fail(name, "%v is already on %v", name.text, item.Name)
+	}
+	p.advance() // The =
+	price := p.tok
+	if price.kind != tokText {
+		p.fail(price, "expected a price after =, found %v", describe(price))
+	}
+	p.advance()
+	item.Prices[name.text] = p.number(price, "a price")
+
+	for p.tok.kind == tokAt {
+		p.advance()
+		tag := p.expect(tokText, "@nutrition")
+		if tag.text != "nutrition" {
+			p.fail(tag, "a size can only have @nutrition, not @%v", tag.text)
+		}
+		var n Nutrition
+		fields := map[string]*float64{"calories": &n.Calories, "fat": &n.Fat, "carbs": &n.Carbs, "sugar": &n.Sugar, "protein": &n.Protein}
+		for _, arg := range p.args() {
+			field, ok := fields[arg.key.text]
+			if !ok || arg.value == nil {
+				p.fail(arg.key, "expected one of calories, fat, carbs, sugar or protein = a number")
+			}
+			*field = p.number(*arg.value, "a number")
+		}
+		if item.Nutrition == nil {
+			item.Nutrition = map[string]Nutrition{}
+		}
+		item.Nutrition[name.text] = n
+	}
+}
+
+// number reads a price or nutrition fact
+func (p *parser) number(tok token, what string) float64 {
+	f, err := strconv.ParseFloat(tok.text, 64)
+	if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+		p.fail(tok, "%q isn't %v", tok.text, what)
+	}
+	return f
+}
+
+// arg is one argument to an annotation, key on its own or key = value
+type arg struct {
+	key   token
+	value *token
+}
+
+// args reads the (a, b = c) after an annotation, if there is one
+func (p *parser) args() []arg {
+	if p.tok.kind != tokLParen {
+		return nil
+	}
+	p.advance()
+	var args []arg
+	for p.tok.kind != tokRParen {
+		if len(args) > 0 {
+			p.expect(tokComma, ", or )")
+		}
+		var a arg
+		if a.key = p.tok; a.key.kind != tokText && a.key.kind != tokString {
+			p.fail(a.key, "expected an argument, found %v", describe(a.key))
+		}
+		p.advance()
+		if p.tok.kind == tokEquals {
+			p.advance()
+			value := p.tok
+			if value.kind != tokText && value.kind != tokString {
+				p.fail(value, "expected a value after =, found %v", describe(value))
+			}
+			p.advance()
+			a.value = &value
+		}
+		args = append(args, a)
+	}
+	p.advance()
+	return args
+}
+
+// MARK: Formatting
+
+// WriteMenuFile writes the whole menu as a .menu file. Reading it back with ReadMenuFile changes nothing
+func WriteMenuFile(w io.Writer) error {
+	mu.RLock()
+	items := data.stored().Items
+	mu.RUnlock()
+	_, err := io.WriteString(w, formatMenuFile(items))
+	return err
+}
+
+// formatMenuFile is the one way to write items as a .menu file. Items without a category come first, then each
+// category in the order it first comes up
+func formatMenuFile(items []storedItem) string {
+	var categories []string
+	for _, item := range items {
+		if !slices.Contains(categories, item.Category) {
+			categories = append(categories, item.Category)
+		}
+	}
+	slices.SortStableFunc(categories, func(a, b string) int { return boolInt(a != "") - boolInt(b != "") })
+
+	var b strings.Builder
+	for _, category := range categories {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		if category != "" {
+			fmt.Fprintf(&b, "[%v]\n\n", quote(category))
+		}
+		first := true
+		for _, item := range items {
+			if item.Category != category {
+				continue
+			}
+			if !first {
+				b.WriteString("\n")
+			}
+			first = false
+			formatItem(&b, item)
+		}
+	}
+	return b.String()
+}
+
+func formatItem(b *strings.Builder, item storedItem) {
+	b.WriteString(quote(item.Name))
+	if item.ID != "" {
+		fmt.Fprintf(b, " @id(%v)", quote(item.ID))
+	}
+	for _, tag := range item.Tags {
+		if tag == "" || strings.ContainsAny(tag, " \t") || slices.Contains([]string{"id", "sold-out", "vegan", "vegetarian", "contains", "nutrition"}, tag) {
+			fmt.Fprintf(b, " @%v", strconv.Quote(tag)) // Quoted so it can't be taken for one of ours
+		} else {
+			fmt.Fprintf(b, " @%v", quote(tag))
+		}
+	}
+	if item.SoldOut {
+		b.WriteString(" @sold-out")
+	}
+	for _, diet := range item.Diet {
+		fmt.Fprintf(b, " @%v", diet)
+	}
+	if len(item.Allergens) > 0 {
+		quoted := make([]string, len(item.Allergens))
+		for i, a := range item.Allergens {
+			quoted[i] = quote(a)
+		}
+		fmt.Fprintf(b, " @contains(%v)", strings.Join(quoted, ", "))
+	}
+	b.WriteString("\n")
+
+	sizes := make([]string, 0, len(item.Prices))
+	for size := range item.Prices {
+		sizes = append(sizes, size)
+	}
+	slices.SortFunc(sizes, compareSizes)
+	for _, size := range sizes {
+		fmt.Fprintf(b, "  %v = %v", quote(size), strconv.FormatFloat(item.Prices[size], 'f', 2, 64))
+		if n, ok := item.Nutrition[size]; ok {
+			fmt.Fprintf(b, " @nutrition(calories = %v, fat = %v, carbs = %v, sugar = %v, protein = %v)",
+				num(n.Calories), num(n.Fat), num(n.Carbs), num(n.Sugar), num(n.Protein))
+		}
+		b.WriteString("\n")
+	}
+}
+
+// compareSizes puts sizes smallest first when they're on one of the ladders, and the rest after them in
+// alphabetical order
+func compareSizes(a, b string) int {
+	rank := func(size string) int {
+		for i, ladder := range sizeLadders {
+			if j := slices.Index(ladder, size); j >= 0 {
+				return i*100 + j
+			}
+		}
+		return math.MaxInt
+	}
+	if ra, rb := rank(a), rank(b); ra != rb {
+		if ra < rb {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// quote puts text in quotes when it has to be, so it reads back as the same thing
+func quote(s string) string {
+	if s == "" || s != strings.TrimSpace(s) || strings.ContainsAny(s, punctuation+"#\"\n\r\t\uFEFF") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+func num(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// MARK: Importing .menu Files
+
+// ReadMenuFile checks a .menu file against the menu and works out what it would change, like ReadCSV. Every item
+// in the file ends up the way the file says, except that nutrition it doesn't give for a size is kept. Mistakes
+// come back as an *ImportError, with the line and column of each one
+func ReadMenuFile(r io.Reader) (*Import, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	items, errs := parseMenuFile(string(b))
+	if len(errs) > 0 {
+		return nil, &ImportError{errs}
+	}
+
+	im := newImporter()
+	for _, fi := range items {
+		s := fi.item
+		i, err := im.existing(s.ID, s.Name)
+		if err != nil {
+			im.fail(fi.line, err)
+			continue
+		}
+		d := Details{Category: s.Category, Tags: s.Tags, SoldOut: s.SoldOut}
+		d.Allergens, _ = ParseAllergens(strings.Join(s.Allergens, ",")) // The parser checked them
+		d.Diet, _ = ParseDiets(strings.Join(s.Diet, ","))
+
+		item := newItem(s.Name, s.Prices, d)
+		if i >= 0 {
+			item.id = im.m[i].id
+			if s.ID == "" {
+				item.name, item.key = im.m[i].name, im.m[i].key // Found by name, it's just spelled differently
+			}
+			item.nutrition = keepNutrition(im.m[i].nutrition, item.prices)
+			item.description, item.translations = im.m[i].description, im.m[i].translations // The file doesn't have them
+		}
+		for size, n := range s.Nutrition {
+			if item.nutrition == nil {
+				item.nutrition = map[string]Nutrition{}
+			}
+			item.nutrition[size] = n
+		}
+		im.put(fi.line, i, item)
+	}
+	return im.done()
+}
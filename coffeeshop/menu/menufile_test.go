@@ -0,0 +1,106 @@
+package menu
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestMenuFile(t *testing.T) {
+	useTestMenu(t)
+	data[1].nutrition = map[string]Nutrition{"small": {Calories: 120, Fat: 4.5}}
+
+	// What we write we can read back, and it changes nothing
+	var b bytes.Buffer
+	if err := WriteMenuFile(&b); err != nil {
+		t.Fatal(err)
+	}
+	written := b.String()
+	imp, err := ReadMenuFile(&b)
+	if err != nil {
+		t.Fatalf("%v in\n%v", err, written)
+	}
+	if len(imp.Changes) != 0 {
+		t.Errorf("Round trip: got changes %v, expected none", imp.Changes)
+	}
+
+	// Written by hand, with the sizes in any order and the items found by name
+	file := `# Comments are fine
+[Coffee]
+latte @hot @contains(milk)
+    large = 3.60
+    small = 3.00
+
+[tea]
+"Green Tea (Loose)" @hot @vegan
+  pot = 2.80
+`
+	imp, err = ReadMenuFile(strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imp.Changes) != 2 || imp.Changes[0].String() != "~ Latte: large 3.50 -> 3.60" || !imp.Changes[1].New {
+		t.Errorf("Got changes %q", imp.Changes)
+	}
+	if err := imp.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	if n := data[data.find("Latte")].nutrition; n["small"].Calories != 120 {
+		t.Errorf("Got nutrition %v, expected the small size's to be kept", n)
+	}
+
+	// Every line with a mistake, and where on the line it is
+	file = `Latte
+  small = 3.00 @nutrition(salt = 1)
+  small = 3.10
+Tea (Green)
+Mocha @contains(milk, gravel)
+"Unclosed
+`
+	_, err = ReadMenuFile(strings.NewReader(file))
+	var ie *ImportError
+	if !errors.As(err, &ie) {
+		t.Fatalf("Got %v, expected an ImportError", err)
+	}
+	expect := []string{"2:27", "3:3", "4:5", "5:23", "6:1"}
+	var got []string
+	for _, l := range ie.Lines {
+		got = append(got, fmt.Sprintf("%v:%v", l.Line, l.Col))
+	}
+	if strings.Join(got, " ") != strings.Join(expect, " ") {
+		t.Errorf("Got errors at %v, expected %v:\n%v", got, expect, err)
+	}
+}
+
+// FuzzMenuFile checks the parser doesn't fall over whatever it's given, and that anything it reads comes out of
+// the formatter the same way every time
+func FuzzMenuFile(f *testing.F) {
+	f.Add(formatMenuFile(testMenu().stored().Items))
+	f.Add("[coffee]\nLatte @id(item-1) @hot @\"vegan\" @sold-out @contains(milk, tree nuts)\n  small = 3 @nutrition(calories = 1e2)\n")
+	f.Add("\"a\\\"b\" # comment\n  \"x y\" = 1.005\n[\"\"]\n")
+	f.Add("= ( ) , @ [\n]")
+	f.Fuzz(func(t *testing.T, src string) {
+		items, errs := parseMenuFile(src)
+		if len(errs) > 0 {
+			return
+		}
+		stored := make([]storedItem, len(items))
+		for i, fi := range items {
+			stored[i] = fi.item
+		}
+		once := formatMenuFile(stored)
+		again, errs := parseMenuFile(once)
+		if len(errs) > 0 {
+			t.Fatalf("Can't read what was written: %v\n%v", errs, once)
+		}
+		stored = stored[:0]
+		for _, fi := range again {
+			stored = append(stored, fi.item)
+		}
+		if twice := formatMenuFile(stored); twice != once {
+			t.Fatalf("Formatting isn't stable:\n%v\n---\n%v", once, twice)
+		}
+	})
+}
@@ -0,0 +1,140 @@
+package menu
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"demo/coffeeshop/money"
+)
+
+// MARK: Currency
+
+// currency and locale are what the base menu's prices are in and how they're written, saved with the menu and
+// guarded by mu. Both empty is how menus were before, plain numbers with two decimals
+var currency, locale string
+
+// Money is how prices are written at a store, or on the base menu for "". A store without its own currency or
+// locale uses the base menu's
+func Money(store string) (money.Format, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	s, err := findStore(store)
+	if err != nil {
+		return money.Format{}, err
+	}
+	return moneyAt(s), nil
+}
+
+// moneyAt is the format for a store, nil for the base menu. The codes were checked when they were set, so a bad
+// one can't get here. mu has to be held
+func moneyAt(s *Store) money.Format {
+	c, l := currency, locale
+	if s != nil && s.Currency != "" && s.Currency != currency {
+		c, l = s.Currency, "" // The base menu's locale goes with the base menu's currency
+	}
+	if s != nil && s.Locale != "" {
+		l = s.Locale
+	}
+	f, _ := money.New(c, l)
+	return f
+}
+
+// rateAt is what a base price is multiplied by to get it in a store's currency, 1 when it's the same currency
+func rateAt(s *Store) float64 {
+	if s == nil || s.Currency == "" || s.Currency == currency {
+		return 1
+	}
+	return s.Rate
+}
+
+// maxPriceAt is the most anything can cost at a store, maxPrice in the base currency converted. A base menu
+// in a currency with no decimals gets maxPrice in hundreds, so 10000 yen
+func maxPriceAt(s *Store) float64 {
+	if r := rateAt(s); r != 1 {
+		return moneyAt(s).Currency.Round(maxPrice * r)
+	}
+	return maxPrice * math.Pow10(2-moneyAt(nil).Currency.Places())
+}
+
+// SetCurrency changes what the base menu is priced in and how prices are written. Every price on the menu (and
+// at the stores that use the base currency) has to make sense in the new currency, so going to yen with 3.50 on
+// the menu is turned down
+func SetCurrency(code, loc string) error {
+	f, err := money.New(code, loc)
+	if err != nil {
+		return &ValidationError{Problems: []FieldError{{Field: "currency", Message: err.Error()}}}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	was, wasLocale := currency, locale
+	currency, locale = f.Currency.Code, strings.TrimSpace(loc)
+	if locale != "" {
+		locale = f.Locale.Tag
+	}
+	if err := checkPrices(); err != nil {
+		currency, locale = was, wasLocale
+		return err
+	}
+	if currency != was || locale != wasLocale {
+		changed(time.Now())
+	}
+	return nil
+}
+
+// Currency is what the base menu is priced in and the locale it's written for, "" for either when not set
+func Currency() (code, loc string) {
+	mu.RLock()
+	defer mu.RUnlock()
+	return currency, locale
+}
+
+// MARK: Tax
+
+// taxRate is the sales tax in the prices, 0.2 for 20%. Prices on the menu include it, so it doesn't change what
+// anyone pays, just how much of it the shop hands on. Saved with the menu and guarded by mu
+var taxRate float64
+
+// SetTaxRate changes the sales tax the prices include, as a fraction: 0.0825 for 8.25%
+func SetTaxRate(rate float64) error {
+	if math.IsNaN(rate) || rate < 0 || rate >= 1 {
+		return &ValidationError{Problems: []FieldError{{Field: "taxRate", Message: fmt.Sprintf("has to be a fraction from 0 up to 1, like 0.2 for 20%%, got %v", rate)}}}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if taxRate != rate {
+		taxRate = rate
+		changed(time.Now())
+	}
+	return nil
+}
+
+// TaxRate is the sales tax the prices include, 0 when the shop hasn't said
+func TaxRate() float64 {
+	mu.RLock()
+	defer mu.RUnlock()
+	return taxRate
+}
+
+// checkPrices validates every item on the base menu and at every store, for after the rules for prices change.
+// mu has to be held
+func checkPrices() error {
+	var errs []error
+	for i, item := range data {
+		if err := data.validate(item, i); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for i := range stores {
+		s := &stores[i]
+		m := data.at(s)
+		for _, item := range m {
+			if err := m.validateAt(item, m.find(item.id), s); err != nil {
+				errs = append(errs, fmt.Errorf("at %v: %w", s.Name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,135 @@
+package menu
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MARK: Nutrition
+
+// Nutrition is per serving. Grams for everything apart from the calories
+type Nutrition struct {
+	Calories float64 `json:"calories"`
+	Fat      float64 `json:"fat"`
+	Carbs    float64 `json:"carbs"`
+	Sugar    float64 `json:"sugar"`
+	Protein  float64 `json:"protein"`
+}
+
+// Plus adds o to n, used to put a modifier's nutrition on top of the drink's
+func (n Nutrition) Plus(o Nutrition) Nutrition {
+	return Nutrition{
+		Calories: n.Calories + o.Calories,
+		Fat:      n.Fat + o.Fat,
+		Carbs:    n.Carbs + o.Carbs,
+		Sugar:    n.Sugar + o.Sugar,
+		Protein:  n.Protein + o.Protein,
+	}
+}
+
+// Times scales n, for an order line with more than one of something
+func (n Nutrition) Times(f float64) Nutrition {
+	return Nutrition{n.Calories * f, n.Fat * f, n.Carbs * f, n.Sugar * f, n.Protein * f}
+}
+
+// valid is whether every value is a real amount, 0 or more
+func (n Nutrition) valid() bool {
+	for _, v := range []float64{n.Calories, n.Fat, n.Carbs, n.Sugar, n.Protein} {
+		if !weighable(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// weighable is a number that can be weighed: not negative, NaN or infinite
+func weighable(v float64) bool {
+	return v >= 0 && !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+func (n Nutrition) String() string {
+	return fmt.Sprintf("%.0f kcal, fat %.1fg, carbs %.1fg (sugar %.1fg), protein %.1fg", n.Calories, n.Fat, n.Carbs, n.Sugar, n.Protein)
+}
+
+// ParseNutrition reads "calories,fat,carbs,sugar,protein", missing values on the end count as 0
+func ParseNutrition(s string) (Nutrition, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) > 5 {
+		return Nutrition{}, fmt.Errorf("%q has more than 5 values (calories,fat,carbs,sugar,protein)", s)
+	}
+	var v [5]float64
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil || !weighable(f) {
+			return Nutrition{}, fmt.Errorf("%q is not an amount", p)
+		}
+		v[i] = f
+	}
+	return Nutrition{v[0], v[1], v[2], v[3], v[4]}, nil
+}
+
+// requireNutrition is set when the shop wants nutrition facts for every size it sells
+var requireNutrition bool
+
+// SetRequireNutrition turns the "every priced size needs nutrition facts" check on or off. It can't be turned on
+// while items on the menu are missing them
+func SetRequireNutrition(on bool) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if on {
+		var errs []error
+		for _, item := range data {
+			if err := item.checkNutrition(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if len(errs) > 0 {
+			return errors.Join(errs...)
+		}
+	}
+	if requireNutrition != on {
+		requireNutrition = on
+		changed(time.Now())
+	}
+	return nil
+}
+
+// missingNutrition lists the priced sizes that don't have nutrition facts
+func (item menuItem) missingNutrition() []string {
+	var missing []string
+	for size := range item.prices {
+		if _, ok := item.nutrition[size]; !ok {
+			missing = append(missing, size)
+		}
+	}
+	slices.Sort(missing)
+	return missing
+}
+
+// checkNutrition is the error for an item that's missing nutrition facts for any of its sizes
+func (item menuItem) checkNutrition() error {
+	if missing := item.missingNutrition(); len(missing) > 0 {
+		return invalid(item.name, "nutrition", "missing for %v", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// NutritionFor is the nutrition of an item as it comes, for every size we have facts for
+func NutritionFor(item string) (map[string]Nutrition, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	i := data.find(item)
+	if i < 0 {
+		return nil, notFound(item)
+	}
+	out := make(map[string]Nutrition, len(data[i].nutrition))
+	for size, n := range data[i].nutrition {
+		out[size] = n
+	}
+	return out, nil
+}
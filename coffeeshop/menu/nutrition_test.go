@@ -0,0 +1,102 @@
+package menu
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNutritionArithmetic(t *testing.T) {
+	latte := Nutrition{Calories: 120, Fat: 4.5, Carbs: 15, Sugar: 12, Protein: 3}
+	tests := []struct {
+		name        string
+		got, expect Nutrition
+	}{
+		{"plus", latte.Plus(Nutrition{Calories: 40, Carbs: 10, Sugar: 10}), Nutrition{160, 4.5, 25, 22, 3}},
+		{"plus nothing", latte.Plus(Nutrition{}), latte},
+		{"times", latte.Times(2), Nutrition{240, 9, 30, 24, 6}},
+		{"times none", latte.Times(0), Nutrition{}},
+	}
+	for _, test := range tests {
+		if test.got != test.expect {
+			t.Errorf("%v: got %+v, expected %+v", test.name, test.got, test.expect)
+		}
+	}
+}
+
+func TestParseNutrition(t *testing.T) {
+	tests := []struct {
+		in     string
+		expect Nutrition
+		ok     bool
+	}{
+		{"120,4.5,15,12,3", Nutrition{120, 4.5, 15, 12, 3}, true},
+		{" 120, 4.5 ", Nutrition{Calories: 120, Fat: 4.5}, true}, // The rest are 0
+		{"0", Nutrition{}, true},
+		{"1,2,3,4,5,6", Nutrition{}, false},
+		{"-1", Nutrition{}, false},
+		{"lots", Nutrition{}, false},
+		{"NaN", Nutrition{}, false},
+		{"120,Inf", Nutrition{}, false},
+		{"", Nutrition{}, false},
+	}
+	for _, test := range tests {
+		got, err := ParseNutrition(test.in)
+		if (err == nil) != test.ok || got != test.expect {
+			t.Errorf("%q: got %+v, %v, expected %+v", test.in, got, err, test.expect)
+		}
+	}
+}
+
+func TestSetRequireNutrition(t *testing.T) {
+	useTestMenu(t)
+	defer SetRequireNutrition(false)
+
+	// None of the test menu has facts, so it can't be turned on yet
+	var ve *ValidationError
+	if err := SetRequireNutrition(true); !errors.As(err, &ve) || requireNutrition {
+		t.Fatalf("Got %v, expected a ValidationError and still off", err)
+	}
+	for i := range data {
+		data[i].nutrition = map[string]Nutrition{}
+		for size := range data[i].prices {
+			data[i].nutrition[size] = Nutrition{Calories: 100}
+		}
+	}
+	if err := SetRequireNutrition(true); err != nil {
+		t.Fatal(err)
+	}
+
+	// Once it's on, a new item has to come with facts for every size
+	if err := Add("Flat White", map[string]float64{"small": 3}, Details{}); err == nil {
+		t.Error("Expected an item without nutrition facts to be turned down")
+	}
+	if err := Add("Flat White", map[string]float64{"small": 3}, Details{Nutrition: map[string]Nutrition{"small": {Calories: 90}}}); err != nil {
+		t.Errorf("Got %v, expected the item to go on", err)
+	}
+}
+
+func TestQuoteNutrition(t *testing.T) {
+	useTestMenu(t)
+	data[data.find("Latte")].nutrition = map[string]Nutrition{"small": {Calories: 120, Fat: 4.5, Carbs: 15, Sugar: 12, Protein: 3}}
+
+	tests := []struct {
+		size      string
+		modifiers []string
+		expect    Nutrition
+		hasFacts  bool
+	}{
+		{"small", nil, Nutrition{120, 4.5, 15, 12, 3}, true},
+		{"small", []string{"vanilla syrup"}, Nutrition{160, 4.5, 25, 22, 3}, true},
+		{"small", []string{"vanilla syrup", "extra shot"}, Nutrition{161, 4.5, 25, 22, 3.1}, true},
+		{"large", []string{"vanilla syrup"}, Nutrition{Calories: 40, Carbs: 10, Sugar: 10}, false}, // Only the syrup is known
+	}
+	for _, test := range tests {
+		q, err := QuoteAt("", "Latte", test.size, test.modifiers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if q.Nutrition != test.expect || q.HasFacts != test.hasFacts {
+			t.Errorf("%v %v: got %+v (facts %v), expected %+v (facts %v)", test.size, test.modifiers, q.Nutrition, q.HasFacts, test.expect, test.hasFacts)
+		}
+	}
+}
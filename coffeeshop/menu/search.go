@@ -0,0 +1,225 @@
+package menu
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// MARK: Search
+
+// Query describes what someone is looking for. Every field is optional, the zero Query matches everything
+type Query struct {
+	Text          string   // Matched against names, categories and tags
+	MinPrice      float64  // 0 means no lower bound
+	MaxPrice      float64  // 0 means no upper bound
+	Size          string   // Only items sold in this size (the price range then applies to this size only)
+	AvailableOnly bool     // Leave out sold out items
+	Tags          []string // Every one of these tags or diets has to be on the item (hot, vegan, gluten-free...)
+	Exclude       Allergen // Leave out anything containing one of these
+	Store         string   // Search the menu as this store sells it, "" for the base menu
+}
+
+// search returns the matching items, best match first
+func (m menu) search(q Query) menu {
+	words := tokens(q.Text)
+	size := strings.ToLower(strings.TrimSpace(q.Size))
+
+	type hit struct {
+		item  menuItem
+		score float64
+	}
+	var hits []hit
+	for _, item := range m {
+		if !item.matches(q, size) {
+			continue
+		}
+		score := 1.0 // No text means everything matches equally
+		if len(words) > 0 {
+			score = item.relevance(words)
+			if score == 0 {
+				continue
+			}
+		}
+		hits = append(hits, hit{item, score})
+	}
+
+	// Highest score first, ties in alphabetical order so the output doesn't jump around
+	slices.SortStableFunc(hits, func(a, b hit) int {
+		if c := cmp.Compare(b.score, a.score); c != 0 {
+			return c
+		}
+		return cmp.Compare(fold(a.item.name), fold(b.item.name))
+	})
+
+	found := make(menu, len(hits))
+	for i, h := range hits {
+		found[i] = h.item
+	}
+	return found
+}
+
+// matches checks everything in the query apart from the text
+func (item menuItem) matches(q Query, size string) bool {
+	if q.AvailableOnly && item.soldOut {
+		return false
+	}
+	if item.allergens&q.Exclude != 0 {
+		return false
+	}
+	for _, t := range q.Tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if !slices.Contains(item.tags, t) && !slices.Contains(item.diets().Names(), t) {
+			return false
+		}
+	}
+	if size == "" && q.MinPrice == 0 && q.MaxPrice == 0 {
+		return true
+	}
+
+	// At least one size has to fit, or the one size that was asked for
+	for s, cost := range item.prices {
+		if size != "" && strings.ToLower(s) != size {
+			continue
+		}
+		if q.MinPrice > 0 && cost < q.MinPrice {
+			continue
+		}
+		if q.MaxPrice > 0 && cost > q.MaxPrice {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// relevance scores the item against the search words. Every word has to match something, otherwise it's 0
+func (item menuItem) relevance(words []string) float64 {
+	name := tokens(item.name)
+	var other []string
+	other = append(other, tokens(item.category)...)
+	for _, t := range append(item.tags, item.diets().Names()...) {
+		other = append(other, tokens(t)...)
+	}
+
+	total := 0.0
+	for _, w := range words {
+		// A match in the name counts for more than one in the category or tags
+		best := max(2*wordScore(w, name), wordScore(w, other))
+		if best == 0 {
+			return 0
+		}
+		total += best
+	}
+
+	// Searching "latte" should put "Latte" before "Chai Latte"
+	if strings.Join(name, " ") == strings.Join(words, " ") {
+		total += 10
+	}
+	return total
+}
+
+// wordScore is how well one search word matches the best of the given words
+func wordScore(w string, in []string) float64 {
+	best := 0.0
+	for _, c := range in {
+		var s float64
+		switch {
+		case c == w:
+			s = 3
+		case strings.HasPrefix(c, w):
+			s = 2
+		case strings.Contains(c, w):
+			s = 1.5
+		case distance(w, c) <= typos(w):
+			s = 1
+		}
+		best = max(best, s)
+	}
+	return best
+}
+
+// typos is how many mistakes we forgive, short words get fewer so "tea" doesn't match everything
+func typos(w string) int {
+	switch n := len([]rune(w)); {
+	case n <= 3:
+		return 0
+	case n <= 6:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// distance is how many letters have to be added, removed, changed or swapped with their neighbour to turn a into b
+// (the optimal string alignment distance, Levenshtein plus swaps, since "mocah" is one slip not two)
+func distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
+
+// tokens folds s and splits it into words
+func tokens(s string) []string {
+	return strings.FieldsFunc(fold(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// fold lower cases s and takes the accents off, so "Café" and "cafe" are the same word
+func fold(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if plain, ok := accents[r]; ok {
+			b.WriteString(plain)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// The standard library doesn't have a way to strip accents (that lives in golang.org/x/text), so we keep a table
+// of the lower case letters we're likely to see on a menu
+var accents = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'æ': "ae",
+	'ç': "c", 'è': "e", 'é': "e", 'ê': "e", 'ë': "e",
+	'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ñ': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'œ': "oe",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ý': "y", 'ÿ': "y", 'ß': "ss",
+}
+
+// Search returns the items that match q, best match first
+func Search(q Query) []Item {
+	mu.RLock()
+	defer mu.RUnlock()
+	s, err := findStore(q.Store)
+	if err != nil {
+		return nil // Nothing matches in a store we don't have
+	}
+	found, code := data.at(s).search(q).views(), moneyAt(s).Currency.Code
+	for i := range found {
+		found[i].Currency = code
+	}
+	return found
+}
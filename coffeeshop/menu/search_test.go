@@ -0,0 +1,65 @@
+package menu
+
+import (
+	"slices"
+	"testing"
+)
+
+func testMenu() menu {
+	var m menu
+	m.insert("Chai Latte", map[string]float64{"small": 3.10, "large": 3.60}, Details{Category: "tea", Tags: []string{"hot"}})
+	m.insert("Latte", map[string]float64{"small": 3.00, "large": 3.50}, Details{Category: "coffee", Tags: []string{"hot"}, Allergens: Milk})
+	m.insert("Café Mocha", map[string]float64{"small": 3.40}, Details{Category: "coffee", Tags: []string{"hot"}, SoldOut: true})
+	m.insert("Iced Tea", map[string]float64{"large": 2.50}, Details{Category: "tea", Tags: []string{"iced"}, Diet: Vegan})
+	return m
+}
+
+// useTestMenu puts testMenu, with IDs, in place of the package's menu until the test is over
+func useTestMenu(t *testing.T) {
+	t.Helper()
+	old := data
+	data = testMenu()
+	data.fillIDs()
+	t.Cleanup(func() { data = old })
+}
+
+func names(m menu) []string {
+	var out []string
+	for _, item := range m {
+		out = append(out, item.name)
+	}
+	return out
+}
+
+func TestSearch(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  Query
+		expect []string
+	}{
+		{"exact name ranks first", Query{Text: "latte"}, []string{"Latte", "Chai Latte"}},
+		{"accents and case", Query{Text: "CAFE"}, []string{"Café Mocha"}},
+		{"typo", Query{Text: "mocah"}, []string{"Café Mocha"}},
+		{"name beats category", Query{Text: "tea"}, []string{"Iced Tea", "Chai Latte"}},
+		{"every word has to match", Query{Text: "iced latte"}, nil},
+		{"price range", Query{MinPrice: 3.05, MaxPrice: 3.20}, []string{"Chai Latte"}},
+		{"size", Query{Size: "Large", MaxPrice: 3}, []string{"Iced Tea"}},
+		{"available", Query{Text: "mocha", AvailableOnly: true}, nil},
+		{"tags", Query{Tags: []string{"Iced"}}, []string{"Iced Tea"}},
+		{"diets", Query{Tags: []string{"Vegan"}}, []string{"Iced Tea"}},
+		{"exclude allergens", Query{Text: "latte", Exclude: Milk}, []string{"Chai Latte"}},
+	}
+
+	for _, tt := range tests {
+		got := names(testMenu().search(tt.query))
+		if !slices.Equal(got, tt.expect) {
+			t.Errorf("%v: got %v, expected %v", tt.name, got, tt.expect)
+		}
+	}
+}
+
+func TestDistance(t *testing.T) {
+	if got := distance("espresso", "expreso"); got != 2 {
+		t.Errorf("Got %v, expected 2", got)
+	}
+}
@@ -0,0 +1,231 @@
+package menu
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"demo/coffeeshop/money"
+)
+
+// MARK: Saving and Loading
+
+// menuItem keeps its fields private, so this is the shape it takes on disk
+type storedItem struct {
+	ID        string               `json:"id,omitempty"` // Older files don't have them, they're filled in on loading
+	Name      string               `json:"name"`
+	Prices    map[string]float64   `json:"prices"`
+	Category  string               `json:"category,omitempty"`
+	Tags      []string             `json:"tags,omitempty"`
+	SoldOut   bool                 `json:"soldOut,omitempty"`
+	Allergens []string             `json:"allergens,omitempty"`
+	Diet      []string             `json:"diet,omitempty"`
+	Nutrition map[string]Nutrition `json:"nutrition,omitempty"`
+
+	Description  string                 `json:"description,omitempty"`
+	Translations map[string]Translation `json:"translations,omitempty"`
+}
+
+// storedMenu is the whole file. Older files are just the list of items
+type storedMenu struct {
+	Currency         string                       `json:"currency,omitempty"` // What the items' prices are in
+	Locale           string                       `json:"locale,omitempty"`
+	TaxRate          float64                      `json:"taxRate,omitempty"` // Included in the prices
+	RequireNutrition bool                         `json:"requireNutrition,omitempty"`
+	RequiredSizes    map[string][]string          `json:"requiredSizes,omitempty"` // Per category
+	SizeLabels       map[string]map[string]string `json:"sizeLabels,omitempty"`    // By language, then size
+	Items            []storedItem                 `json:"items"`
+	Stores           []Store                      `json:"stores,omitempty"` // Their differences from the items above
+}
+
+// loaded is set once Load has succeeded, guarded by mu
+var loaded bool
+
+// Loaded reports whether a menu has been loaded yet
+func Loaded() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return loaded
+}
+
+// Load replaces the menu with the one saved at path. If the file doesn't exist yet we keep the built in menu
+func Load(path string) error {
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		mu.Lock()
+		loaded = true // The built in menu is a perfectly good menu
+		mu.Unlock()
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := load(b, info.ModTime()); err != nil {
+		return fmt.Errorf("reading %v: %w", path, err)
+	}
+	return nil
+}
+
+// load parses a menu file and swaps it in. If anything is wrong with it the menu we had stays as it was
+func load(b []byte, modTime time.Time) error {
+	var stored storedMenu
+	var err error
+	if len(bytes.TrimSpace(b)) > 0 && bytes.TrimSpace(b)[0] == '[' {
+		err = json.Unmarshal(b, &stored.Items)
+	} else {
+		err = json.Unmarshal(b, &stored)
+	}
+	if err != nil {
+		return err
+	}
+
+	// The nutrition check looks at the package setting, so switch it over before reading the items
+	mu.Lock()
+	defer mu.Unlock()
+	// The same for the currency, it decides what a valid price is
+	before, wasCurrency, wasLocale := requireNutrition, currency, locale
+	requireNutrition, currency, locale = stored.RequireNutrition, stored.Currency, stored.Locale
+	_, err = money.New(currency, locale)
+	if err == nil {
+		err = checkStores(stored.Stores, currency)
+	}
+	if err == nil && (stored.TaxRate < 0 || stored.TaxRate >= 1) {
+		err = fmt.Errorf("the tax rate has to be a fraction from 0 up to 1, got %v", stored.TaxRate)
+	}
+	var m menu
+	if err == nil {
+		m, err = stored.menu()
+	}
+	if err != nil {
+		requireNutrition, currency, locale = before, wasCurrency, wasLocale
+		return err
+	}
+	data = m
+	stores = stored.Stores
+	taxRate = stored.TaxRate
+	requiredSizes = stored.RequiredSizes
+	if requiredSizes == nil {
+		requiredSizes = map[string][]string{}
+	}
+	sizeLabels = cleanSizeLabels(stored.SizeLabels)
+	loaded = true
+	fileSum = sha256.Sum256(b)
+	changed(modTime)
+	return nil
+}
+
+// menu turns what was on disk back into menu items
+func (stored storedMenu) menu() (menu, error) {
+	m := make(menu, 0, len(stored.Items))
+	for _, s := range stored.Items {
+		d := Details{Category: s.Category, Tags: s.Tags, SoldOut: s.SoldOut, Nutrition: s.Nutrition, Description: s.Description}
+		var err error
+		if d.Allergens, err = ParseAllergens(strings.Join(s.Allergens, ",")); err != nil {
+			return nil, fmt.Errorf("%q: %w", s.Name, err)
+		}
+		if d.Diet, err = ParseDiets(strings.Join(s.Diet, ",")); err != nil {
+			return nil, fmt.Errorf("%q: %w", s.Name, err)
+		}
+		if err := m.insert(s.Name, s.Prices, d); err != nil {
+			return nil, fmt.Errorf("%q: %w", s.Name, err)
+		}
+		if s.ID != "" && m.hasID(s.ID) {
+			return nil, fmt.Errorf("%q: the ID %v is used twice", s.Name, s.ID)
+		}
+		m[len(m)-1].id = s.ID
+		m[len(m)-1].translations = cleanTranslations(s.Translations)
+	}
+	m.fillIDs() // After every saved ID is in, so a new one can't take one of them
+	return m, nil
+}
+
+// Save writes the menu to path so the next run can Load it
+func Save(path string) error {
+	mu.Lock() // Not RLock, we update fileSum
+	defer mu.Unlock()
+	b, err := json.MarshalIndent(data.stored(), "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	if err := os.WriteFile(path, b, 0644); err != nil {
+		return err
+	}
+	fileSum = sha256.Sum256(b) // So Watch knows this one is ours
+	return nil
+}
+
+// stored is the menu the way it's written to disk
+func (m menu) stored() storedMenu {
+	stored := storedMenu{Currency: currency, Locale: locale, TaxRate: taxRate, SizeLabels: sizeLabels, RequireNutrition: requireNutrition, RequiredSizes: requiredSizes, Items: make([]storedItem, 0, len(m)), Stores: stores}
+	for _, item := range m {
+		stored.Items = append(stored.Items, storedItem{
+			ID:        item.id,
+			Name:      item.name,
+			Prices:    item.prices,
+			Category:  item.category,
+			Tags:      item.tags,
+			SoldOut:   item.soldOut,
+			Allergens: item.allergens.Names(),
+			Diet:      item.diet.Names(),
+			Nutrition: item.nutrition,
+
+			Description:  item.description,
+			Translations: item.translations,
+		})
+	}
+	return stored
+}
+
+// MARK: Import and Export
+
+// ImportText reads the menu.txt format (one item name per line, blank lines between groups) and adds any
+// items we don't already have. It returns how many items were added
+func ImportText(r io.Reader) (int, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	m := slices.Clone(data) // Everything goes in or nothing does
+	var errs []error
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		name := strings.TrimSpace(sc.Text())
+		if name == "" {
+			continue
+		}
+		if m.find(name) >= 0 {
+			continue // Already on the menu
+		}
+		item := newItem(name, nil, Details{})
+		if err := m.validate(item, -1); err != nil {
+			errs = append(errs, fmt.Errorf("line %v: %w", line, err))
+			continue
+		}
+		item.id = m.newID(item.name)
+		m = append(m, item)
+	}
+	if err := sc.Err(); err != nil {
+		return 0, err
+	}
+	if len(errs) > 0 {
+		return 0, errors.Join(errs...)
+	}
+	added := len(m) - len(data)
+	if added > 0 {
+		data = m
+		changed(time.Now())
+	}
+	return added, nil
+}
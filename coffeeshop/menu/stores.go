@@ -0,0 +1,341 @@
+package menu
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"demo/coffeeshop/money"
+)
+
+// MARK: Stores
+
+// Store is one of our shops. They all sell from the same base menu, each with its own prices for some sizes and
+// its own idea of what's on sale. Items are the differences, by item ID
+//
+// A store abroad has its own currency. Base prices (and modifiers) are converted at Rate and rounded to the
+// currency, its own prices in Items are already in it
+type Store struct {
+	ID       string               `json:"id"` // Short, for URLs and flags: high-st
+	Name     string               `json:"name"`
+	Currency string               `json:"currency,omitempty"` // "" for the base menu's
+	Locale   string               `json:"locale,omitempty"`   // How prices are written, "" for the currency's usual way
+	Rate     float64              `json:"rate,omitempty"`     // One of the base currency is this much of the store's
+	Items    map[string]StoreItem `json:"items,omitempty"`
+}
+
+// StoreItem is how one item is different at one store
+type StoreItem struct {
+	Prices  map[string]float64 `json:"prices,omitempty"`  // Sizes that cost something else here
+	NotSold bool               `json:"notSold,omitempty"` // Not on this store's menu at all
+	SoldOut bool               `json:"soldOut,omitempty"` // Run out here, the other stores might still have it
+}
+
+// stores is every store, in the order they were opened, guarded by mu and saved with the menu
+var stores []Store
+
+// findStore is the store with the given ID, or nil for "" which means the base menu. mu has to be held
+func findStore(id string) (*Store, error) {
+	if id == "" {
+		return nil, nil
+	}
+	for i := range stores {
+		if stores[i].ID == id {
+			return &stores[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%q: %w", id, ErrStoreNotFound)
+}
+
+// at is the menu as store s sells it. A nil store sells the base menu as it is
+func (m menu) at(s *Store) menu {
+	if s == nil {
+		return m
+	}
+	cur, rate := moneyAt(s).Currency, rateAt(s)
+	out := make(menu, 0, len(m))
+	for _, item := range m {
+		o := s.Items[item.id]
+		if o.NotSold {
+			continue
+		}
+		item.prices = maps.Clone(item.prices) // Don't change the base menu's map
+		if rate != 1 {
+			for size, price := range item.prices {
+				item.prices[size] = cur.Round(price * rate)
+			}
+		}
+		for size, price := range o.Prices {
+			if _, ok := item.prices[size]; ok { // An override for a size the item has stopped coming in does nothing
+				item.prices[size] = price
+			}
+		}
+		item.soldOut = item.soldOut || o.SoldOut
+		out = append(out, item)
+	}
+	return out
+}
+
+// Stores lists every store
+func Stores() []Store {
+	mu.RLock()
+	defer mu.RUnlock()
+	out := make([]Store, len(stores))
+	for i, s := range stores {
+		out[i] = s.clone()
+	}
+	return out
+}
+
+// LookupStore finds a store by its ID
+func LookupStore(id string) (Store, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	s, err := findStore(id)
+	if err != nil || s == nil {
+		return Store{}, err
+	}
+	return s.clone(), nil
+}
+
+func (s Store) clone() Store {
+	s.Items = maps.Clone(s.Items)
+	for id, o := range s.Items {
+		o.Prices = maps.Clone(o.Prices)
+		s.Items[id] = o
+	}
+	return s
+}
+
+// StoreItems is the menu as one store sells it, or the base menu for ""
+func StoreItems(store string) ([]Item, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	s, err := findStore(store)
+	if err != nil {
+		return nil, err
+	}
+	return data.viewsAt(s), nil
+}
+
+// LookupAt finds a single item the way one store sells it. Items the store doesn't sell aren't found
+func LookupAt(store, ref string) (Item, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	s, err := findStore(store)
+	if err != nil {
+		return Item{}, err
+	}
+	m := data.at(s)
+	i := m.find(ref)
+	if i < 0 {
+		return Item{}, notFound(ref)
+	}
+	v := m[i].view()
+	v.Currency = moneyAt(s).Currency.Code
+	return v, nil
+}
+
+// viewsAt is the menu as store s sells it, ready for the renderers
+func (m menu) viewsAt(s *Store) []Item {
+	out := m.at(s).views()
+	code := moneyAt(s).Currency.Code
+	for i := range out {
+		out[i].Currency = code
+	}
+	return out
+}
+
+// MARK: Changing Stores
+
+// AddStore opens a new store. It sells the whole base menu at base prices until it's told otherwise
+func AddStore(id, name string) error {
+	id, name = strings.ToLower(strings.TrimSpace(id)), strings.TrimSpace(name)
+	var problems []FieldError
+	if id == "" || strings.ContainsFunc(id, func(r rune) bool { return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-') }) {
+		problems = append(problems, FieldError{Field: "id", Message: "can only have letters, numbers and -, like high-st"})
+	}
+	if name == "" {
+		problems = append(problems, FieldError{Field: "name", Message: "a name is needed"})
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if s, _ := findStore(id); s != nil {
+		problems = append(problems, FieldError{Field: "id", Message: fmt.Sprintf("%v is already %v", id, s.Name), Err: ErrDuplicateItem})
+	}
+	if len(problems) > 0 {
+		return &ValidationError{Item: name, Problems: problems}
+	}
+	stores = append(stores, Store{ID: id, Name: name})
+	changed(time.Now())
+	return nil
+}
+
+// SetStorePrice changes what one size of an item costs at one store, and returns what it cost there before. The
+// price has to follow the same rules as a base price
+func SetStorePrice(store, ref, size string, price float64) (float64, error) {
+	var old float64
+	err := editStoreItem(store, ref, func(item *menuItem, o *StoreItem) error {
+		var ok bool
+		if old, ok = item.prices[size]; !ok {
+			return invalid(item.name, "size", "doesn't come in %q", size)
+		}
+		if o.Prices == nil {
+			o.Prices = map[string]float64{}
+		}
+		o.Prices[size] = price
+		return nil
+	})
+	return old, err
+}
+
+// ClearStorePrice puts one size of an item back to the base price at one store
+func ClearStorePrice(store, ref, size string) error {
+	return editStoreItem(store, ref, func(item *menuItem, o *StoreItem) error {
+		delete(o.Prices, size)
+		return nil
+	})
+}
+
+// SetSoldAt says whether a store sells an item at all
+func SetSoldAt(store, ref string, sold bool) error {
+	return editStoreItem(store, ref, func(item *menuItem, o *StoreItem) error {
+		o.NotSold = !sold
+		return nil
+	})
+}
+
+// SetSoldOutAt marks an item as run out, or back in, at one store
+func SetSoldOutAt(store, ref string, soldOut bool) error {
+	return editStoreItem(store, ref, func(item *menuItem, o *StoreItem) error {
+		o.SoldOut = soldOut
+		return nil
+	})
+}
+
+// SetStoreCurrency changes what a store prices in and how it writes prices. rate converts the base prices, and
+// is needed unless the currency is the base menu's. A store's own prices are in its currency, so they have to
+// be cleared before it can change to another one. An empty code goes back to the base menu's currency
+func SetStoreCurrency(store, code, loc string, rate float64) error {
+	mu.Lock()
+	defer mu.Unlock()
+	s, err := findStore(store)
+	if err != nil || s == nil {
+		return cmp.Or(err, fmt.Errorf("which store? %w", ErrStoreNotFound))
+	}
+
+	next := *s
+	next.Currency, next.Locale, next.Rate = "", strings.TrimSpace(loc), 0
+	var problems []FieldError
+	if strings.TrimSpace(code) != "" {
+		c, err := money.LookupCurrency(code)
+		if err != nil {
+			problems = append(problems, FieldError{Field: "currency", Message: err.Error()})
+		}
+		next.Currency = c.Code
+	}
+	if next.Locale != "" {
+		l, err := money.LookupLocale(next.Locale)
+		if err != nil {
+			problems = append(problems, FieldError{Field: "locale", Message: err.Error()})
+		}
+		next.Locale = l.Tag
+	}
+	if next.Currency != "" && next.Currency != currency {
+		if rate <= 0 || math.IsNaN(rate) || math.IsInf(rate, 0) {
+			problems = append(problems, FieldError{Field: "rate", Message: fmt.Sprintf("needs a rate to convert %v prices to %v", cmp.Or(currency, "base"), next.Currency)})
+		}
+		next.Rate = rate
+	}
+	if moneyAt(&next).Currency.Code != moneyAt(s).Currency.Code {
+		for _, o := range s.Items {
+			if len(o.Prices) > 0 {
+				problems = append(problems, FieldError{Field: "currency", Message: "it has its own prices in " + cmp.Or(moneyAt(s).Currency.Code, "the old currency") + ", put them back to the base prices first"})
+				break
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return &ValidationError{Item: s.Name, Problems: problems}
+	}
+
+	was := *s
+	*s = next
+	if err := checkPrices(); err != nil {
+		*s = was
+		return err
+	}
+	changed(time.Now())
+	return nil
+}
+
+// editStoreItem changes how an item is at a store. edit gets the item with the store's prices on it and a copy of
+// the store's differences to change. The item as the store would then sell it has to pass validation before any
+// of it is kept
+func editStoreItem(store, ref string, edit func(item *menuItem, o *StoreItem) error) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if store == "" {
+		return fmt.Errorf("which store? %w", ErrStoreNotFound)
+	}
+	s, err := findStore(store)
+	if err != nil {
+		return err
+	}
+	i := data.find(ref)
+	if i < 0 {
+		return notFound(ref)
+	}
+	base := data[i]
+	o := s.Items[base.id]
+	o.Prices = maps.Clone(o.Prices)
+
+	item := (menu{base}).at(&Store{Currency: s.Currency, Rate: s.Rate, Items: map[string]StoreItem{base.id: {Prices: o.Prices}}})[0]
+	if err := edit(&item, &o); err != nil {
+		return err
+	}
+	item = (menu{base}).at(&Store{Currency: s.Currency, Rate: s.Rate, Items: map[string]StoreItem{base.id: {Prices: o.Prices}}})[0]
+	if err := data.validateAt(item, i, s); err != nil {
+		return err
+	}
+
+	if s.Items == nil {
+		s.Items = map[string]StoreItem{}
+	}
+	if len(o.Prices) == 0 {
+		o.Prices = nil
+	}
+	if o.Prices == nil && !o.NotSold && !o.SoldOut {
+		delete(s.Items, base.id) // Back to the base menu, nothing to remember
+	} else {
+		s.Items[base.id] = o
+	}
+	changed(time.Now())
+	return nil
+}
+
+// checkStores makes sure stores read from a file make sense. base is the base menu's currency
+func checkStores(list []Store, base string) error {
+	var ids []string
+	for _, s := range list {
+		if s.ID == "" {
+			return fmt.Errorf("a store with no ID")
+		}
+		if slices.Contains(ids, s.ID) {
+			return fmt.Errorf("the store ID %v is used twice", s.ID)
+		}
+		ids = append(ids, s.ID)
+		if _, err := money.New(cmp.Or(s.Currency, base), s.Locale); s.Currency+s.Locale != "" && err != nil {
+			return fmt.Errorf("store %v: %w", s.ID, err)
+		}
+		if s.Currency != "" && s.Currency != base && !(s.Rate > 0) {
+			return fmt.Errorf("store %v: prices in %v need a rate", s.ID, s.Currency)
+		}
+	}
+	return nil
+}
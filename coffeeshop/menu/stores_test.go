@@ -0,0 +1,118 @@
+package menu
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestStores(t *testing.T) {
+	data = testMenu()
+	data.fillIDs()
+	stores = nil
+
+	if err := AddStore("high-st", "High Street"); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddStore("High St", "Again"); err == nil {
+		t.Error("Expected a bad store ID to be turned down")
+	}
+	if err := AddStore("high-st", "Again"); !errors.Is(err, ErrDuplicateItem) {
+		t.Errorf("Adding a store twice: got %v", err)
+	}
+
+	// Overrides only change the one store
+	if old, err := SetStorePrice("high-st", "latte", "small", 3.25); err != nil || old != 3 {
+		t.Fatalf("Got %v, %v", old, err)
+	}
+	if _, err := SetStorePrice("high-st", "latte", "small", -1); err == nil {
+		t.Error("Expected a negative store price to be turned down")
+	}
+	if _, err := SetStorePrice("high-st", "latte", "medium", 3); err == nil {
+		t.Error("Expected a size the item doesn't come in to be turned down")
+	}
+	if item, _ := LookupAt("high-st", "latte"); item.Sizes[0].Price != 3.25 {
+		t.Errorf("High Street latte: got %v", item.Sizes)
+	}
+	if item, _ := LookupAt("", "latte"); item.Sizes[0].Price != 3 {
+		t.Errorf("Base latte: got %v", item.Sizes)
+	}
+	if q, err := QuoteAt("high-st", "latte", "small", nil); err != nil || q.Price != 3.25 {
+		t.Errorf("Quote at High Street: got %v, %v", q.Price, err)
+	}
+
+	// Not sold and sold out
+	if err := SetSoldAt("high-st", "iced tea", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetSoldOutAt("high-st", "chai latte", true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LookupAt("high-st", "iced tea"); !errors.Is(err, ErrItemNotFound) {
+		t.Errorf("Iced tea at High Street: got %v", err)
+	}
+	if found := Search(Query{Store: "high-st", AvailableOnly: true}); len(found) != 1 || found[0].Name != "Latte" {
+		t.Errorf("Available at High Street: got %v", found)
+	}
+	if item, _ := LookupAt("", "chai latte"); item.SoldOut {
+		t.Error("Sold out at one store shouldn't be sold out everywhere")
+	}
+
+	// Putting it all back leaves nothing to remember
+	ClearStorePrice("high-st", "latte", "small")
+	SetSoldAt("high-st", "iced tea", true)
+	SetSoldOutAt("high-st", "chai latte", false)
+	if s, _ := LookupStore("high-st"); len(s.Items) != 0 {
+		t.Errorf("Expected no differences left, got %v", s.Items)
+	}
+
+	if _, err := StoreItems("nowhere"); !errors.Is(err, ErrStoreNotFound) {
+		t.Errorf("Unknown store: got %v", err)
+	}
+	stores = nil
+}
+
+func TestStoreCurrency(t *testing.T) {
+	data = testMenu()
+	data.fillIDs()
+	stores, currency, locale = nil, "", ""
+	defer func() { stores = nil }()
+
+	if err := SetCurrency("USD", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetCurrency("JPY", ""); err == nil {
+		t.Error("Expected yen to be turned down with cents on the menu")
+	}
+	if err := AddStore("shibuya", "Shibuya"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetStoreCurrency("shibuya", "JPY", "", 0); err == nil {
+		t.Error("Expected yen without a rate to be turned down")
+	}
+	if err := SetStoreCurrency("shibuya", "JPY", "", 151.3); err != nil {
+		t.Fatal(err)
+	}
+
+	// Base prices are converted and rounded to the yen, and so are modifiers
+	item, _ := LookupAt("shibuya", "latte")
+	if item.Currency != "JPY" || item.Sizes[0].Price != 454 {
+		t.Errorf("Latte in Shibuya: got %v %v", item.Currency, item.Sizes)
+	}
+	if q, _ := QuoteAt("shibuya", "latte", "small", []string{"extra shot"}); q.Price != 454+113 || q.Currency != "JPY" {
+		t.Errorf("Quote in Shibuya: got %v %v", q.Price, q.Currency)
+	}
+	if f, _ := Money("shibuya"); f.Amount(1234) != "¥1,234" {
+		t.Errorf("Got %v", f.Amount(1234))
+	}
+
+	// Its own prices are in yen, whole ones
+	if _, err := SetStorePrice("shibuya", "latte", "small", 480.5); err == nil {
+		t.Error("Expected half a yen to be turned down")
+	}
+	if _, err := SetStorePrice("shibuya", "latte", "small", 480); err != nil {
+		t.Error(err)
+	}
+	if err := SetStoreCurrency("shibuya", "", "", 0); err == nil {
+		t.Error("Expected changing currency with yen prices on the store to be turned down")
+	}
+}
@@ -0,0 +1,233 @@
+package menu
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"demo/coffeeshop/i18n"
+)
+
+// MARK: Translations
+
+// Translation is an item's name and description in another language. Either can be left out, the item's own
+// is used instead
+type Translation struct {
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Long enough for a sentence about the beans, short enough for the board
+const maxDescriptionLen = 200
+
+// sizeLabels are what the sizes are called in other languages, by language tag and then size, saved with the
+// menu and guarded by mu. A size without a label is shown as it's named
+var sizeLabels = map[string]map[string]string{}
+
+// Localize is items in another language, as far as the menu has been translated. A missing translation falls back
+// to the language's parent (fr for fr-CA) and then to the item's own name and description. Size names stay the
+// same, so they can still be ordered by, with the translation in Label
+func Localize(items []Item, lang string) []Item {
+	tags := i18n.Fallbacks(lang)
+	if len(tags) == 0 || tags[len(tags)-1] == i18n.Default {
+		return items // Already in the shop's own language
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	out := make([]Item, len(items))
+	for i, v := range items {
+		if j := data.find(v.ID); j >= 0 {
+			v = data[j].localize(v, tags)
+		}
+		v.Sizes = slices.Clone(v.Sizes)
+		for k, s := range v.Sizes {
+			for _, tag := range tags {
+				if label := sizeLabels[tag][s.Name]; label != "" {
+					v.Sizes[k].Label = label
+					break
+				}
+			}
+		}
+		out[i] = v
+	}
+	return out
+}
+
+// localize puts the item's translation in tags (best first) on its view v
+func (item menuItem) localize(v Item, tags []string) Item {
+	name, desc := false, false
+	for _, tag := range tags {
+		t := item.translations[tag]
+		if !name && t.Name != "" {
+			v.Name, v.Lang, name = t.Name, tag, true
+		}
+		if !desc && t.Description != "" {
+			v.Description, desc = t.Description, true
+		}
+	}
+	return v
+}
+
+// Languages lists the shop's own language and every one the menu has a translation in, sorted
+func Languages() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	out := []string{i18n.Default}
+	add := func(tag string) {
+		if !slices.Contains(out, tag) {
+			out = append(out, tag)
+		}
+	}
+	for _, item := range data {
+		for tag := range item.translations {
+			add(tag)
+		}
+	}
+	for tag := range sizeLabels {
+		add(tag)
+	}
+	slices.Sort(out)
+	return out
+}
+
+// MARK: Changing Translations
+
+// SetDescription changes an item's description, "" to take it off
+func SetDescription(ref, description string) error {
+	return editItem(ref, func(item *menuItem) error {
+		item.description = strings.TrimSpace(description)
+		return nil
+	})
+}
+
+// SetTranslation changes what an item is called and how it's described in another language. Leaving both empty
+// takes the translation off
+func SetTranslation(ref, lang, name, description string) error {
+	tag, err := checkLanguage(lang)
+	if err != nil {
+		return err
+	}
+	return editItem(ref, func(item *menuItem) error {
+		t := Translation{Name: strings.TrimSpace(name), Description: strings.TrimSpace(description)}
+		var problems []FieldError
+		if n := len([]rune(t.Name)); n > maxNameLen {
+			problems = append(problems, FieldError{Field: "name", Message: fmt.Sprintf("can be at most %v characters, this is %v", maxNameLen, n)})
+		}
+		if i := strings.IndexFunc(t.Name, func(r rune) bool { return !nameRune(r) }); i >= 0 {
+			problems = append(problems, FieldError{Field: "name", Message: fmt.Sprintf("can't contain %q", []rune(t.Name[i:])[0])})
+		}
+		if n := len([]rune(t.Description)); n > maxDescriptionLen {
+			problems = append(problems, FieldError{Field: "description", Message: fmt.Sprintf("can be at most %v characters, this is %v", maxDescriptionLen, n)})
+		}
+		if len(problems) > 0 {
+			return &ValidationError{Item: item.name + " (" + tag + ")", Problems: problems}
+		}
+
+		item.translations = maps.Clone(item.translations)
+		if t == (Translation{}) {
+			delete(item.translations, tag)
+		} else {
+			if item.translations == nil {
+				item.translations = map[string]Translation{}
+			}
+			item.translations[tag] = t
+		}
+		return nil
+	})
+}
+
+// SetSizeLabel changes what a size is called in another language, "" to go back to its own name
+func SetSizeLabel(lang, size, label string) error {
+	tag, err := checkLanguage(lang)
+	if err != nil {
+		return err
+	}
+	size, label = strings.TrimSpace(size), strings.TrimSpace(label)
+	if n := len([]rune(label)); n > maxSizeLen {
+		return invalid(size, "label", "can be at most %v characters, this is %v", maxSizeLen, n)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	labels := maps.Clone(sizeLabels[tag])
+	if label == "" {
+		delete(labels, size)
+	} else {
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[size] = label
+	}
+	if len(labels) == 0 {
+		delete(sizeLabels, tag)
+	} else {
+		sizeLabels[tag] = labels
+	}
+	changed(time.Now())
+	return nil
+}
+
+// SizeLabels is what the sizes are called in one language, by size
+func SizeLabels(lang string) map[string]string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return maps.Clone(sizeLabels[i18n.Normalize(lang)])
+}
+
+// editItem changes one item on the base menu, as long as it still passes validation afterwards
+func editItem(ref string, edit func(item *menuItem) error) error {
+	mu.Lock()
+	defer mu.Unlock()
+	i := data.find(ref)
+	if i < 0 {
+		return notFound(ref)
+	}
+	item := data[i]
+	if err := edit(&item); err != nil {
+		return err
+	}
+	if err := data.validate(item, i); err != nil {
+		return err
+	}
+	data[i] = item
+	changed(time.Now())
+	return nil
+}
+
+// checkLanguage tidies a language tag for a translation. The shop's own language doesn't need one
+func checkLanguage(lang string) (string, error) {
+	tag := i18n.Normalize(lang)
+	switch {
+	case !i18n.Valid(tag):
+		return "", &ValidationError{Problems: []FieldError{{Field: "language", Message: fmt.Sprintf("%q isn't a language tag, like fr or pt-br", lang)}}}
+	case tag == i18n.Default:
+		return "", &ValidationError{Problems: []FieldError{{Field: "language", Message: tag + " is the menu's own language, change the item itself"}}}
+	}
+	return tag, nil
+}
+
+// cleanTranslations tidies translations read from a file, so fr_FR and fr-fr are the same language
+func cleanTranslations(in map[string]Translation) map[string]Translation {
+	var out map[string]Translation
+	for tag, t := range in {
+		if tag = i18n.Normalize(tag); t != (Translation{}) && i18n.Valid(tag) {
+			if out == nil {
+				out = map[string]Translation{}
+			}
+			out[tag] = t
+		}
+	}
+	return out
+}
+
+// cleanSizeLabels is cleanTranslations for the size labels
+func cleanSizeLabels(in map[string]map[string]string) map[string]map[string]string {
+	out := map[string]map[string]string{}
+	for tag, labels := range in {
+		if tag = i18n.Normalize(tag); len(labels) > 0 && i18n.Valid(tag) {
+			out[tag] = maps.Clone(labels)
+		}
+	}
+	return out
+}
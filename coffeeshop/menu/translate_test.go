@@ -0,0 +1,79 @@
+package menu
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLocalize(t *testing.T) {
+	data = testMenu()
+	data.fillIDs()
+	sizeLabels = map[string]map[string]string{}
+
+	if err := SetDescription("latte", "Espresso and steamed milk"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetTranslation("latte", "fr", "Café au lait", "Expresso et lait chaud"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetTranslation("latte", "fr_CA", "Café latté", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetTranslation("latte", "ja", "ラテ", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetSizeLabel("fr", "small", "petit"); err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range []string{"en", "f", "fr/../x"} {
+		if err := SetTranslation("latte", bad, "x", ""); err == nil {
+			t.Errorf("%q: expected the language to be turned down", bad)
+		}
+	}
+	if err := SetTranslation("latte", "de", "Latte <b>", ""); err == nil {
+		t.Error("Expected a bad name to be turned down")
+	}
+
+	latte, _ := Lookup("latte")
+	tests := []struct {
+		lang, name, desc, small string
+	}{
+		{"", "Latte", "Espresso and steamed milk", ""},
+		{"en-GB", "Latte", "Espresso and steamed milk", ""},
+		{"fr", "Café au lait", "Expresso et lait chaud", "petit"},
+		{"fr-CA", "Café latté", "Expresso et lait chaud", "petit"}, // Falls back to fr for what fr-CA doesn't have
+		{"ja", "ラテ", "Espresso and steamed milk", ""},
+		{"de", "Latte", "Espresso and steamed milk", ""},
+	}
+	for _, tt := range tests {
+		got := Localize([]Item{latte}, tt.lang)[0]
+		if got.Name != tt.name || got.Description != tt.desc || got.Sizes[0].Label != tt.small || got.Sizes[0].Name != "small" {
+			t.Errorf("%q: got %q, %q, %+v", tt.lang, got.Name, got.Description, got.Sizes[0])
+		}
+	}
+	if latte.Sizes[0].Label != "" {
+		t.Error("Localize changed the item it was given")
+	}
+	if got := Languages(); !reflect.DeepEqual(got, []string{"en", "fr", "fr-ca", "ja"}) {
+		t.Errorf("Got languages %v", got)
+	}
+
+	// They're saved with the menu
+	b, _ := json.Marshal(data.stored())
+	if err := load(b, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if got := Localize([]Item{latte}, "fr")[0]; got.Name != "Café au lait" || got.Sizes[0].Label != "petit" {
+		t.Errorf("After loading: got %+v", got)
+	}
+
+	if err := SetTranslation("latte", "ja", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := Localize([]Item{latte}, "ja")[0]; got.Name != "Latte" || got.Lang != "" {
+		t.Errorf("Taking the translation off: got %+v", got)
+	}
+	sizeLabels = map[string]map[string]string{}
+}
@@ -0,0 +1,176 @@
+package menu
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"demo/coffeeshop/money"
+)
+
+// MARK: Validation
+
+// Limits every item has to stay within
+const (
+	maxNameLen = 40 // Fits on the board and the receipt
+	maxSizeLen = 20
+	maxPrice   = 100.0 // Anything more is a typo, probably a missing decimal point
+)
+
+// requiredSizes lists the sizes every item in a category has to come in, saved with the menu and guarded by mu
+var requiredSizes = map[string][]string{}
+
+// Ladders of sizes from smallest to largest. A bigger size can't be cheaper than a smaller one on the same ladder
+var sizeLadders = [][]string{{"small", "medium", "large"}, {"single", "double", "triple"}}
+
+// Animal allergens that don't fit a diet
+const (
+	notVegetarian = Fish | Crustaceans | Molluscs
+	notVegan      = notVegetarian | Milk | Eggs
+)
+
+// validate checks an item that's about to go on m and returns every problem with it at once, as a
+// *ValidationError. skip is the index of the item being changed, so it isn't a duplicate of itself, or -1 for a
+// new item
+func (m menu) validate(item menuItem, skip int) error {
+	return m.validateAt(item, skip, nil)
+}
+
+// validateAt is validate for the item the way store s sells it, with its prices in the store's currency. nil is
+// the base menu
+func (m menu) validateAt(item menuItem, skip int, s *Store) error {
+	cur, most := moneyAt(s).Currency, maxPriceAt(s)
+	amount := money.Format{Currency: cur}.Number // No symbol or separators, the problems are read next to the input
+	ve := &ValidationError{Item: item.name}
+	add := func(field string, err error, format string, args ...any) {
+		ve.Problems = append(ve.Problems, FieldError{Field: field, Message: fmt.Sprintf(format, args...), Err: err})
+	}
+
+	// The name
+	switch n := len([]rune(item.name)); {
+	case n == 0:
+		add("name", nil, "a name is needed")
+	case n > maxNameLen:
+		add("name", nil, "can be at most %v characters, this is %v", maxNameLen, n)
+	}
+	if i := strings.IndexFunc(item.name, func(r rune) bool { return !nameRune(r) }); i >= 0 {
+		add("name", nil, "can't contain %q, only letters, numbers, spaces and - ' & . , ( )", []rune(item.name[i:])[0])
+	}
+	if n := len([]rune(item.description)); n > maxDescriptionLen {
+		add("description", nil, "can be at most %v characters, this is %v", maxDescriptionLen, n)
+	}
+	for i, other := range m {
+		if i != skip && item.name != "" && other.key == item.key {
+			add("name", ErrDuplicateItem, "%q is already on the menu", other.name)
+			break
+		}
+	}
+
+	// The sizes and prices, in order so the problems come out the same way every time
+	sizes := make([]string, 0, len(item.prices))
+	for size := range item.prices {
+		sizes = append(sizes, size)
+	}
+	slices.Sort(sizes)
+	for _, size := range sizes {
+		price := item.prices[size]
+		switch {
+		case strings.TrimSpace(size) == "":
+			add("size", nil, "a size needs a name")
+		case len([]rune(size)) > maxSizeLen:
+			add("size", nil, "%q is longer than %v characters", size, maxSizeLen)
+		case strings.ContainsFunc(size, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != ' ' && r != '-' }):
+			add("size", nil, "%q can only have letters, numbers, spaces and -", size)
+		}
+		switch {
+		case math.IsNaN(price) || price <= 0:
+			add("prices", nil, "%v has to cost something", size)
+		case price > most:
+			add("prices", nil, "%v costs %v, the most anything can cost is %v", size, amount(price), amount(most))
+		case math.Abs(price/cur.Unit()-math.Round(price/cur.Unit())) > 1e-6:
+			add("prices", nil, "%v costs %v, prices can't go smaller than %v", size, price, amount(cur.Unit()))
+		}
+	}
+	for _, size := range requiredSizes[item.category] {
+		if _, ok := item.prices[size]; !ok {
+			add("prices", nil, "every %v needs a %v price", item.category, size)
+		}
+	}
+
+	// Rules that need more than one field to agree
+	for _, ladder := range sizeLadders {
+		for i, smaller := range ladder {
+			for _, bigger := range ladder[i+1:] {
+				s, ok1 := item.prices[smaller]
+				b, ok2 := item.prices[bigger]
+				if ok1 && ok2 && b < s {
+					add("prices", nil, "%v (%v) is cheaper than %v (%v)", bigger, amount(b), smaller, amount(s))
+				}
+			}
+		}
+	}
+	for size := range item.nutrition {
+		if _, ok := item.prices[size]; !ok {
+			add("nutrition", nil, "has facts for %v, which doesn't have a price", size)
+		}
+		if !item.nutrition[size].valid() {
+			add("nutrition", nil, "%v has to be amounts of 0 or more", size)
+		}
+	}
+	if missing := item.missingNutrition(); requireNutrition && len(missing) > 0 {
+		add("nutrition", nil, "missing for %v", strings.Join(missing, ", "))
+	}
+	if item.diet&Vegan != 0 && item.allergens&notVegan != 0 {
+		add("diet", nil, "can't be vegan with %v in it", item.allergens&notVegan)
+	} else if item.diet&Vegetarian != 0 && item.allergens&notVegetarian != 0 {
+		add("diet", nil, "can't be vegetarian with %v in it", item.allergens&notVegetarian)
+	}
+
+	if len(ve.Problems) > 0 {
+		return ve
+	}
+	return nil
+}
+
+// nameRune is whether r can be in an item name. Letters include accented ones, so Café is fine
+func nameRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune(" -'&.,()", r)
+}
+
+// nameKey is what two names have to share to be the same item: case, accents and extra spaces don't count
+func nameKey(name string) string {
+	return strings.Join(strings.Fields(fold(name)), " ")
+}
+
+// SetRequiredSizes makes every item in category come in the given sizes, or lifts the rule if there are none.
+// Like SetRequireNutrition, it can't be set while items on the menu don't follow it
+func SetRequiredSizes(category string, sizes []string) error {
+	category = strings.ToLower(strings.TrimSpace(category))
+	mu.Lock()
+	defer mu.Unlock()
+	var errs []error
+	for _, item := range data {
+		if item.category != category {
+			continue
+		}
+		for _, size := range sizes {
+			if _, ok := item.prices[size]; !ok {
+				errs = append(errs, invalid(item.name, "prices", "every %v needs a %v price", category, size))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	if len(sizes) == 0 {
+		delete(requiredSizes, category)
+	} else {
+		requiredSizes[category] = slices.Clone(sizes)
+	}
+	changed(time.Now())
+	return nil
+}
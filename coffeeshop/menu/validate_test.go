@@ -0,0 +1,57 @@
+package menu
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	m := testMenu()
+	defer func(before map[string][]string) { requiredSizes = before }(requiredSizes)
+	requiredSizes = map[string][]string{"tea": {"small"}}
+
+	tests := []struct {
+		name   string
+		item   menuItem
+		expect []string // The fields with problems, in order
+	}{
+		{"fine", newItem("Flat White", map[string]float64{"small": 3.10, "large": 3.60}, Details{}), nil},
+		{"accents", newItem("Crème Brûlée Latte", nil, Details{}), nil},
+		{"empty", newItem("  ", nil, Details{}), []string{"name"}},
+		{"charset", newItem("Latte <b>", nil, Details{}), []string{"name"}},
+		{"duplicate", newItem("chai  LATTE", nil, Details{}), []string{"name"}},
+		{"prices", newItem("Mocha", map[string]float64{"small": 0, "large": 250, "medium": 3.333}, Details{}), []string{"prices", "prices", "prices"}},
+		{"blank size", newItem("Mocha", map[string]float64{" ": 3}, Details{}), []string{"size"}},
+		{"required size", newItem("Green Tea", map[string]float64{"large": 3}, Details{Category: "tea"}), []string{"prices"}},
+		{"bigger is cheaper", newItem("Mocha", map[string]float64{"small": 3, "large": 2.5}, Details{}), []string{"prices"}},
+		{"nutrition without price", newItem("Mocha", map[string]float64{"small": 3}, Details{Nutrition: map[string]Nutrition{"large": {}}}), []string{"nutrition"}},
+		{"nutrition not a number", newItem("Mocha", map[string]float64{"small": 3}, Details{Nutrition: map[string]Nutrition{"small": {Calories: math.NaN()}}}), []string{"nutrition"}},
+		{"vegan with milk", newItem("Mocha", nil, Details{Diet: Vegan, Allergens: Milk}), []string{"diet"}},
+		{"everything at once", newItem("", map[string]float64{"small": -1}, Details{Diet: Vegetarian, Allergens: Fish}), []string{"name", "prices", "diet"}},
+	}
+	for _, test := range tests {
+		err := m.validate(test.item, -1)
+		var got []string
+		var ve *ValidationError
+		if errors.As(err, &ve) {
+			for _, p := range ve.Problems {
+				got = append(got, p.Field)
+			}
+		} else if err != nil {
+			t.Errorf("%v: got %v, expected a ValidationError", test.name, err)
+		}
+		if !slices.Equal(got, test.expect) {
+			t.Errorf("%v: got problems with %v, expected %v (%v)", test.name, got, test.expect, err)
+		}
+	}
+
+	// An item isn't a duplicate of itself when it's being changed
+	if err := m.validate(m[0], 0); err != nil {
+		t.Errorf("Got %v, expected no problems", err)
+	}
+	if err := m.validate(newItem("CAFE  MOCHA", nil, Details{}), -1); !errors.Is(err, ErrDuplicateItem) {
+		t.Errorf("Got %v, expected ErrDuplicateItem", err)
+	}
+}
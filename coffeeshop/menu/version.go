@@ -0,0 +1,34 @@
+package menu
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// MARK: Versions
+
+var (
+	version  string       // Hash of the menu, "" until someone asks for it after a change
+	modified = time.Now() // When the menu last changed, the built in menu counts as new when the program starts
+)
+
+// changed is called after every edit, with mu held for writing
+func changed(at time.Time) {
+	version = ""
+	modified = at
+}
+
+// Version identifies the menu as it is right now, along with when it last changed. It's a hash of the contents,
+// so the same menu has the same version even after a restart
+func Version() (string, time.Time) {
+	mu.Lock() // Not RLock, we might be filling in version
+	defer mu.Unlock()
+	if version == "" {
+		h := sha256.New()
+		json.NewEncoder(h).Encode(data.stored()) // Map keys come out sorted, so the same menu always hashes the same
+		version = hex.EncodeToString(h.Sum(nil))[:16]
+	}
+	return version, modified
+}
@@ -0,0 +1,129 @@
+package menu
+
+import (
+	"cmp"
+	"encoding/json"
+	"slices"
+	"strings"
+)
+
+// MARK: Views
+
+// Item is a read only copy of a menu item for the renderers. Changing it doesn't change the menu
+type Item struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Lang        string   `json:"lang,omitempty"` // The language Name is in, left out for the shop's own
+	Description string   `json:"description,omitempty"`
+	Category    string   `json:"category,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	SoldOut     bool     `json:"soldOut,omitempty"`
+	Sizes       []Size   `json:"sizes"`
+	Currency    string   `json:"currency,omitempty"` // What the prices are in, left out when the shop hasn't said
+	Allergens   Allergen `json:"allergens"`
+	Diet        Diet     `json:"diet"` // Includes the diets that come from the allergens
+}
+
+// Size is one way an item is sold
+type Size struct {
+	Name      string     `json:"name"`
+	Label     string     `json:"label,omitempty"` // What it's called in the language asked for, see Localize
+	Price     float64    `json:"price"`
+	Nutrition *Nutrition `json:"nutrition,omitempty"` // nil when we don't have the facts
+}
+
+// view copies the item, with the sizes cheapest first so they read small to large
+func (item menuItem) view() Item {
+	v := Item{
+		ID:        item.id,
+		Name:      item.name,
+		Category:  item.category,
+		Tags:      slices.Clone(item.tags),
+		SoldOut:   item.soldOut,
+		Allergens: item.allergens,
+		Diet:      item.diets(),
+
+		Description: item.description,
+	}
+	for size, cost := range item.prices {
+		s := Size{Name: size, Price: cost}
+		if n, ok := item.nutrition[size]; ok {
+			s.Nutrition = &n
+		}
+		v.Sizes = append(v.Sizes, s)
+	}
+	slices.SortFunc(v.Sizes, func(a, b Size) int {
+		if c := cmp.Compare(a.Price, b.Price); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return v
+}
+
+func (m menu) views() []Item {
+	out := make([]Item, len(m))
+	for i, item := range m {
+		out[i] = item.view()
+	}
+	return out
+}
+
+// Items is the whole menu in menu order
+func Items() []Item {
+	mu.RLock()
+	defer mu.RUnlock()
+	return data.viewsAt(nil)
+}
+
+// Lookup finds a single item by its ID or name
+func Lookup(ref string) (Item, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	i := data.find(ref)
+	if i < 0 {
+		return Item{}, notFound(ref)
+	}
+	v := data[i].view()
+	v.Currency = currency
+	return v, nil
+}
+
+// MARK: JSON
+
+// Allergens and diets go out as lists of names, the bits are our business
+func (a Allergen) MarshalJSON() ([]byte, error) {
+	return json.Marshal(nonNil(a.Names()))
+}
+
+func (a *Allergen) UnmarshalJSON(b []byte) error {
+	var names []string
+	if err := json.Unmarshal(b, &names); err != nil {
+		return err
+	}
+	parsed, err := ParseAllergens(strings.Join(names, ","))
+	*a = parsed
+	return err
+}
+
+func (d Diet) MarshalJSON() ([]byte, error) {
+	return json.Marshal(nonNil(d.Names()))
+}
+
+func (d *Diet) UnmarshalJSON(b []byte) error {
+	var names []string
+	if err := json.Unmarshal(b, &names); err != nil {
+		return err
+	}
+	parsed, err := ParseDiets(strings.Join(names, ","))
+	*d = parsed
+	return err
+}
+
+// nonNil makes an empty list come out as [] rather than null
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
@@ -0,0 +1,61 @@
+package menu
+
+import (
+	"context"
+	"crypto/sha256"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// MARK: Watching
+
+// fileSum is the hash of the menu file as we last loaded or saved it, guarded by mu
+var fileSum [sha256.Size]byte
+
+// Watch checks the menu file every so often and loads it again when someone edits it, until ctx is done.
+// It looks at the modification time first and the contents second, so touching the file or saving it
+// unchanged doesn't count. If the new file doesn't parse we keep serving the old menu and log why
+func Watch(ctx context.Context, path string, every time.Duration) {
+	var lastMod time.Time // Zero, so the first check always looks at the contents
+
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			continue // Probably halfway through being replaced, try again next time
+		}
+		if info.ModTime().Equal(lastMod) {
+			continue
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			slog.WarnContext(ctx, "menu: can't read the menu file", "file", path, "error", err)
+			continue
+		}
+		lastMod = info.ModTime()
+		sum := sha256.Sum256(b)
+		mu.RLock()
+		same := sum == fileSum // Arrays are comparable
+		mu.RUnlock()
+		if same {
+			continue
+		}
+
+		if err := load(b, info.ModTime()); err != nil {
+			slog.ErrorContext(ctx, "menu: keeping the current menu, the file has a problem", "file", path, "error", err)
+			mu.Lock()
+			fileSum = sum // Don't complain again until it changes
+			mu.Unlock()
+			continue
+		}
+		slog.InfoContext(ctx, "menu: reloaded", "file", path, "items", len(Items()))
+	}
+}
@@ -0,0 +1,56 @@
+package menu
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// waitFor polls until check passes or a second has gone by
+func waitFor(check func() bool) bool {
+	for end := time.Now().Add(time.Second); time.Now().Before(end); time.Sleep(5 * time.Millisecond) {
+		if check() {
+			return true
+		}
+	}
+	return check()
+}
+
+func TestWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "menu.json")
+	write := func(s string, mod time.Time) {
+		if err := os.WriteFile(path, []byte(s), 0644); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(path, mod, mod) // Make sure the time moves even on file systems with coarse timestamps
+	}
+	hasItem := func(name string) func() bool {
+		return func() bool {
+			_, err := Lookup(name)
+			return err == nil
+		}
+	}
+
+	start := time.Now().Add(-time.Hour)
+	write(`{"items": [{"name": "Mocha", "prices": {"small": 3}}]}`, start)
+	if err := Load(path); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go Watch(ctx, path, 5*time.Millisecond)
+
+	write(`{"items": [{"name": "Flat White", "prices": {"small": 3.2}}]}`, start.Add(time.Minute))
+	if !waitFor(hasItem("Flat White")) {
+		t.Fatal("Expected the edited menu to be loaded")
+	}
+
+	// A broken file is ignored and the menu we had stays
+	write(`{"items": [{"name": "Cortado"`, start.Add(2*time.Minute))
+	time.Sleep(50 * time.Millisecond)
+	if !hasItem("Flat White")() || hasItem("Cortado")() {
+		t.Error("Expected the broken file to be ignored")
+	}
+}
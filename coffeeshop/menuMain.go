@@ -2,39 +2,102 @@ package coffeeshop
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"strings"
 
+	"demo/coffeeshop/i18n"
 	// Adding my own package
 	menu "demo/coffeeshop/menu"
+	"demo/coffeeshop/render"
 )
 
 // MARK: Coffee Shop Demo App
 
 var in = bufio.NewReader(os.Stdin)
 
-func Operate() {
+// shopStore is the store the shell works as, "" for the base menu
+var shopStore string
+
+// shopLang is the language the shell talks and shows the menu in
+var shopLang = i18n.Default
+
+// shopAudit is the audit trail items added in the shell go in, "" to not keep one
+var shopAudit string
+
+// Operate runs the interactive menu until the user quits or stdin runs out (piped input, Ctrl-D), both of which
+// return nil. Anything else that goes wrong reading stdin is returned
+func Operate() error {
+	menu.SetInput(in)
+	ui := i18n.For(shopLang) // The prompts come from the message catalogs, see i18n/catalogs
 loop: // This is a label, it helps us access things like telling the switch what to break
 	for {
-		fmt.Println("Please select an option")
-		fmt.Println("1) Print menu")
-		fmt.Println("2) Add item")
-		fmt.Println("q) Quit")
-		choice, _ := in.ReadString('\n')
+		fmt.Println(ui.T("operate.choose"))
+		fmt.Println(ui.T("operate.print"))
+		fmt.Println(ui.T("operate.add"))
+		fmt.Println(ui.T("operate.search"))
+		fmt.Println(ui.T("operate.quit"))
+		choice, err := in.ReadString('\n')
+		if err != nil && (!errors.Is(err, io.EOF) || strings.TrimSpace(choice) == "") {
+			if errors.Is(err, io.EOF) {
+				break loop // Nothing left to read, so treat it like q
+			}
+			slog.Error("reading the menu option", "error", err)
+			return fmt.Errorf("reading option: %w", err)
+		}
 
 		switch strings.TrimSpace(choice) {
 		case "1":
-			menu.PrintMenu()
+			items, _ := menu.StoreItems(shopStore) // runShell already checked the store
+			f, _ := menu.Money(shopStore)
+			render.Text{Money: f}.Menu(os.Stdout, menu.Localize(items, shopLang))
 		case "2":
+			fmt.Println(ui.T("operate.add.name"))
 			err := menu.AddItem()
+			if errors.Is(err, io.EOF) {
+				break loop
+			}
 			if err != nil { // True if error occured
-				fmt.Println(fmt.Errorf("invalid input: %w", err)) // Sometimes we don't want to return the actual error message to the user, but maybe log it somewhere
+				// Sometimes we don't want to return the actual error message to the user, so it goes in the log
+				// and they get something friendlier
+				msg, known := userMessage(err)
+				if !known {
+					slog.Error("adding an item", "error", err)
+				}
+				fmt.Println(msg)
+				continue
+			}
+			if shopAudit != "" {
+				items := menu.Items() // In menu order, so the new one's last
+				added := items[len(items)-1]
+				if err := recordChange(shopAudit, "menu.add", fmt.Sprintf("%v (%v)", added.Name, added.ID)); err != nil {
+					slog.Error("writing the audit trail", "error", err)
+				}
+			}
+		case "3":
+			fmt.Println(ui.T("operate.search.ask"))
+			text, err := in.ReadString('\n')
+			if err != nil && (!errors.Is(err, io.EOF) || strings.TrimSpace(text) == "") {
+				if errors.Is(err, io.EOF) {
+					break loop // Same as above, no more input
+				}
+				slog.Error("reading the search", "error", err)
+				return fmt.Errorf("reading search: %w", err)
+			}
+			found := menu.Search(menu.Query{Text: text, Store: shopStore})
+			if len(found) == 0 {
+				fmt.Println(ui.T("operate.search.none"))
 			}
+			f, _ := menu.Money(shopStore)
+			render.Text{Money: f}.Menu(os.Stdout, menu.Localize(found, shopLang))
 		case "q":
 			break loop
 		default:
-			fmt.Println("Unknown option")
+			fmt.Println(ui.T("operate.unknown"))
 		}
 	}
+	return nil
 }
@@ -0,0 +1,241 @@
+package metrics
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MARK: Metrics
+
+// A small version of what the Prometheus client library does: counters, gauges and histograms with labels, written
+// out in the text format Prometheus scrapes. Metrics are made once as package variables and register themselves
+
+type kind string
+
+const (
+	counter   kind = "counter"
+	gauge     kind = "gauge"
+	histogram kind = "histogram"
+)
+
+// metric is what every kind has in common. Each set of label values is its own series
+type metric struct {
+	name   string
+	help   string
+	kind   kind
+	labels []string
+
+	mu     sync.Mutex
+	series map[string]*series // Keyed by the label values joined together
+	read   func() float64     // Set for gauges that are worked out when scraped
+	bounds []float64          // Histogram bucket upper bounds, smallest first
+}
+
+type series struct {
+	values []string
+	value  float64  // Counter and gauge value, the sum for a histogram
+	counts []uint64 // Histogram only, one per bound (not cumulative)
+	count  uint64   // Histogram only
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = map[string]*metric{}
+)
+
+func register(m *metric) *metric {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[m.name]; ok {
+		panic("metrics: " + m.name + " registered twice") // Always a programming mistake, like a duplicate flag
+	}
+	m.series = make(map[string]*series)
+	registry[m.name] = m
+	return m
+}
+
+// get finds or makes the series for the label values, call with mu held
+func (m *metric) get(values []string) *series {
+	if len(values) != len(m.labels) {
+		panic(fmt.Sprintf("metrics: %v takes %v label values, got %v", m.name, len(m.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff") // A byte that can't appear in UTF-8, so different values can't collide
+	s, ok := m.series[key]
+	if !ok {
+		s = &series{values: slices.Clone(values)}
+		if m.kind == histogram {
+			s.counts = make([]uint64, len(m.bounds))
+		}
+		m.series[key] = s
+	}
+	return s
+}
+
+// Counter only goes up: requests served, orders placed
+type Counter struct{ m *metric }
+
+func NewCounter(name, help string, labels ...string) *Counter {
+	return &Counter{register(&metric{name: name, help: help, kind: counter, labels: labels})}
+}
+
+// Inc adds one to the series for the label values, given in the same order as the label names
+func (c *Counter) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+func (c *Counter) Add(v float64, values ...string) {
+	if v < 0 {
+		panic("metrics: counters can't go down")
+	}
+	c.m.mu.Lock()
+	defer c.m.mu.Unlock()
+	c.m.get(values).value += v
+}
+
+// Gauge goes up and down: requests in flight, items on the menu
+type Gauge struct{ m *metric }
+
+func NewGauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{register(&metric{name: name, help: help, kind: gauge, labels: labels})}
+}
+
+func (g *Gauge) Set(v float64, values ...string) {
+	g.m.mu.Lock()
+	defer g.m.mu.Unlock()
+	g.m.get(values).value = v
+}
+
+func (g *Gauge) Add(v float64, values ...string) {
+	g.m.mu.Lock()
+	defer g.m.mu.Unlock()
+	g.m.get(values).value += v
+}
+
+func (g *Gauge) Inc(values ...string) { g.Add(1, values...) }
+func (g *Gauge) Dec(values ...string) { g.Add(-1, values...) }
+
+// NewGaugeFunc is a gauge without labels that calls read every time it's scraped, for numbers we already keep
+// somewhere else
+func NewGaugeFunc(name, help string, read func() float64) {
+	register(&metric{name: name, help: help, kind: gauge, read: read})
+}
+
+// Histogram counts observations into buckets: how long requests take, what orders come to
+type Histogram struct{ m *metric }
+
+// DefBuckets suit request durations in seconds, the same as the Prometheus client's
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	bounds := slices.Clone(buckets)
+	slices.Sort(bounds)
+	return &Histogram{register(&metric{name: name, help: help, kind: histogram, labels: labels, bounds: bounds})}
+}
+
+func (h *Histogram) Observe(v float64, values ...string) {
+	h.m.mu.Lock()
+	defer h.m.mu.Unlock()
+	s := h.m.get(values)
+	if i, _ := slices.BinarySearch(h.m.bounds, v); i < len(s.counts) {
+		s.counts[i]++ // Bounds are "less than or equal", which is what BinarySearch finds
+	}
+	s.value += v
+	s.count++
+}
+
+// MARK: Exposition
+
+// Handler serves every registered metric in the Prometheus text format
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		Write(w)
+	})
+}
+
+// Write writes every metric, sorted by name so the output is stable
+func Write(w io.Writer) error {
+	registryMu.Lock()
+	all := make([]*metric, 0, len(registry))
+	for _, m := range registry {
+		all = append(all, m)
+	}
+	registryMu.Unlock()
+	slices.SortFunc(all, func(a, b *metric) int { return cmp.Compare(a.name, b.name) })
+
+	var b strings.Builder
+	for _, m := range all {
+		m.write(&b)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (m *metric) write(b *strings.Builder) {
+	fmt.Fprintf(b, "# HELP %v %v\n# TYPE %v %v\n", m.name, helpEscaper.Replace(m.help), m.name, m.kind)
+	if m.read != nil {
+		fmt.Fprintf(b, "%v %v\n", m.name, number(m.read()))
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keys := make([]string, 0, len(m.series))
+	for k := range m.series {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		s := m.series[k]
+		if m.kind != histogram {
+			fmt.Fprintf(b, "%v%v %v\n", m.name, labelSet(m.labels, s.values), number(s.value))
+			continue
+		}
+		var cumulative uint64
+		names := append(slices.Clone(m.labels), "le")
+		for i, bound := range m.bounds {
+			cumulative += s.counts[i]
+			fmt.Fprintf(b, "%v_bucket%v %v\n", m.name, labelSet(names, append(slices.Clone(s.values), number(bound))), cumulative)
+		}
+		fmt.Fprintf(b, "%v_bucket%v %v\n", m.name, labelSet(names, append(slices.Clone(s.values), "+Inf")), s.count)
+		fmt.Fprintf(b, "%v_sum%v %v\n", m.name, labelSet(m.labels, s.values), number(s.value))
+		fmt.Fprintf(b, "%v_count%v %v\n", m.name, labelSet(m.labels, s.values), s.count)
+	}
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+// labelSet is {name="value",...}, or nothing at all when there are no labels
+func labelSet(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + `="` + labelEscaper.Replace(values[i]) + `"`
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// number formats a value the way Prometheus expects, including its spelling of infinity
+func number(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	c := NewCounter("test_requests_total", "Requests.\nSecond line", "path")
+	c.Inc("/")
+	c.Add(2, `/say "hi"`)
+	g := NewGauge("test_queue_depth", "Queue depth.")
+	g.Inc()
+	g.Inc()
+	g.Dec()
+	h := NewHistogram("test_duration_seconds", "Durations.", []float64{1, 0.5})
+	h.Observe(0.5)
+	h.Observe(0.7)
+	h.Observe(3)
+
+	var b strings.Builder
+	if err := Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, line := range strings.Split(b.String(), "\n") {
+		if strings.Contains(line, "test_") {
+			got = append(got, line)
+		}
+	}
+	expect := []string{
+		"# HELP test_duration_seconds Durations.",
+		"# TYPE test_duration_seconds histogram",
+		`test_duration_seconds_bucket{le="0.5"} 1`,
+		`test_duration_seconds_bucket{le="1"} 2`,
+		`test_duration_seconds_bucket{le="+Inf"} 3`,
+		"test_duration_seconds_sum 4.2",
+		"test_duration_seconds_count 3",
+		"# HELP test_queue_depth Queue depth.",
+		"# TYPE test_queue_depth gauge",
+		"test_queue_depth 1",
+		`# HELP test_requests_total Requests.\nSecond line`,
+		"# TYPE test_requests_total counter",
+		`test_requests_total{path="/"} 1`,
+		`test_requests_total{path="/say \"hi\""} 2`,
+	}
+	if strings.Join(got, "\n") != strings.Join(expect, "\n") {
+		t.Errorf("Got\n%v\nexpected\n%v", strings.Join(got, "\n"), strings.Join(expect, "\n"))
+	}
+}
@@ -0,0 +1,245 @@
+package money
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// MARK: Currencies
+
+// Currency is what prices are counted in. Decimals is how many digits come after the point, 0 for yen
+type Currency struct {
+	Code     string // ISO 4217, like USD
+	Symbol   string
+	Decimals int
+	locale   string // Where it's usually spent, for when no locale is given
+}
+
+// The currencies we know. Adding one is a line here, and maybe a locale below
+var currencies = []Currency{
+	{"USD", "$", 2, "en-US"},
+	{"CAD", "$", 2, "en-CA"},
+	{"AUD", "$", 2, "en-AU"},
+	{"GBP", "£", 2, "en-GB"},
+	{"EUR", "€", 2, "de-DE"},
+	{"CHF", "CHF", 2, "de-CH"},
+	{"SEK", "kr", 2, "sv-SE"},
+	{"JPY", "¥", 0, "ja-JP"},
+	{"KRW", "₩", 0, "ko-KR"},
+}
+
+// denominations are each currency's notes and coins, biggest first. Dollars are also the zero Currency's, the
+// drawer the shop had before it had currencies
+var denominations = map[string][]float64{
+	"":    dollars,
+	"USD": dollars,
+	"CAD": {100, 50, 20, 10, 5, 2, 1, 0.25, 0.10, 0.05}, // No pennies since 2013
+	"AUD": {100, 50, 20, 10, 5, 2, 1, 0.50, 0.20, 0.10, 0.05},
+	"GBP": {50, 20, 10, 5, 2, 1, 0.50, 0.20, 0.10, 0.05, 0.02, 0.01},
+	"EUR": {500, 200, 100, 50, 20, 10, 5, 2, 1, 0.50, 0.20, 0.10, 0.05, 0.02, 0.01},
+	"CHF": {1000, 200, 100, 50, 20, 10, 5, 2, 1, 0.50, 0.20, 0.10, 0.05},
+	"SEK": {1000, 500, 200, 100, 50, 20, 10, 5, 2, 1},
+	"JPY": {10000, 5000, 2000, 1000, 500, 100, 50, 10, 5, 1},
+	"KRW": {50000, 10000, 5000, 1000, 500, 100, 50, 10},
+}
+
+var dollars = []float64{100, 50, 20, 10, 5, 1, 0.25, 0.10, 0.05, 0.01}
+
+// LookupCurrency finds a currency by its code, in any case
+func LookupCurrency(code string) (Currency, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	for _, c := range currencies {
+		if c.Code == code {
+			return c, nil
+		}
+	}
+	return Currency{}, fmt.Errorf("unknown currency %q, expected one of %v", code, strings.Join(Currencies(), ", "))
+}
+
+// Currencies lists the codes we know, sorted
+func Currencies() []string {
+	out := make([]string, len(currencies))
+	for i, c := range currencies {
+		out[i] = c.Code
+	}
+	slices.Sort(out)
+	return out
+}
+
+// Unit is the smallest amount there is, 0.01 for dollars and 1 for yen
+func (c Currency) Unit() float64 {
+	return math.Pow10(-c.Places())
+}
+
+// Round rounds an amount to the smallest unit, so a converted 3.456 dollars is 3.46 and 512.4 yen is 512
+func (c Currency) Round(v float64) float64 {
+	p := math.Pow10(c.Places())
+	return math.Round(v*p) / p
+}
+
+// Denominations are the notes and coins there are, biggest first
+func (c Currency) Denominations() []float64 {
+	return slices.Clone(denominations[c.Code])
+}
+
+// Places is how many decimals amounts have. It's 2 for the zero Currency, which is how prices were before there
+// were currencies
+func (c Currency) Places() int {
+	if c.Code == "" {
+		return 2
+	}
+	return c.Decimals
+}
+
+// MARK: Locales
+
+// Locale is how a place writes money: what separates the decimals and the thousands, and which side of the
+// number the symbol goes on
+type Locale struct {
+	Tag         string // BCP 47, like en-US
+	Decimal     string
+	Group       string // Thousands, every three digits
+	SymbolAfter bool   // 3,50 € rather than €3.50
+	Space       bool   // Between the symbol and the number
+}
+
+// Non-breaking spaces so a price never wraps in the middle
+const (
+	nbsp       = "\u00a0"
+	narrowNBSP = "\u202f"
+)
+
+var locales = []Locale{
+	{"en-US", ".", ",", false, false},
+	{"en-CA", ".", ",", false, false},
+	{"en-AU", ".", ",", false, false},
+	{"en-GB", ".", ",", false, false},
+	{"en-IE", ".", ",", false, false},
+	{"fr-CA", ",", nbsp, true, true},
+	{"fr-FR", ",", narrowNBSP, true, true},
+	{"de-DE", ",", ".", true, true},
+	{"de-AT", ",", nbsp, false, true},
+	{"de-CH", ".", "’", false, true},
+	{"es-ES", ",", ".", true, true},
+	{"it-IT", ",", ".", true, true},
+	{"nl-NL", ",", ".", false, true},
+	{"sv-SE", ",", nbsp, true, true},
+	{"ja-JP", ".", ",", false, false},
+	{"ko-KR", ".", ",", false, false},
+}
+
+// LookupLocale finds a locale by its tag. Case and _ vs - don't matter, and just the language (de) gets that
+// language's first locale
+func LookupLocale(tag string) (Locale, error) {
+	want := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+	for _, l := range locales {
+		if strings.ToLower(l.Tag) == want {
+			return l, nil
+		}
+	}
+	for _, l := range locales {
+		if lang, _, _ := strings.Cut(strings.ToLower(l.Tag), "-"); lang == want {
+			return l, nil
+		}
+	}
+	return Locale{}, fmt.Errorf("unknown locale %q, expected one of %v", tag, strings.Join(Locales(), ", "))
+}
+
+// Locales lists the tags we know, sorted
+func Locales() []string {
+	out := make([]string, len(locales))
+	for i, l := range locales {
+		out[i] = l.Tag
+	}
+	slices.Sort(out)
+	return out
+}
+
+// MARK: Formatting
+
+// Format writes amounts of one currency the way one locale does. The zero Format writes plain numbers with two
+// decimals and no symbol, which is how every price looked before the shop had currencies
+type Format struct {
+	Currency Currency
+	Locale   Locale
+}
+
+// New is the format for a currency code and locale tag. An empty locale uses the one the currency is usually
+// spent in, and an empty currency is the zero Format
+func New(currency, locale string) (Format, error) {
+	if strings.TrimSpace(currency) == "" {
+		if strings.TrimSpace(locale) != "" {
+			return Format{}, fmt.Errorf("locale %v needs a currency to go with it", locale)
+		}
+		return Format{}, nil
+	}
+	c, err := LookupCurrency(currency)
+	if err != nil {
+		return Format{}, err
+	}
+	if strings.TrimSpace(locale) == "" {
+		locale = c.locale
+	}
+	l, err := LookupLocale(locale)
+	if err != nil {
+		return Format{}, err
+	}
+	return Format{c, l}, nil
+}
+
+// Amount writes v with the symbol, like $1,234.50, 1.234,50 € or ¥1,235
+func (f Format) Amount(v float64) string {
+	s := f.Number(v)
+	if f.Currency.Symbol == "" {
+		return s
+	}
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	sep := ""
+	if f.Locale.Space {
+		sep = nbsp
+	}
+	if f.Locale.SymbolAfter {
+		s = s + sep + f.Currency.Symbol
+	} else {
+		s = f.Currency.Symbol + sep + s
+	}
+	if neg {
+		s = "-" + s
+	}
+	return s
+}
+
+// Number writes v rounded to the currency's decimals with the locale's separators, but no symbol
+func (f Format) Number(v float64) string {
+	d := f.Currency.Places()
+	s := strconv.FormatFloat(math.Abs(f.Currency.Round(v)), 'f', d, 64)
+	whole, frac, _ := strings.Cut(s, ".")
+	if f.Locale.Group != "" {
+		for i := len(whole) - 3; i > 0; i -= 3 {
+			whole = whole[:i] + f.Locale.Group + whole[i:]
+		}
+	}
+	if frac != "" {
+		dec := f.Locale.Decimal
+		if dec == "" {
+			dec = "."
+		}
+		whole += dec + frac
+	}
+	if f.Currency.Round(v) < 0 {
+		whole = "-" + whole
+	}
+	return whole
+}
+
+// String describes the format, like JPY (ja-JP), or "" for the zero Format
+func (f Format) String() string {
+	if f.Currency.Code == "" {
+		return ""
+	}
+	return f.Currency.Code + " (" + f.Locale.Tag + ")"
+}
@@ -0,0 +1,49 @@
+package money
+
+import "testing"
+
+func TestAmount(t *testing.T) {
+	tests := []struct {
+		currency, locale string
+		v                float64
+		expect           string
+	}{
+		{"", "", 3.5, "3.50"},
+		{"", "", 1234.5, "1234.50"}, // Plain, like it always was
+		{"USD", "", 1234.5, "$1,234.50"},
+		{"usd", "en_us", -3.5, "-$3.50"},
+		{"EUR", "de-DE", 1234.5, "1.234,50\u00a0€"},
+		{"EUR", "nl", 3.5, "€\u00a03,50"},
+		{"EUR", "fr-FR", 1234567.891, "1\u202f234\u202f567,89\u00a0€"},
+		{"JPY", "", 512.4, "¥512"},
+		{"JPY", "", 1499.5, "¥1,500"},
+		{"CHF", "", 1234.5, "CHF\u00a01’234.50"},
+		{"GBP", "", 0.5, "£0.50"},
+	}
+	for _, tt := range tests {
+		f, err := New(tt.currency, tt.locale)
+		if err != nil {
+			t.Fatalf("%v %v: %v", tt.currency, tt.locale, err)
+		}
+		if got := f.Amount(tt.v); got != tt.expect {
+			t.Errorf("%v %v %v: got %q, expected %q", tt.currency, tt.locale, tt.v, got, tt.expect)
+		}
+	}
+
+	for _, bad := range [][2]string{{"XYZ", ""}, {"USD", "tlh"}, {"", "en-US"}} {
+		if _, err := New(bad[0], bad[1]); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+func TestRound(t *testing.T) {
+	jpy, _ := LookupCurrency("JPY")
+	usd, _ := LookupCurrency("USD")
+	if jpy.Round(512.5) != 513 || jpy.Unit() != 1 || usd.Round(3.456) != 3.46 || usd.Unit() != 0.01 {
+		t.Errorf("Got %v %v %v %v", jpy.Round(512.5), jpy.Unit(), usd.Round(3.456), usd.Unit())
+	}
+	if (Currency{}).Round(3.456) != 3.46 {
+		t.Error("The zero currency should round to cents")
+	}
+}
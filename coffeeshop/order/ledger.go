@@ -0,0 +1,103 @@
+package order
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// MARK: Ledger
+
+// The ledger is every order placed, one JSON object per line, for the reports. Like the audit trail it's only
+// ever added to
+var (
+	ledgerMu sync.Mutex
+	ledger   io.Writer = io.Discard // Nothing is kept until OpenLedger is called
+)
+
+// OpenLedger appends placed orders to the file at path from now on. The returned func closes it
+func OpenLedger(path string) (func() error, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	SetLedger(f)
+	return func() error {
+		SetLedger(io.Discard)
+		return f.Close()
+	}, nil
+}
+
+// SetLedger sends placed orders to w, tests use a bytes.Buffer
+func SetLedger(w io.Writer) {
+	ledgerMu.Lock()
+	defer ledgerMu.Unlock()
+	ledger = w
+}
+
+// record adds a placed order to the ledger
+func record(o Order) error {
+	b, err := json.Marshal(o)
+	if err != nil {
+		return err
+	}
+	ledgerMu.Lock()
+	defer ledgerMu.Unlock()
+	_, err = ledger.Write(append(b, '\n'))
+	return err
+}
+
+// Record writes an order that's already been placed to the ledger again, after a refund say. Lines are only
+// ever added, so the newest one for an order is the one that counts
+func Record(o Order) error {
+	if o.ID == "" {
+		return errors.New("only placed orders go in the ledger")
+	}
+	return record(o)
+}
+
+// ReadLedger reads back every order in a ledger, oldest first. An order that was recorded again is where it was
+// first placed, as it was last recorded
+func ReadLedger(r io.Reader) ([]Order, error) {
+	var orders []Order
+	seen := map[string]int{}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20) // A big order is still one line
+	for line := 1; sc.Scan(); line++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var o Order
+		if err := json.Unmarshal(sc.Bytes(), &o); err != nil {
+			return nil, fmt.Errorf("line %v: %w", line, err)
+		}
+		if i, ok := seen[o.ID]; ok && o.ID != "" {
+			orders[i] = o
+			continue
+		}
+		seen[o.ID] = len(orders)
+		orders = append(orders, o)
+	}
+	return orders, sc.Err()
+}
+
+// LoadLedger reads the ledger file at path. A ledger that doesn't exist yet has no orders in it
+func LoadLedger(path string) ([]Order, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	orders, err := ReadLedger(f)
+	if err != nil {
+		return nil, fmt.Errorf("reading %v: %w", path, err)
+	}
+	return orders, nil
+}
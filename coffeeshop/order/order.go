@@ -0,0 +1,204 @@
+package order
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"demo/coffeeshop/logging"
+	menu "demo/coffeeshop/menu"
+	"demo/coffeeshop/metrics"
+)
+
+// MARK: Orders
+
+// Line is one item on an order. The quote is taken when the line is added, so later menu changes don't change
+// what the customer was charged or told
+type Line struct {
+	menu.Quote
+	Modifiers []string `json:"modifiers,omitempty"`
+	Qty       int      `json:"qty"`
+}
+
+// Total is the price of the line times how many were ordered
+func (l Line) Total() float64 {
+	return l.Price * float64(l.Qty)
+}
+
+type Order struct {
+	ID     string    `json:"id,omitempty"`    // Given out by Place
+	Store  string    `json:"store,omitempty"` // Where it was taken, set before adding lines since prices differ
+	Placed time.Time `json:"placed"`          // Set by Place
+	Lines  []Line    `json:"lines"`
+
+	Discount float64 `json:"discount,omitempty"` // Taken off the whole order, see Discount
+	Tax      float64 `json:"tax,omitempty"`      // How much of the total was sales tax, set by Place
+
+	Tenders []Tender `json:"tenders,omitempty"` // How it was paid, see payment.go
+	Refunds []Refund `json:"refunds,omitempty"`
+}
+
+// Add puts qty of an item on the order, at the order's store's prices
+func (o *Order) Add(item, size string, modifiers []string, qty int) error {
+	if qty < 1 {
+		return fmt.Errorf("quantity has to be at least 1, got %v", qty)
+	}
+	q, err := menu.QuoteAt(o.Store, item, size, modifiers)
+	if err == nil {
+		// Sold out items can still be looked at (and their nutrition checked), just not ordered
+		if mi, _ := menu.LookupAt(o.Store, item); mi.SoldOut {
+			err = fmt.Errorf("%v is %w", mi.Name, menu.ErrUnavailable)
+		}
+	}
+	if err != nil {
+		lineErrors.Inc()
+		return err
+	}
+	o.Lines = append(o.Lines, Line{Quote: q, Modifiers: modifiers, Qty: qty})
+	return nil
+}
+
+// MARK: Metrics
+
+var (
+	ordersPlaced = metrics.NewCounter("coffeeshop_orders_total", "Orders placed, by store.", "store")
+	orderValue   = metrics.NewHistogram("coffeeshop_order_value", "What orders came to.",
+		[]float64{2, 4, 6, 8, 10, 15, 20, 30, 50})
+	itemsOrdered = metrics.NewCounter("coffeeshop_items_ordered_total", "Drinks ordered, by store, item and size.", "store", "item", "size")
+	lineErrors   = metrics.NewCounter("coffeeshop_order_line_errors_total",
+		"Order lines turned down because the item, size or a modifier wasn't on the menu.")
+)
+
+// placed is when each order placed since we started was placed, by ID. An order placed again under the same ID
+// (a retried request with the same idempotency key) is the first one, not another
+var (
+	placedMu sync.Mutex
+	placed   = map[string]time.Time{}
+)
+
+// Place is the end of taking an order: it gets an ID, is logged, counted and goes in the ledger and the barista's
+// queue, see queue.go. The returned context carries the order ID, so anything logged with it afterwards can be
+// matched to the order. Placing an ID that's already been placed only gives the order its first Placed time back
+func (o *Order) Place(ctx context.Context) context.Context {
+	if o.ID == "" {
+		o.ID = newID()
+	}
+	if rate := menu.TaxRate(); rate > 0 && o.Tax == 0 {
+		o.Tax = o.round(o.Total() * rate / (1 + rate)) // The prices include it
+	}
+	ctx = logging.WithOrderID(ctx, o.ID)
+	placedMu.Lock()
+	first, again := placed[o.ID]
+	if !again {
+		if o.Placed.IsZero() {
+			o.Placed = time.Now()
+		}
+		placed[o.ID] = o.Placed
+	}
+	placedMu.Unlock()
+	if again {
+		o.Placed = first
+		return ctx
+	}
+
+	slog.InfoContext(ctx, "order placed", "store", o.Store, "lines", len(o.Lines), "total", o.Total())
+	if err := record(*o); err != nil {
+		// The customer has their order either way, the reports will be short one
+		slog.ErrorContext(ctx, "writing the order to the ledger", "error", err)
+	}
+
+	enqueue(*o)
+	ordersPlaced.Inc(o.Store)
+	orderValue.Observe(o.Total())
+	for _, l := range o.Lines {
+		itemsOrdered.Add(float64(l.Qty), o.Store, l.Item, l.Size)
+	}
+	return ctx
+}
+
+// AlreadyPlaced is whether an order with this ID has been placed since we started
+func AlreadyPlaced(id string) bool {
+	placedMu.Lock()
+	defer placedMu.Unlock()
+	_, ok := placed[id]
+	return ok
+}
+
+// newID is short enough to read out over the counter and random enough not to repeat in a day's orders
+func newID() string {
+	b := make([]byte, 5)
+	rand.Read(b)
+	return strings.ToUpper(hex.EncodeToString(b))
+}
+
+// Subtotal is what the lines add up to, before the discount
+func (o Order) Subtotal() float64 {
+	total := 0.0
+	for _, l := range o.Lines {
+		total += l.Total()
+	}
+	return total
+}
+
+// Total is what the whole order costs, after the discount
+func (o Order) Total() float64 {
+	if o.Discount == 0 {
+		return o.Subtotal() // Not rounded, so orders from before discounts add up the way they always did
+	}
+	return o.round(o.Subtotal() - o.Discount)
+}
+
+// ErrBadDiscount is a discount for less than nothing or more than the order
+var ErrBadDiscount = errors.New("a discount has to be between nothing and the whole order")
+
+// SetDiscount takes amount off the order. It goes on once the lines are in and before anything is paid, since
+// what's due depends on it
+func (o *Order) SetDiscount(amount float64) error {
+	amount = o.round(amount)
+	if math.IsNaN(amount) || amount < 0 || amount > o.Subtotal() {
+		return fmt.Errorf("%v off %v: %w", amount, o.round(o.Subtotal()), ErrBadDiscount)
+	}
+	if len(o.Tenders) > 0 {
+		return fmt.Errorf("the order's already being paid for, a discount has to go on first")
+	}
+	o.Discount = amount
+	return nil
+}
+
+// Currency is what the order was priced in, "" when the shop hasn't said. Every line is in the store's
+// currency, so the first one speaks for them all
+func (o Order) Currency() string {
+	if len(o.Lines) == 0 {
+		return ""
+	}
+	return o.Lines[0].Currency
+}
+
+// Nutrition adds up every line we have nutrition facts for. complete is false if some lines didn't have any
+func (o Order) Nutrition() (n menu.Nutrition, complete bool) {
+	complete = true
+	for _, l := range o.Lines {
+		if !l.HasFacts {
+			complete = false
+			continue
+		}
+		n = n.Plus(l.Nutrition.Times(float64(l.Qty)))
+	}
+	return n, complete
+}
+
+// Allergens is every allergen anywhere on the order
+func (o Order) Allergens() menu.Allergen {
+	var a menu.Allergen
+	for _, l := range o.Lines {
+		a |= l.Allergens
+	}
+	return a
+}
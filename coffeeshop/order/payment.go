@@ -0,0 +1,210 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"demo/coffeeshop/money"
+	"demo/coffeeshop/payment"
+)
+
+// MARK: Payments
+
+// Tender is one payment towards an order. An order can be split over several, some cash and the rest on a card
+type Tender struct {
+	Provider  string    `json:"provider"` // cash or card
+	AuthID    string    `json:"authId"`
+	CaptureID string    `json:"captureId"`
+	Amount    float64   `json:"amount"`
+	Tendered  float64   `json:"tendered,omitempty"` // Cash handed over
+	Change    float64   `json:"change,omitempty"`
+	Card      string    `json:"card,omitempty"` // Last four digits only
+	Refunded  float64   `json:"refunded,omitempty"`
+	Key       string    `json:"key"` // The idempotency key it was paid with
+	At        time.Time `json:"at"`
+}
+
+// Refund is money given back, from one tender
+type Refund struct {
+	Provider  string    `json:"provider"`
+	CaptureID string    `json:"captureId"`
+	ID        string    `json:"id"`
+	Amount    float64   `json:"amount"`
+	Key       string    `json:"key"` // The key Refund was called with, the same for every tender it came from
+	At        time.Time `json:"at"`
+}
+
+// ErrOverpaid is a payment for more than is left to pay, ErrUnderpaid is payments that don't cover the order
+var (
+	ErrOverpaid  = errors.New("that's more than is owed")
+	ErrUnderpaid = errors.New("the payments don't cover the order")
+)
+
+// Paid is what's been taken for the order, before any refunds
+func (o Order) Paid() float64 {
+	paid := 0.0
+	for _, t := range o.Tenders {
+		paid += t.Amount
+	}
+	return o.round(paid)
+}
+
+// Refunded is what's been given back
+func (o Order) Refunded() float64 {
+	refunded := 0.0
+	for _, r := range o.Refunds {
+		refunded += r.Amount
+	}
+	return o.round(refunded)
+}
+
+// Completed is an order that was paid for in full and hasn't all been given back, so it counts as a sale. An
+// order placed without taking payment was only priced, and one refunded in full didn't sell anything. One the
+// discount took down to nothing is complete with no payments at all
+func (o Order) Completed() bool {
+	if o.Due() > 0 || len(o.Tenders) == 0 && o.Total() > 0 {
+		return false
+	}
+	return o.Paid() == 0 || o.Refunded() < o.Paid()
+}
+
+// Due is what's still to pay
+func (o Order) Due() float64 {
+	return o.round(o.Total() - o.Paid())
+}
+
+// round is to the smallest coin of the order's currency, so adding up tenders doesn't leave 0.0000001 due
+func (o Order) round(v float64) float64 {
+	cur, _ := money.LookupCurrency(o.Currency()) // The zero Currency, 2 decimals, for an order from before
+	return cur.Round(v)
+}
+
+// Pay takes a payment towards the order with p. When req leaves the amount out it's what's due, or for cash
+// what was handed over if that's less, so the rest can go on a card. The order gets
+// its ID here if it doesn't have one, since the idempotency keys are made from it: paying again with the same
+// key (retrying after a timeout, say) gives back the tender that was already taken instead of charging twice
+func (o *Order) Pay(ctx context.Context, p payment.PaymentProvider, req payment.Request) (Tender, error) {
+	if o.ID == "" {
+		o.ID = newID()
+	}
+	if req.Key == "" {
+		req.Key = fmt.Sprintf("%v-%v", o.ID, len(o.Tenders)+1)
+	}
+	if i := slices.IndexFunc(o.Tenders, func(t Tender) bool { return t.Key == req.Key }); i >= 0 {
+		return o.Tenders[i], nil
+	}
+	due := o.Due()
+	if req.Amount == 0 {
+		req.Amount = due
+		if req.Tendered > 0 {
+			req.Amount = min(req.Tendered, due)
+		}
+	}
+	if req.Amount > due {
+		return Tender{}, fmt.Errorf("paying %v with %v due: %w", req.Amount, due, ErrOverpaid)
+	}
+	req.Currency = o.Currency()
+
+	a, err := p.Authorize(ctx, req)
+	if err != nil {
+		return Tender{}, err
+	}
+	c, err := p.Capture(ctx, a.ID, a.Amount, req.Key+"/capture")
+	if err != nil {
+		// Let the hold go, so the customer isn't left with money tied up for a payment that didn't happen
+		if verr := p.Void(ctx, a.ID, req.Key+"/void"); verr != nil && !errors.Is(verr, payment.ErrVoided) {
+			err = errors.Join(err, fmt.Errorf("voiding %v: %w", a.ID, verr))
+		}
+		return Tender{}, err
+	}
+	if c.Refunded > 0 {
+		// A retry of a payment that was taken and then given back, the request it was for must have failed
+		return Tender{}, fmt.Errorf("%v was paid and given back, pay again with a new key: %w", req.Key, payment.ErrKeyReused)
+	}
+	t := Tender{Provider: p.Name(), AuthID: a.ID, CaptureID: c.ID, Amount: c.Amount, Tendered: a.Tendered,
+		Change: a.Change, Card: a.Card, Key: req.Key, At: c.At}
+	o.Tenders = append(o.Tenders, t)
+	return t, nil
+}
+
+// Payment is one of the ways a customer pays, in the order they hand them over
+type Payment struct {
+	Provider string // cash or card
+	payment.Request
+}
+
+// PayInFull takes every payment in turn, and it's all or nothing: if one fails or they don't add up to the
+// order, whatever was already taken is given back. providers finds a provider by its name, like for Refund
+func (o *Order) PayInFull(ctx context.Context, payments []Payment, providers func(name string) (payment.PaymentProvider, error)) error {
+	var failed error
+	for i, pay := range payments {
+		p, err := providers(pay.Provider)
+		if err != nil {
+			failed = err
+			break
+		}
+		if pay.Key != "" {
+			pay.Key = fmt.Sprintf("%v-%v", pay.Key, i+1) // One key for the lot, a retry of the lot takes the same payments
+		}
+		if _, failed = o.Pay(ctx, p, pay.Request); failed != nil {
+			break
+		}
+	}
+	if failed == nil && o.Due() > 0 {
+		failed = fmt.Errorf("%v is still due: %w", o.Due(), ErrUnderpaid)
+	}
+	if failed == nil || len(o.Tenders) == 0 {
+		return failed
+	}
+	if err := o.Refund(ctx, o.Paid()-o.Refunded(), o.ID+"-undo", providers); err != nil {
+		return errors.Join(failed, fmt.Errorf("giving back what was already paid: %w", err))
+	}
+	return fmt.Errorf("%w, what was already paid has been given back", failed)
+}
+
+// Refund gives amount back, from the newest tenders first so a card top-up is undone before the cash under it.
+// providers finds each tender's provider by its name. If one tender's refund fails, the ones before it still
+// stand and are kept on the order. Trying again with the same key picks up where it stopped, and once it's all
+// gone through does nothing, so a retried refund is never paid twice
+func (o *Order) Refund(ctx context.Context, amount float64, key string, providers func(name string) (payment.PaymentProvider, error)) error {
+	if key == "" {
+		return errors.New("a refund needs an idempotency key")
+	}
+	amount = o.round(amount)
+	if amount <= 0 {
+		return payment.ErrBadAmount
+	}
+	for _, r := range o.Refunds {
+		if r.Key == key {
+			amount = o.round(amount - r.Amount)
+		}
+	}
+	if amount <= 0 {
+		return nil
+	}
+	if left := o.round(o.Paid() - o.Refunded()); amount > left {
+		return fmt.Errorf("refunding %v of %v: %w", amount, left, payment.ErrOverRefund)
+	}
+	for i := len(o.Tenders) - 1; i >= 0 && amount > 0; i-- {
+		t := &o.Tenders[i]
+		part := min(amount, o.round(t.Amount-t.Refunded))
+		if part <= 0 {
+			continue
+		}
+		p, err := providers(t.Provider)
+		if err != nil {
+			return err
+		}
+		r, err := p.Refund(ctx, t.CaptureID, part, fmt.Sprintf("%v/%v", key, t.CaptureID))
+		if err != nil {
+			return fmt.Errorf("refunding %v to %v: %w", part, t.Provider, err)
+		}
+		t.Refunded = o.round(t.Refunded + part)
+		o.Refunds = append(o.Refunds, Refund{Provider: t.Provider, CaptureID: t.CaptureID, ID: r.ID, Amount: part, Key: key, At: r.At})
+		amount = o.round(amount - part)
+	}
+	return nil
+}
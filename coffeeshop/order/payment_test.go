@@ -0,0 +1,96 @@
+package order
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	menu "demo/coffeeshop/menu"
+	"demo/coffeeshop/money"
+	"demo/coffeeshop/payment"
+)
+
+func TestPay(t *testing.T) {
+	ctx := context.Background()
+	till, _ := payment.OpenTill("")
+	cash := till.Cash("", money.Currency{})
+	cash.Open(0, "")
+	providers := func(name string) (payment.PaymentProvider, error) { return till.Provider(name, "", money.Currency{}) }
+	o := Order{Lines: []Line{{Quote: menu.Quote{Price: 3.5}, Qty: 2}}}
+
+	if _, err := o.Pay(ctx, cash, payment.Request{Amount: 8}); !errors.Is(err, ErrOverpaid) {
+		t.Errorf("Paying 8 of 7: got %v", err)
+	}
+	if _, err := o.Pay(ctx, till.Card(), payment.Request{Card: payment.CardDeclined}); !errors.Is(err, payment.ErrDeclined) || len(o.Tenders) != 0 {
+		t.Errorf("Declined: got %v, %+v", err, o.Tenders)
+	}
+
+	// Split: 2 in cash, the rest on a card, and a retry of the card payment doesn't take it twice
+	if _, err := o.Pay(ctx, cash, payment.Request{Amount: 2, Tendered: 2}); err != nil {
+		t.Fatal(err)
+	}
+	card, err := o.Pay(ctx, till.Card(), payment.Request{Card: payment.CardApproved, Key: "tap"})
+	if err != nil || card.Amount != 5 {
+		t.Fatalf("Got %+v, %v", card, err)
+	}
+	if again, err := o.Pay(ctx, till.Card(), payment.Request{Card: payment.CardApproved, Key: "tap"}); err != nil || again.CaptureID != card.CaptureID {
+		t.Errorf("Retry: got %+v, %v", again, err)
+	}
+	if o.Paid() != 7 || o.Due() != 0 || len(o.Tenders) != 2 {
+		t.Errorf("Paid %v, due %v, tenders %+v", o.Paid(), o.Due(), o.Tenders)
+	}
+
+	// 6 back comes off the card first, then the cash. Asking again with the same key gives nothing more
+	for range 2 {
+		if err := o.Refund(ctx, 6, "r1", providers); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if o.Refunded() != 6 || o.Tenders[1].Refunded != 5 || o.Tenders[0].Refunded != 1 || cash.Drawer() != 1 {
+		t.Errorf("Refunded %v, tenders %+v, drawer %v", o.Refunded(), o.Tenders, cash.Drawer())
+	}
+	if err := o.Refund(ctx, 2, "r2", providers); !errors.Is(err, payment.ErrOverRefund) {
+		t.Errorf("Refunding 2 of 1: got %v", err)
+	}
+}
+
+func TestDiscount(t *testing.T) {
+	till, _ := payment.OpenTill("")
+	o := Order{Lines: []Line{{Quote: menu.Quote{Price: 3.5}, Qty: 2}}}
+	if err := o.SetDiscount(8); !errors.Is(err, ErrBadDiscount) {
+		t.Errorf("8 off 7: got %v", err)
+	}
+	if err := o.SetDiscount(1.5); err != nil {
+		t.Fatal(err)
+	}
+	if o.Subtotal() != 7 || o.Total() != 5.5 || o.Due() != 5.5 {
+		t.Errorf("Subtotal %v, total %v, due %v", o.Subtotal(), o.Total(), o.Due())
+	}
+	if _, err := o.Pay(context.Background(), till.Card(), payment.Request{Card: payment.CardApproved}); err != nil || o.Paid() != 5.5 {
+		t.Fatalf("Paid %v, %v", o.Paid(), err)
+	}
+	if err := o.SetDiscount(1); err == nil {
+		t.Error("Expected an error for a discount once it's paid")
+	}
+}
+
+func TestLedgerKeepsNewest(t *testing.T) {
+	var buf bytes.Buffer
+	SetLedger(&buf)
+	defer SetLedger(io.Discard)
+	a, b := Order{ID: "A"}, Order{ID: "B"}
+	record(a)
+	record(b)
+	a.Refunds = []Refund{{Amount: 1}}
+	Record(a)
+
+	orders, err := ReadLedger(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 2 || orders[0].ID != "A" || len(orders[0].Refunds) != 1 {
+		t.Errorf("Got %+v", orders)
+	}
+}
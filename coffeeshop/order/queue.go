@@ -0,0 +1,61 @@
+package order
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"demo/coffeeshop/metrics"
+)
+
+// MARK: Queue
+
+// queued is the barista's queue: orders placed and not made yet, by ID. It's only in memory, so it's the
+// server's queue. An order from the command line is made as soon as its ticket is printed
+var (
+	queueMu sync.Mutex
+	queued  = map[string]Order{}
+)
+
+var (
+	queueDepth = metrics.NewGauge("coffeeshop_queue_depth", "Orders placed and waiting to be made, by store.", "store")
+	prepTime   = metrics.NewHistogram("coffeeshop_prep_seconds", "Time from an order being placed to it being ready, by store.",
+		[]float64{30, 60, 120, 180, 300, 450, 600, 900, 1200}, "store")
+)
+
+// ErrNotQueued is an order that isn't waiting to be made, it's already ready or was never placed here
+var ErrNotQueued = errors.New("not waiting to be made")
+
+// enqueue puts a placed order at the back of the queue. Placing the same order again doesn't queue it twice
+func enqueue(o Order) {
+	queueMu.Lock()
+	defer queueMu.Unlock()
+	if _, ok := queued[o.ID]; ok {
+		return
+	}
+	queued[o.ID] = o
+	queueDepth.Inc(o.Store)
+}
+
+// Ready takes an order off the queue once it's been made, and gives back how long it took from being placed
+func Ready(id string) (Order, time.Duration, error) {
+	queueMu.Lock()
+	defer queueMu.Unlock()
+	o, ok := queued[id]
+	if !ok {
+		return Order{}, 0, fmt.Errorf("order %v: %w", id, ErrNotQueued)
+	}
+	delete(queued, id)
+	took := time.Since(o.Placed)
+	queueDepth.Dec(o.Store)
+	prepTime.Observe(took.Seconds(), o.Store)
+	return o, took, nil
+}
+
+// Queued is how many orders are waiting to be made
+func Queued() int {
+	queueMu.Lock()
+	defer queueMu.Unlock()
+	return len(queued)
+}
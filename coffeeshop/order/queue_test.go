@@ -0,0 +1,27 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	menu "demo/coffeeshop/menu"
+)
+
+func TestQueue(t *testing.T) {
+	o := Order{Store: "queue-test", Placed: time.Now().Add(-90 * time.Second), Lines: []Line{{Quote: menu.Quote{Price: 3}, Qty: 1}}}
+	o.Place(context.Background())
+	o.Place(context.Background()) // Placing it again doesn't queue it twice
+	if Queued() < 1 {
+		t.Fatalf("Queued: got %v", Queued())
+	}
+	before := Queued()
+	_, took, err := Ready(o.ID)
+	if err != nil || took < 90*time.Second || Queued() != before-1 {
+		t.Errorf("Ready: took %v, %v, %v left", took, err, Queued())
+	}
+	if _, _, err := Ready(o.ID); !errors.Is(err, ErrNotQueued) {
+		t.Errorf("Ready twice: got %v", err)
+	}
+}
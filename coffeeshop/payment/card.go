@@ -0,0 +1,114 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MARK: Cards
+
+// Test card numbers. Any other number that passes the check digit is approved
+const (
+	CardApproved     = "4242424242424242"
+	CardDeclined     = "4000000000000002"
+	CardInsufficient = "4000000000009995" // Declined for insufficient funds
+	CardTimeout      = "4000000000000119" // The processor never answers
+)
+
+// Card is a pretend card processor. It answers the same way every time for the same card, so tests and demos
+// can count on it, and the test numbers act out what a real one does when it goes wrong
+type Card struct {
+	b *book
+
+	// Timeout is how long to wait for an answer that isn't coming, for CardTimeout. The context can give up sooner
+	Timeout time.Duration
+}
+
+func NewCard() *Card {
+	return &Card{b: newBook("card"), Timeout: 5 * time.Second}
+}
+
+func (c *Card) Name() string { return "card" }
+
+// Authorize checks the card and holds the amount on it
+func (c *Card) Authorize(ctx context.Context, req Request) (Authorization, error) {
+	number := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return -1
+		}
+		return r
+	}, req.Card)
+	if !luhn(number) {
+		return Authorization{}, ErrInvalidCard
+	}
+	if number == CardTimeout {
+		// Waiting happens outside the lock, the till can take other payments meanwhile. Nothing's remembered for
+		// the key, so trying again is fine
+		select {
+		case <-time.After(c.Timeout):
+		case <-ctx.Done():
+		}
+		return Authorization{}, ErrTimeout
+	}
+
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+	var a Authorization
+	last4 := number[len(number)-4:]
+	call := fmt.Sprintf("authorize %v %v %v", req.Amount, req.Currency, last4)
+	err := once(c.b, req.Key, call, &a, func() (Authorization, error) {
+		switch {
+		case req.Amount <= 0:
+			return Authorization{}, ErrBadAmount
+		case number == CardDeclined:
+			return Authorization{}, ErrDeclined
+		case number == CardInsufficient:
+			return Authorization{}, fmt.Errorf("insufficient funds: %w", ErrDeclined)
+		}
+		a := Authorization{ID: c.b.newID("auth"), Provider: c.Name(), Amount: req.Amount, Currency: req.Currency, Card: "•••• " + last4}
+		c.b.Auths[a.ID] = &authRecord{Authorization: a}
+		return a, nil
+	})
+	return a, err
+}
+
+func (c *Card) Capture(ctx context.Context, authID string, amount float64, key string) (Transaction, error) {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+	return c.b.capture(authID, amount, key)
+}
+
+func (c *Card) Void(ctx context.Context, authID, key string) error {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+	return c.b.void(authID, key)
+}
+
+func (c *Card) Refund(ctx context.Context, captureID string, amount float64, key string) (Transaction, error) {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+	return c.b.refund(captureID, amount, key, nil)
+}
+
+// luhn checks the last digit of a card number against the rest
+func luhn(number string) bool {
+	if len(number) < 12 || len(number) > 19 {
+		return false
+	}
+	sum := 0
+	for i := range len(number) {
+		d := int(number[len(number)-1-i] - '0')
+		if d < 0 || d > 9 {
+			return false
+		}
+		if i%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
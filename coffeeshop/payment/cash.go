@@ -0,0 +1,170 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"demo/coffeeshop/money"
+)
+
+// MARK: Cash
+
+// Cash takes notes and coins over the counter. It works out the change and keeps a running count of what
+// should be in the drawer. The drawer has to be open for cash to go in or out, see drawer.go
+type Cash struct {
+	cur     money.Currency
+	b       *book
+	store   string
+	session *Session  // nil while the drawer is closed
+	history []Session // Closed sessions, oldest first
+}
+
+// Move is money going in or out of the drawer
+type Move struct {
+	At     time.Time `json:"at"`
+	Kind   string    `json:"kind"`             // sale, refund, pay-in or pay-out
+	Amount float64   `json:"amount"`           // Out of the drawer is negative
+	Ref    string    `json:"ref,omitempty"`    // The capture a sale or refund was for
+	Reason string    `json:"reason,omitempty"` // Pay-ins and pay-outs, like "milk from the shop next door"
+	By     string    `json:"by,omitempty"`
+}
+
+// NewCash is a drawer in cur, already open with float in it. A till's drawers start closed instead
+func NewCash(cur money.Currency, float float64) *Cash {
+	c := &Cash{cur: cur, b: newBook("cash")}
+	c.open(float, "")
+	return c
+}
+
+func (c *Cash) Name() string { return "cash" }
+
+// Authorize checks enough was handed over and that the drawer can give the change. Nothing goes in the drawer
+// until it's captured, a void hands the customer's money straight back
+func (c *Cash) Authorize(ctx context.Context, req Request) (Authorization, error) {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+	var a Authorization
+	call := fmt.Sprintf("authorize %v %v %v", req.Amount, req.Currency, req.Tendered)
+	err := once(c.b, req.Key, call, &a, func() (Authorization, error) {
+		if c.session == nil {
+			return Authorization{}, ErrDrawerClosed
+		}
+		amount, tendered := c.cur.Round(req.Amount), c.cur.Round(req.Tendered)
+		if amount <= 0 {
+			return Authorization{}, ErrBadAmount
+		}
+		if tendered == 0 {
+			tendered = amount // Exact money
+		}
+		if tendered < amount {
+			return Authorization{}, fmt.Errorf("%v for %v: %w", c.amount(tendered), c.amount(amount), ErrNotEnough)
+		}
+		change := c.cur.Round(tendered - amount)
+		if drawer := c.balance(); change > drawer {
+			return Authorization{}, fmt.Errorf("change of %v with %v in the drawer: %w", c.amount(change), c.amount(drawer), ErrNoChange)
+		}
+		a := Authorization{ID: c.b.newID("auth"), Provider: c.Name(), Amount: amount, Currency: req.Currency, Tendered: tendered, Change: change}
+		c.b.Auths[a.ID] = &authRecord{Authorization: a}
+		return a, nil
+	})
+	return a, err
+}
+
+// Capture puts the money in the drawer, what was handed over less the change
+func (c *Cash) Capture(ctx context.Context, authID string, amount float64, key string) (Transaction, error) {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+	fresh := c.b.Keys[key].Call == ""
+	if fresh && c.session == nil {
+		return Transaction{}, ErrDrawerClosed // A retry can still find out what happened
+	}
+	t, err := c.b.capture(authID, amount, key)
+	if err == nil && fresh {
+		c.move(Move{Kind: "sale", Amount: t.Amount, Ref: t.ID})
+	}
+	return t, err
+}
+
+func (c *Cash) Void(ctx context.Context, authID, key string) error {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+	return c.b.void(authID, key)
+}
+
+// Refund pays back out of the drawer, as long as it's open and there's enough in it
+func (c *Cash) Refund(ctx context.Context, captureID string, amount float64, key string) (Transaction, error) {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+	amount = c.cur.Round(amount)
+	return c.b.refund(captureID, amount, key, func(*Transaction) error {
+		if c.session == nil {
+			return ErrDrawerClosed
+		}
+		if drawer := c.balance(); amount > drawer {
+			return fmt.Errorf("refunding %v with %v in the drawer: %w", c.amount(amount), c.amount(drawer), ErrNoChange)
+		}
+		c.move(Move{Kind: "refund", Amount: -amount, Ref: captureID})
+		return nil
+	})
+}
+
+// Drawer is what should be in the drawer now, nothing while it's closed
+func (c *Cash) Drawer() float64 {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+	return c.balance()
+}
+
+// Moves is everything that's gone in or out of the drawer since it was opened, oldest first
+func (c *Cash) Moves() []Move {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+	if c.session == nil {
+		return nil
+	}
+	return append([]Move(nil), c.session.Moves...)
+}
+
+// balance is Drawer with c.b.mu held
+func (c *Cash) balance() float64 {
+	if c.session == nil {
+		return 0
+	}
+	return c.session.Expected()
+}
+
+// move puts a move in the open session. c.b.mu has to be held
+func (c *Cash) move(m Move) {
+	m.At = time.Now()
+	c.session.Moves = append(c.session.Moves, m)
+}
+
+func (c *Cash) amount(v float64) string {
+	return money.Format{Currency: c.cur}.Amount(v)
+}
+
+// MARK: Change
+
+// Piece is some of one note or coin
+type Piece struct {
+	Value float64 `json:"value"`
+	Count int     `json:"count"`
+}
+
+// Breakdown is the fewest notes and coins that make up amount, biggest first. Anything smaller than the smallest
+// coin is left off
+func Breakdown(cur money.Currency, amount float64) []Piece {
+	var out []Piece
+	unit := cur.Unit()
+	left := math.Round(amount / unit) // Whole units, so the float noise doesn't lose a penny
+	for _, d := range cur.Denominations() {
+		units := math.Round(d / unit)
+		if n := int(left / units); n > 0 {
+			out = append(out, Piece{Value: d, Count: n})
+			left -= float64(n) * units
+		}
+	}
+	return out
+}
@@ -0,0 +1,190 @@
+package payment
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"demo/coffeeshop/money"
+)
+
+// MARK: Drawer sessions
+
+// Session is a drawer from when it's opened with a float to when it's counted at close. Everything that went in
+// or out in between says what should be there, and the count says what is
+type Session struct {
+	ID       string     `json:"id"`
+	Store    string     `json:"store,omitempty"`
+	Currency string     `json:"currency,omitempty"`
+	Float    float64    `json:"float"` // What it was opened with
+	OpenedAt time.Time  `json:"openedAt"`
+	OpenedBy string     `json:"openedBy,omitempty"`
+	Moves    []Move     `json:"moves"`
+	ClosedAt *time.Time `json:"closedAt,omitempty"` // nil while it's open
+	ClosedBy string     `json:"closedBy,omitempty"`
+	Counted  []Piece    `json:"counted,omitempty"` // The notes and coins found at close, biggest first
+}
+
+var (
+	ErrDrawerClosed = errors.New("the drawer isn't open")
+	ErrDrawerOpen   = errors.New("the drawer is already open")
+	ErrNoReason     = errors.New("money in or out of the drawer needs a reason, for whoever counts it")
+	ErrNotCash      = errors.New("that isn't a note or coin")
+)
+
+// Total adds up the moves of one kind: sale, refund, pay-in or pay-out. Money out comes back negative
+func (s Session) Total(kind string) float64 {
+	total := 0.0
+	for _, m := range s.Moves {
+		if m.Kind == kind {
+			total += m.Amount
+		}
+	}
+	return s.round(total)
+}
+
+// Expected is what should be in the drawer: the float and everything since
+func (s Session) Expected() float64 {
+	total := s.Float
+	for _, m := range s.Moves {
+		total += m.Amount
+	}
+	return s.round(total)
+}
+
+// CountedTotal is what was found at close
+func (s Session) CountedTotal() float64 {
+	total := 0.0
+	for _, p := range s.Counted {
+		total += p.Value * float64(p.Count)
+	}
+	return s.round(total)
+}
+
+// Variance is the count less what was expected. Over is more than there should be, short is less
+func (s Session) Variance() float64 {
+	return s.round(s.CountedTotal() - s.Expected())
+}
+
+func (s Session) round(v float64) float64 {
+	cur, _ := money.LookupCurrency(s.Currency) // "" is the zero Currency
+	return cur.Round(v)
+}
+
+// open starts a session. c.b.mu has to be held, or c not shared yet
+func (c *Cash) open(float float64, by string) Session {
+	c.session = &Session{ID: c.b.newID("session"), Store: c.store, Currency: c.cur.Code, Float: c.cur.Round(float),
+		OpenedAt: time.Now(), OpenedBy: by, Moves: []Move{}}
+	return *c.session
+}
+
+// Open starts the day (or the shift) with float in the drawer
+func (c *Cash) Open(float float64, by string) (Session, error) {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+	if c.session != nil {
+		return Session{}, fmt.Errorf("%w, since %v", ErrDrawerOpen, c.session.OpenedAt.Format(time.Kitchen))
+	}
+	if float < 0 || math.IsNaN(float) || math.IsInf(float, 0) {
+		return Session{}, ErrBadAmount
+	}
+	return c.open(float, by), nil
+}
+
+// PayIn is cash put in that isn't a sale, like more change from the bank
+func (c *Cash) PayIn(amount float64, reason, by string) error {
+	return c.pay("pay-in", amount, reason, by)
+}
+
+// PayOut is cash taken out that isn't a refund, like paying for milk from the shop next door
+func (c *Cash) PayOut(amount float64, reason, by string) error {
+	return c.pay("pay-out", amount, reason, by)
+}
+
+// pay moves amount in or out of the drawer. It's always more than nothing, the kind says which way it goes, so
+// a pay-in of -20 can't take cash out behind the balance check
+func (c *Cash) pay(kind string, amount float64, reason, by string) error {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+	reason = strings.TrimSpace(reason)
+	switch {
+	case c.session == nil:
+		return ErrDrawerClosed
+	case amount <= 0 || math.IsNaN(amount) || math.IsInf(amount, 0):
+		return fmt.Errorf("%v of %v: %w", kind, amount, ErrBadAmount)
+	case reason == "":
+		return fmt.Errorf("%v: %w", kind, ErrNoReason)
+	case kind == "pay-out" && amount > c.balance():
+		return fmt.Errorf("paying out %v with %v in the drawer: %w", c.amount(amount), c.amount(c.balance()), ErrNoChange)
+	}
+	if kind == "pay-out" {
+		amount = -amount
+	}
+	c.move(Move{Kind: kind, Amount: c.cur.Round(amount), Reason: reason, By: by})
+	return nil
+}
+
+// Close ends the session with what was counted in the drawer. The count goes to the bank, so the drawer is
+// empty until it's opened again
+func (c *Cash) Close(counted []Piece, by string) (Session, error) {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+	if c.session == nil {
+		return Session{}, ErrDrawerClosed
+	}
+	denominations := c.cur.Denominations()
+	var pieces []Piece
+	for _, p := range counted {
+		if !slices.Contains(denominations, p.Value) {
+			return Session{}, fmt.Errorf("%v: %w, expected one of %v", c.amount(p.Value), ErrNotCash, c.denominations())
+		}
+		if p.Count < 0 {
+			return Session{}, fmt.Errorf("%v of %v: %w", p.Count, c.amount(p.Value), ErrBadAmount)
+		}
+		if i := slices.IndexFunc(pieces, func(q Piece) bool { return q.Value == p.Value }); i >= 0 {
+			pieces[i].Count += p.Count // Counted in two goes, a bag of coins and the tray say
+		} else if p.Count > 0 {
+			pieces = append(pieces, p)
+		}
+	}
+	slices.SortFunc(pieces, func(a, b Piece) int { return cmp.Compare(b.Value, a.Value) })
+
+	now := time.Now()
+	s := *c.session
+	s.ClosedAt, s.ClosedBy, s.Counted = &now, by, pieces
+	c.history = append(c.history, s)
+	c.session = nil
+	return s, nil
+}
+
+// Session is the open session, false when the drawer is closed
+func (c *Cash) Session() (Session, bool) {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+	if c.session == nil {
+		return Session{}, false
+	}
+	s := *c.session
+	s.Moves = slices.Clone(s.Moves)
+	return s, true
+}
+
+// History is every closed session, oldest first
+func (c *Cash) History() []Session {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+	return slices.Clone(c.history)
+}
+
+// denominations lists the notes and coins for an error message
+func (c *Cash) denominations() string {
+	var names []string
+	for _, d := range c.cur.Denominations() {
+		names = append(names, money.Format{Currency: c.cur}.Number(d))
+	}
+	return strings.Join(names, ", ")
+}
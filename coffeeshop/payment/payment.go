@@ -0,0 +1,223 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+)
+
+// MARK: Payments
+
+// PaymentProvider is something that takes money for an order: the cash drawer, a card terminal. Taking a payment
+// is two steps so the money can be held while the order is checked, and let go of if it doesn't go through:
+//
+//   - Authorize holds the amount (checks the card, or that enough cash was handed over)
+//   - Capture takes what was held, Void lets it go instead
+//   - Refund gives some or all of a capture back
+//
+// Every call takes an idempotency key. Calling again with the same key (a retry after a timeout, a double tap on
+// the till) gets the first call's answer instead of doing it twice. Reusing a key for a different call is an
+// error, and so is capturing an authorization twice under different keys
+type PaymentProvider interface {
+	Name() string
+	Authorize(ctx context.Context, req Request) (Authorization, error)
+	Capture(ctx context.Context, authID string, amount float64, key string) (Transaction, error)
+	Void(ctx context.Context, authID, key string) error
+	Refund(ctx context.Context, captureID string, amount float64, key string) (Transaction, error)
+}
+
+// Request is what an authorization is asked for
+type Request struct {
+	Amount   float64 // What's being paid towards the order
+	Currency string  // "" when the shop hasn't said
+	Tendered float64 // Cash handed over, change is given from it
+	Card     string  // The card number, for card providers. Only the last four are kept
+	Key      string  // Idempotency key, required
+}
+
+// Authorization is money held, waiting to be captured or voided
+type Authorization struct {
+	ID       string  `json:"id"`
+	Provider string  `json:"provider"`
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency,omitempty"`
+	Tendered float64 `json:"tendered,omitempty"` // Cash only
+	Change   float64 `json:"change,omitempty"`   // Cash only, what goes back to the customer
+	Card     string  `json:"card,omitempty"`     // Cards only, like •••• 4242
+}
+
+// Transaction is money that actually moved, a capture or a refund
+type Transaction struct {
+	ID       string    `json:"id"`
+	AuthID   string    `json:"authId"`
+	Kind     string    `json:"kind"` // capture or refund
+	Amount   float64   `json:"amount"`
+	Refunded float64   `json:"refunded,omitempty"` // Captures only, what's been given back so far
+	At       time.Time `json:"at"`
+}
+
+// These are the ways a payment goes wrong. Providers wrap one of them, so callers can use errors.Is
+var (
+	ErrDeclined        = errors.New("the card was declined")
+	ErrTimeout         = errors.New("the card processor didn't answer in time")
+	ErrInvalidCard     = errors.New("that isn't a card number")
+	ErrNotEnough       = errors.New("not enough was handed over")
+	ErrNoChange        = errors.New("not enough cash in the drawer")
+	ErrAlreadyCaptured = errors.New("already captured")
+	ErrVoided          = errors.New("already voided")
+	ErrOverRefund      = errors.New("more than is left to refund")
+	ErrNotFound        = errors.New("no such payment")
+	ErrKeyReused       = errors.New("idempotency key already used for something else")
+	ErrUnknownMethod   = errors.New("unknown payment method")
+	ErrBadAmount       = errors.New("amounts have to be more than nothing")
+)
+
+// MARK: Records
+
+// book is what a provider remembers: every authorization and transaction, and the answer to every idempotency
+// key. Providers embed one and save it with their own state
+type book struct {
+	mu       sync.Mutex
+	provider string
+	Next     int                     `json:"next"` // For the next ID, so IDs are the same every run
+	Auths    map[string]*authRecord  `json:"auths"`
+	Captures map[string]*Transaction `json:"captures"`
+	Keys     map[string]keyRecord    `json:"keys"`
+}
+
+type authRecord struct {
+	Authorization
+	CaptureID string `json:"captureId,omitempty"`
+	Voided    bool   `json:"voided,omitempty"`
+}
+
+// keyRecord is an idempotency key's first call and its answer. Only calls that worked are kept, one that failed
+// can be tried again with the same key
+type keyRecord struct {
+	Call   string          `json:"call"` // The operation and its arguments, a different call with the key is refused
+	Result json.RawMessage `json:"result"`
+}
+
+func newBook(provider string) *book {
+	return &book{provider: provider, Auths: map[string]*authRecord{}, Captures: map[string]*Transaction{},
+		Keys: map[string]keyRecord{}}
+}
+
+// newID is the next ID, like cash_auth_0001. Counting rather than random keeps tests and demos repeatable
+func (b *book) newID(kind string) string {
+	b.Next++
+	return fmt.Sprintf("%v_%v_%04d", b.provider, kind, b.Next)
+}
+
+// once runs do for a new key and remembers what it returned in result. For a key that's been used it fills in
+// result from the first call instead, as long as it's the same call. b.mu has to be held
+func once[T any](b *book, key, call string, result *T, do func() (T, error)) error {
+	if key == "" {
+		return errors.New("an idempotency key is needed")
+	}
+	if k, ok := b.Keys[key]; ok {
+		if k.Call != call {
+			return fmt.Errorf("%v: %w", key, ErrKeyReused)
+		}
+		return json.Unmarshal(k.Result, result)
+	}
+	r, err := do()
+	if err != nil {
+		return err
+	}
+	raw, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	b.Keys[key] = keyRecord{Call: call, Result: raw}
+	*result = r
+	return nil
+}
+
+// auth finds an authorization that can still be captured or voided. b.mu has to be held
+func (b *book) auth(id string) (*authRecord, error) {
+	a, ok := b.Auths[id]
+	switch {
+	case !ok:
+		return nil, fmt.Errorf("authorization %v: %w", id, ErrNotFound)
+	case a.Voided:
+		return nil, fmt.Errorf("authorization %v: %w", id, ErrVoided)
+	case a.CaptureID != "":
+		return nil, fmt.Errorf("authorization %v: %w as %v", id, ErrAlreadyCaptured, a.CaptureID)
+	}
+	return a, nil
+}
+
+// capture takes up to the authorized amount. b.mu has to be held
+func (b *book) capture(authID string, amount float64, key string) (Transaction, error) {
+	var t Transaction
+	err := once(b, key, fmt.Sprintf("capture %v %v", authID, amount), &t, func() (Transaction, error) {
+		a, err := b.auth(authID)
+		if err != nil {
+			return Transaction{}, err
+		}
+		if amount <= 0 || amount > a.Amount+1e-9 {
+			return Transaction{}, fmt.Errorf("capturing %v of %v: %w", amount, a.Amount, ErrBadAmount)
+		}
+		t := Transaction{ID: b.newID("cap"), AuthID: authID, Kind: "capture", Amount: amount, At: time.Now()}
+		a.CaptureID = t.ID
+		b.Captures[t.ID] = &t
+		return t, nil
+	})
+	if err == nil {
+		t = *b.Captures[t.ID] // As it is now, a retry should see what's been refunded since
+	}
+	return t, err
+}
+
+// void lets an authorization go. b.mu has to be held
+func (b *book) void(authID, key string) error {
+	var done bool
+	return once(b, key, "void "+authID, &done, func() (bool, error) {
+		a, err := b.auth(authID)
+		if err != nil {
+			return false, err
+		}
+		a.Voided = true
+		return true, nil
+	})
+}
+
+// refund checks a refund against what's left of a capture, and do gives the money back. b.mu has to be held
+func (b *book) refund(captureID string, amount float64, key string, do func(c *Transaction) error) (Transaction, error) {
+	var t Transaction
+	err := once(b, key, fmt.Sprintf("refund %v %v", captureID, amount), &t, func() (Transaction, error) {
+		c, ok := b.Captures[captureID]
+		if !ok {
+			return Transaction{}, fmt.Errorf("capture %v: %w", captureID, ErrNotFound)
+		}
+		if amount <= 0 {
+			return Transaction{}, ErrBadAmount
+		}
+		if left := c.Amount - c.Refunded; amount > left+1e-9 {
+			return Transaction{}, fmt.Errorf("refunding %v with %v left: %w", amount, left, ErrOverRefund)
+		}
+		if do != nil {
+			if err := do(c); err != nil {
+				return Transaction{}, err
+			}
+		}
+		c.Refunded += amount
+		return Transaction{ID: b.newID("ref"), AuthID: c.AuthID, Kind: "refund", Amount: amount, At: time.Now()}, nil
+	})
+	return t, err
+}
+
+// named is a book read from a file, with its provider and any maps the file didn't have
+func (b *book) named(provider string) *book {
+	fresh := newBook(provider)
+	fresh.Next = b.Next
+	maps.Copy(fresh.Auths, b.Auths)
+	maps.Copy(fresh.Captures, b.Captures)
+	maps.Copy(fresh.Keys, b.Keys)
+	return fresh
+}
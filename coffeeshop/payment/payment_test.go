@@ -0,0 +1,196 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"demo/coffeeshop/money"
+)
+
+func TestCash(t *testing.T) {
+	ctx := context.Background()
+	usd, _ := money.LookupCurrency("USD")
+	c := NewCash(usd, 10)
+
+	if _, err := c.Authorize(ctx, Request{Amount: 4.5, Tendered: 4, Key: "k1"}); !errors.Is(err, ErrNotEnough) {
+		t.Errorf("Short: got %v", err)
+	}
+	if _, err := c.Authorize(ctx, Request{Amount: 4.5, Tendered: 50, Key: "k1"}); !errors.Is(err, ErrNoChange) {
+		t.Errorf("Change from 50 with 10 in the drawer: got %v", err)
+	}
+	a, err := c.Authorize(ctx, Request{Amount: 4.5, Tendered: 10, Key: "k1"})
+	if err != nil || a.Change != 5.5 {
+		t.Fatalf("Got %+v, %v", a, err)
+	}
+
+	// Capturing twice with the same key is the one capture, with another it's refused
+	cap1, err := c.Capture(ctx, a.ID, a.Amount, "k2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, err := c.Capture(ctx, a.ID, a.Amount, "k2"); err != nil || again.ID != cap1.ID || !again.At.Equal(cap1.At) {
+		t.Errorf("Retried capture: got %+v, %v", again, err)
+	}
+	if _, err := c.Capture(ctx, a.ID, a.Amount, "k3"); !errors.Is(err, ErrAlreadyCaptured) {
+		t.Errorf("Second capture: got %v", err)
+	}
+	if _, err := c.Capture(ctx, a.ID, 1, "k2"); !errors.Is(err, ErrKeyReused) {
+		t.Errorf("Key for a different capture: got %v", err)
+	}
+	if c.Drawer() != 14.5 {
+		t.Errorf("Drawer: got %v", c.Drawer())
+	}
+
+	if _, err := c.Refund(ctx, cap1.ID, 2, "k4"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Refund(ctx, cap1.ID, 3, "k5"); !errors.Is(err, ErrOverRefund) {
+		t.Errorf("Refunding 5 of 4.50: got %v", err)
+	}
+	if c.Drawer() != 12.5 || len(c.Moves()) != 2 {
+		t.Errorf("Drawer: got %v, %+v", c.Drawer(), c.Moves())
+	}
+}
+
+func TestCard(t *testing.T) {
+	ctx := context.Background()
+	c := NewCard()
+	c.Timeout = time.Millisecond
+
+	for number, expect := range map[string]error{
+		"4242 4242 4242 4241": ErrInvalidCard,
+		CardDeclined:          ErrDeclined,
+		CardInsufficient:      ErrDeclined,
+		CardTimeout:           ErrTimeout,
+	} {
+		if _, err := c.Authorize(ctx, Request{Amount: 3, Card: number, Key: number}); !errors.Is(err, expect) {
+			t.Errorf("%v: got %v, expected %v", number, err, expect)
+		}
+	}
+
+	a, err := c.Authorize(ctx, Request{Amount: 3, Card: "4242-4242-4242-4242", Key: "a"})
+	if err != nil || a.Card != "•••• 4242" {
+		t.Fatalf("Got %+v, %v", a, err)
+	}
+	if err := c.Void(ctx, a.ID, "v"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Capture(ctx, a.ID, 3, "c"); !errors.Is(err, ErrVoided) {
+		t.Errorf("Capturing a void: got %v", err)
+	}
+}
+
+func TestBreakdown(t *testing.T) {
+	usd, _ := money.LookupCurrency("USD")
+	got := Breakdown(usd, 16.41)
+	expect := []Piece{{10, 1}, {5, 1}, {1, 1}, {0.25, 1}, {0.10, 1}, {0.05, 1}, {0.01, 1}}
+	if len(got) != len(expect) {
+		t.Fatalf("Got %v", got)
+	}
+	for i := range got {
+		if got[i] != expect[i] {
+			t.Errorf("Got %v, expected %v", got, expect)
+		}
+	}
+}
+
+func TestTill(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "payments.json")
+	till, err := OpenTill(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eur, _ := money.LookupCurrency("EUR")
+	cash := till.Cash("dock", eur)
+	if _, err := cash.Authorize(ctx, Request{Amount: 3.2, Key: "a"}); !errors.Is(err, ErrDrawerClosed) {
+		t.Errorf("Before opening: got %v", err)
+	}
+	cash.Open(0, "sam")
+	a, _ := cash.Authorize(ctx, Request{Amount: 3.2, Key: "a"})
+	capture, err := cash.Capture(ctx, a.ID, a.Amount, "c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := till.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Tomorrow, the refund finds the payment and the drawer still has it
+	till, err = OpenTill(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cash = till.Cash("dock", money.Currency{})
+	if cash.Drawer() != 3.2 || cash.cur.Code != "EUR" {
+		t.Errorf("Drawer: got %v %v", cash.Drawer(), cash.cur.Code)
+	}
+	if _, err := cash.Refund(ctx, capture.ID, 3.2, "r"); err != nil {
+		t.Error(err)
+	}
+	if b, _ := cash.Authorize(ctx, Request{Amount: 1, Key: "b"}); b.ID == a.ID {
+		t.Errorf("ID %v given out twice", b.ID)
+	}
+}
+
+func TestDrawerSession(t *testing.T) {
+	ctx := context.Background()
+	usd, _ := money.LookupCurrency("USD")
+	till, _ := OpenTill("")
+	c := till.Cash("high-st", usd)
+	if _, err := c.Open(50, "sam"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Open(50, "sam"); !errors.Is(err, ErrDrawerOpen) {
+		t.Errorf("Opening twice: got %v", err)
+	}
+
+	a, _ := c.Authorize(ctx, Request{Amount: 4.5, Tendered: 10, Key: "a"})
+	capture, _ := c.Capture(ctx, a.ID, a.Amount, "c")
+	c.Refund(ctx, capture.ID, 1, "r")
+	if err := c.PayOut(3, "milk", "sam"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.PayIn(10, "", "sam"); err == nil {
+		t.Error("Pay-in without a reason: expected an error")
+	}
+	if err := c.PayIn(-20, "coins from the bank", "sam"); !errors.Is(err, ErrBadAmount) {
+		t.Errorf("Negative pay-in: got %v", err)
+	}
+	if err := c.PayOut(-20, "milk", "sam"); !errors.Is(err, ErrBadAmount) {
+		t.Errorf("Negative pay-out: got %v", err)
+	}
+	if err := c.PayIn(math.NaN(), "coins from the bank", "sam"); !errors.Is(err, ErrBadAmount) {
+		t.Errorf("NaN pay-in: got %v", err)
+	}
+	for _, float := range []float64{-1, math.NaN(), math.Inf(1)} {
+		if _, err := till.Cash("dock", usd).Open(float, "sam"); !errors.Is(err, ErrBadAmount) {
+			t.Errorf("Opening with %v: got %v", float, err)
+		}
+	}
+	c.PayIn(10, "coins from the bank", "sam")
+	if c.Drawer() != 60.5 {
+		t.Errorf("Drawer: got %v, expected 60.5", c.Drawer())
+	}
+
+	if _, err := c.Close([]Piece{{3, 1}}, "jo"); err == nil {
+		t.Error("Counting $3 notes: expected an error")
+	}
+	s, err := c.Close([]Piece{{0.25, 1}, {20, 2}, {20, 1}}, "jo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Store != "high-st" || s.Total("sale") != 4.5 || s.Total("pay-out") != -3 || s.CountedTotal() != 60.25 || s.Variance() != -0.25 {
+		t.Errorf("Got %+v, counted %v, variance %v", s, s.CountedTotal(), s.Variance())
+	}
+	if len(s.Counted) != 2 || s.Counted[0] != (Piece{20, 3}) {
+		t.Errorf("Counted: got %v", s.Counted)
+	}
+	if _, err := c.Authorize(ctx, Request{Amount: 1, Key: "b"}); !errors.Is(err, ErrDrawerClosed) || len(c.History()) != 1 {
+		t.Errorf("After closing: got %v, %v sessions", err, len(c.History()))
+	}
+}
@@ -0,0 +1,123 @@
+package payment
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"demo/coffeeshop/money"
+)
+
+// MARK: Till
+
+// Till is what a shop takes payments with: one card processor, and a cash drawer for each store. It's kept in a
+// file so a refund tomorrow can find today's payment, and the drawer count carries over between runs
+type Till struct {
+	mu      sync.Mutex
+	path    string
+	card    *Card
+	drawers map[string]*Cash // By store ID, "" for the base menu's
+}
+
+// tillFile is how a till is saved
+type tillFile struct {
+	Card    *book                 `json:"card"`
+	Drawers map[string]drawerFile `json:"drawers"`
+}
+
+type drawerFile struct {
+	Currency string    `json:"currency,omitempty"`
+	Session  *Session  `json:"session,omitempty"` // The open one
+	History  []Session `json:"history,omitempty"`
+	Book     *book     `json:"book"`
+}
+
+// OpenTill reads the till saved at path. A file that isn't there yet is an empty till, and "" keeps nothing
+func OpenTill(path string) (*Till, error) {
+	t := &Till{path: path, card: NewCard(), drawers: map[string]*Cash{}}
+	if path == "" {
+		return t, nil
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f tillFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("reading %v: %w", path, err)
+	}
+	if f.Card != nil {
+		t.card.b = f.Card.named("card")
+	}
+	for store, d := range f.Drawers {
+		var cur money.Currency
+		if d.Currency != "" {
+			if cur, err = money.LookupCurrency(d.Currency); err != nil {
+				return nil, fmt.Errorf("reading %v: %w", path, err)
+			}
+		}
+		c := &Cash{cur: cur, b: newBook("cash"), store: store, session: d.Session, history: d.History}
+		if d.Book != nil {
+			c.b = d.Book.named("cash")
+		}
+		t.drawers[store] = c
+	}
+	return t, nil
+}
+
+// Card is the card processor, the same for every store
+func (t *Till) Card() *Card {
+	return t.card
+}
+
+// Cash is a store's drawer. A store's first one starts closed, in cur. After that it stays in the currency it
+// was first used in, the cash in it doesn't change when the menu's does
+func (t *Till) Cash(store string, cur money.Currency) *Cash {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	c, ok := t.drawers[store]
+	if !ok {
+		c = &Cash{cur: cur, b: newBook("cash"), store: store}
+		t.drawers[store] = c
+	}
+	return c
+}
+
+// Provider finds a provider by its name for a store, cash or card
+func (t *Till) Provider(name, store string, cur money.Currency) (PaymentProvider, error) {
+	switch name {
+	case "cash":
+		return t.Cash(store, cur), nil
+	case "card":
+		return t.card, nil
+	}
+	return nil, fmt.Errorf("%q: %w, expected cash or card", name, ErrUnknownMethod)
+}
+
+// Save writes the till back to its file
+func (t *Till) Save() error {
+	if t.path == "" {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	f := tillFile{Drawers: map[string]drawerFile{}}
+	t.card.b.mu.Lock()
+	defer t.card.b.mu.Unlock()
+	f.Card = t.card.b
+	for store, c := range t.drawers {
+		c.b.mu.Lock()
+		defer c.b.mu.Unlock()
+		f.Drawers[store] = drawerFile{Currency: c.cur.Code, Session: c.session, History: c.history, Book: c.b}
+	}
+	b, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(t.path, append(b, '\n'), 0o600) // Card digits are only ever the last four, but still
+}
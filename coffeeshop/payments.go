@@ -0,0 +1,143 @@
+package coffeeshop
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	menu "demo/coffeeshop/menu"
+	"demo/coffeeshop/money"
+	"demo/coffeeshop/order"
+	"demo/coffeeshop/payment"
+)
+
+// MARK: Payments
+
+// paymentsFile is where the till keeps the card payments and each store's drawer between runs
+func paymentsFile(fs *flag.FlagSet) *string {
+	return fs.String("payments", "payments.json", "file the till's payments and cash drawers are kept in")
+}
+
+// parseTender reads one --pay: cash, cash:TENDERED, card:NUMBER or card:NUMBER:AMOUNT
+func parseTender(s string) (order.Payment, error) {
+	method, rest, _ := strings.Cut(s, ":")
+	p := order.Payment{Provider: method}
+	switch method {
+	case "cash":
+		if rest != "" {
+			v, err := strconv.ParseFloat(rest, 64)
+			if err != nil || v <= 0 {
+				return p, fmt.Errorf("%q: cash:TENDERED needs an amount handed over", s)
+			}
+			p.Tendered = v
+		}
+	case "card":
+		number, amount, hasAmount := strings.Cut(rest, ":")
+		if number == "" {
+			return p, fmt.Errorf("%q: card:NUMBER needs the card number", s)
+		}
+		p.Card = number
+		if hasAmount {
+			v, err := strconv.ParseFloat(amount, 64)
+			if err != nil || v <= 0 {
+				return p, fmt.Errorf("%q: %q is not an amount", s, amount)
+			}
+			p.Amount = v
+		}
+	default:
+		return p, fmt.Errorf("%q: pay with cash or card", s)
+	}
+	return p, nil
+}
+
+// giveChange tells the till what to hand back for each cash payment
+func giveChange(o order.Order, f money.Format) {
+	for _, t := range o.Tenders {
+		if t.Change <= 0 {
+			continue
+		}
+		var pieces []string
+		for _, p := range payment.Breakdown(f.Currency, t.Change) {
+			pieces = append(pieces, fmt.Sprintf("%v x %v", p.Count, f.Amount(p.Value)))
+		}
+		fmt.Fprintf(os.Stderr, "Change: %v (%v)\n", f.Amount(t.Change), strings.Join(pieces, ", "))
+	}
+}
+
+// MARK: Refunds
+
+func runRefund(args []string) error {
+	fs := newFlags("refund", "[flags] ORDER-ID AMOUNT",
+		"Gives AMOUNT of an order back, to the way it was paid. The last payment is refunded first.")
+	file := menuFile(fs)
+	orders := ordersFile(fs)
+	payments := paymentsFile(fs)
+	trail := auditFile(fs)
+	key := fs.String("key", "", "idempotency key, running it again with the same key won't refund twice (default a new one)")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return errUsage
+	}
+	amount, err := strconv.ParseFloat(fs.Arg(1), 64)
+	if err != nil {
+		fmt.Fprintf(fs.Output(), "%q is not an amount\n", fs.Arg(1))
+		return errUsage
+	}
+	if err := menu.Load(*file); err != nil {
+		return err
+	}
+	placed, err := order.LoadLedger(*orders)
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(placed, func(o order.Order) bool { return strings.EqualFold(o.ID, fs.Arg(0)) })
+	if i < 0 {
+		return fmt.Errorf("no order %v in %v", fs.Arg(0), *orders)
+	}
+	o := placed[i]
+	if *key == "" {
+		*key = fmt.Sprintf("%v-refund-%v", o.ID, len(o.Refunds)+1)
+	}
+	f, err := menu.Money(o.Store)
+	if err != nil {
+		return err
+	}
+	till, err := payment.OpenTill(*payments)
+	if err != nil {
+		return err
+	}
+
+	before := len(o.Refunds)
+	err = o.Refund(context.Background(), amount, *key, func(name string) (payment.PaymentProvider, error) {
+		return till.Provider(name, o.Store, f.Currency)
+	})
+	if len(o.Refunds) == before {
+		return err // Nothing went back, so there's nothing to write down
+	}
+	// Some went back even if not all of it, and that has to be kept whatever happens next
+	err = errors.Join(err, till.Save(), ledgerRecord(*orders, o))
+	var done []string
+	for _, r := range o.Refunds[before:] {
+		done = append(done, fmt.Sprintf("%v to %v", f.Amount(r.Amount), r.Provider))
+		fmt.Printf("Refunded %v to %v (%v)\n", f.Amount(r.Amount), r.Provider, r.ID)
+	}
+	return errors.Join(err, recordChange(*trail, "order.refund", fmt.Sprintf("%v: %v", o.ID, strings.Join(done, ", "))))
+}
+
+// ledgerRecord writes an order to the ledger again after a change
+func ledgerRecord(path string, o order.Order) error {
+	closeLedger, err := order.OpenLedger(path)
+	if err != nil {
+		return err
+	}
+	defer closeLedger()
+	return order.Record(o)
+}
@@ -0,0 +1,90 @@
+package render
+
+import (
+	"html/template"
+	"io"
+	"strings"
+
+	menu "demo/coffeeshop/menu"
+	"demo/coffeeshop/money"
+	"demo/coffeeshop/order"
+)
+
+// HTML is a fragment for the shop's website, html/template takes care of escaping the names
+type HTML struct {
+	Money money.Format
+}
+
+func (HTML) ContentType() string { return "text/html; charset=utf-8" }
+
+var pages = template.Must(template.New("html").Funcs(template.FuncMap{
+	"price":      money.Format{}.Amount, // Swapped for the renderer's own in page
+	"calories":   calories,
+	"describe":   describe,
+	"tenderName": tenderName,
+	"sizeName":   sizeName,
+	"join":       strings.Join,
+}).Parse(`
+{{define "item"}}<section class="item">
+<h2{{with .Lang}} lang="{{.}}"{{end}}>{{.Name}}{{if .SoldOut}} <small>(sold out)</small>{{end}}</h2>
+{{with .Description}}<p class="description">{{.}}</p>
+{{end}}<table>
+<tr><th>Size</th><th>Price</th><th>kcal</th></tr>
+{{range .Sizes}}<tr><td>{{sizeName .}}</td><td>{{price .Price}}</td><td>{{calories .Nutrition}}</td></tr>
+{{end}}</table>
+<p>Contains: {{.Allergens}}</p>
+{{with .Diet.Names}}<p>Suitable for: {{join . ", "}}</p>
+{{end}}</section>
+{{end}}
+
+{{define "menu"}}<main class="menu">
+<h1>Menu</h1>
+{{range .}}{{template "item" .}}{{end}}</main>
+{{end}}
+
+{{define "order"}}<ul class="order">
+{{range .Lines}}<li>{{.Qty}} x {{describe .}}</li>
+{{end}}</ul>
+{{end}}
+
+{{define "receipt"}}<table class="receipt">
+{{range .Lines}}<tr><td>{{.Qty}}</td><td>{{describe .}}</td><td>{{price .Total}}</td></tr>
+{{end}}{{with .Discount}}<tr class="discount"><td></td><td>Discount</td><td>-{{price .}}</td></tr>
+{{end}}<tr><th></th><th>Total</th><th>{{price .Total}}</th></tr>
+{{with .Tax}}<tr class="tax"><td></td><td>incl. tax</td><td>{{price .}}</td></tr>
+{{end}}{{range .Tenders}}<tr class="tender"><td></td><td>{{tenderName .}}</td><td>{{price .Amount}}</td></tr>
+{{if .Change}}<tr class="change"><td></td><td>Change</td><td>{{price .Change}}</td></tr>
+{{end}}{{end}}{{with .Refunded}}<tr class="refund"><td></td><td>Refunded</td><td>{{price .}}</td></tr>
+{{end}}</table>
+<p>Contains: {{.Allergens}}</p>
+{{end}}
+`))
+
+// page runs one of the templates with prices in h's currency
+func (h HTML) page(w io.Writer, name string, v any) error {
+	t := pages
+	if h.Money != (money.Format{}) {
+		var err error
+		if t, err = pages.Clone(); err != nil {
+			return err
+		}
+		t.Funcs(template.FuncMap{"price": h.Money.Amount})
+	}
+	return t.ExecuteTemplate(w, name, v)
+}
+
+func (h HTML) Menu(w io.Writer, items []menu.Item) error {
+	return h.page(w, "menu", items)
+}
+
+func (h HTML) Item(w io.Writer, item menu.Item) error {
+	return h.page(w, "item", item)
+}
+
+func (h HTML) Order(w io.Writer, o order.Order) error {
+	return h.page(w, "order", o)
+}
+
+func (h HTML) Receipt(w io.Writer, o order.Order) error {
+	return h.page(w, "receipt", o)
+}
@@ -0,0 +1,55 @@
+package render
+
+import (
+	"encoding/json"
+	"io"
+
+	menu "demo/coffeeshop/menu"
+	"demo/coffeeshop/order"
+)
+
+// JSON is for the mobile app and anything else that wants the data rather than the look
+type JSON struct{}
+
+func (JSON) ContentType() string { return "application/json" }
+
+func (JSON) Menu(w io.Writer, items []menu.Item) error {
+	if items == nil {
+		items = []menu.Item{} // [] rather than null
+	}
+	return encode(w, struct {
+		Items []menu.Item `json:"items"`
+	}{items})
+}
+
+func (JSON) Item(w io.Writer, item menu.Item) error {
+	return encode(w, item)
+}
+
+func (JSON) Order(w io.Writer, o order.Order) error {
+	return encode(w, o)
+}
+
+// receipt is the order with the sums done
+type receipt struct {
+	order.Order
+	Total     float64         `json:"total"`
+	Due       float64         `json:"due"` // Left to pay once there are tenders
+	Currency  string          `json:"currency,omitempty"`
+	Allergens menu.Allergen   `json:"allergens"`
+	Nutrition *menu.Nutrition `json:"nutrition,omitempty"` // Left out unless we have facts for every line
+}
+
+func (JSON) Receipt(w io.Writer, o order.Order) error {
+	r := receipt{Order: o, Total: o.Total(), Due: o.Due(), Currency: o.Currency(), Allergens: o.Allergens()}
+	if n, complete := o.Nutrition(); complete {
+		r.Nutrition = &n
+	}
+	return encode(w, r)
+}
+
+func encode(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
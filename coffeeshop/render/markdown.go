@@ -0,0 +1,93 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	menu "demo/coffeeshop/menu"
+	"demo/coffeeshop/money"
+	"demo/coffeeshop/order"
+)
+
+// Markdown is for pasting the menu into a wiki or a chat
+type Markdown struct {
+	Money money.Format
+}
+
+func (Markdown) ContentType() string { return "text/markdown; charset=utf-8" }
+
+func (md Markdown) Menu(w io.Writer, items []menu.Item) error {
+	fmt.Fprintln(w, "# Menu")
+	for _, item := range items {
+		fmt.Fprintln(w)
+		if err := md.Item(w, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (md Markdown) Item(w io.Writer, item menu.Item) error {
+	fmt.Fprintf(w, "## %v", escape(item.Name))
+	if item.SoldOut {
+		fmt.Fprint(w, " *(sold out)*")
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w)
+	if item.Description != "" {
+		fmt.Fprintln(w, escape(item.Description))
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintln(w, "| Size | Price | kcal |")
+	fmt.Fprintln(w, "| --- | ---: | ---: |")
+	for _, s := range item.Sizes {
+		fmt.Fprintf(w, "| %v | %v | %v |\n", escape(sizeName(s)), md.Money.Amount(s.Price), calories(s.Nutrition))
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "**Contains:** %v\n", item.Allergens)
+	if d := item.Diet.String(); d != "" {
+		fmt.Fprintf(w, "\n**Suitable for:** %v\n", d)
+	}
+	return nil
+}
+
+func (Markdown) Order(w io.Writer, o order.Order) error {
+	for _, l := range o.Lines {
+		fmt.Fprintf(w, "- %d x %v\n", l.Qty, escape(describe(l)))
+	}
+	return nil
+}
+
+func (md Markdown) Receipt(w io.Writer, o order.Order) error {
+	fmt.Fprintln(w, "| Qty | Item | Price |")
+	fmt.Fprintln(w, "| ---: | --- | ---: |")
+	for _, l := range o.Lines {
+		fmt.Fprintf(w, "| %d | %v | %v |\n", l.Qty, escape(describe(l)), md.Money.Amount(l.Total()))
+	}
+	if o.Discount > 0 {
+		fmt.Fprintf(w, "| | Discount | %v |\n", md.Money.Amount(-o.Discount))
+	}
+	fmt.Fprintf(w, "| | **Total** | **%v** |\n", md.Money.Amount(o.Total()))
+	if o.Tax > 0 {
+		fmt.Fprintf(w, "| | incl. tax | %v |\n", md.Money.Amount(o.Tax))
+	}
+	for _, p := range o.Tenders {
+		fmt.Fprintf(w, "| | %v | %v |\n", tenderName(p), md.Money.Amount(p.Amount))
+		if p.Change > 0 {
+			fmt.Fprintf(w, "| | Change | %v |\n", md.Money.Amount(p.Change))
+		}
+	}
+	if r := o.Refunded(); r > 0 {
+		fmt.Fprintf(w, "| | Refunded | %v |\n", md.Money.Amount(-r))
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "**Contains:** %v\n", o.Allergens())
+	if n, complete := o.Nutrition(); complete {
+		fmt.Fprintf(w, "\n%v\n", n)
+	}
+	return nil
+}
+
+// escape stops names with | or * in them from breaking the table
+var escape = strings.NewReplacer(`\`, `\\`, "|", `\|`, "*", `\*`, "_", `\_`, "#", `\#`).Replace
@@ -0,0 +1,170 @@
+package render
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	menu "demo/coffeeshop/menu"
+	"demo/coffeeshop/money"
+	"demo/coffeeshop/order"
+)
+
+// MARK: Renderers
+
+// Renderer turns the menu and orders into one output format. An order is the ticket the barista works from,
+// a receipt is what the customer takes away (prices, totals, allergens and nutrition)
+type Renderer interface {
+	ContentType() string
+	Menu(w io.Writer, items []menu.Item) error
+	Item(w io.Writer, item menu.Item) error
+	Order(w io.Writer, o order.Order) error
+	Receipt(w io.Writer, o order.Order) error
+}
+
+// Every format we know, by the name used for --format flags and ?format= parameters
+var formats = map[string]Renderer{
+	"text":     Text{},
+	"markdown": Markdown{},
+	"html":     HTML{},
+	"json":     JSON{},
+}
+
+// Formats lists the format names, sorted
+func Formats() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Format looks up a renderer by name
+func Format(name string) (Renderer, error) {
+	r, ok := formats[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q, expected one of %v", name, strings.Join(Formats(), ", "))
+	}
+	return r, nil
+}
+
+// Negotiate picks the renderer that best fits an Accept header. ok is false when the client only accepts types we
+// can't produce. An empty header gets plain text, like curl would want
+func Negotiate(accept string) (r Renderer, ok bool) {
+	if strings.TrimSpace(accept) == "" {
+		return Text{}, true
+	}
+
+	type option struct {
+		r       Renderer
+		q       float64
+		order   int
+		precise int // text/html beats text/* beats */* when the q values are equal
+	}
+	var best []option
+	for i, part := range strings.Split(accept, ",") {
+		mediaType, params, _ := strings.Cut(part, ";")
+		mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+		q := 1.0
+		for _, p := range strings.Split(params, ";") {
+			k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+			if k == "q" {
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					q = f
+				}
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		for _, name := range []string{"text", "html", "json", "markdown"} { // Order decides who wins a wildcard
+			r := formats[name]
+			if p := matchType(mediaType, r.ContentType()); p > 0 {
+				best = append(best, option{r, q, i, p})
+				break
+			}
+		}
+	}
+	if len(best) == 0 {
+		return nil, false
+	}
+	slices.SortStableFunc(best, func(a, b option) int {
+		if c := cmp.Compare(b.q, a.q); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(b.precise, a.precise); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.order, b.order)
+	})
+	return best[0].r, true
+}
+
+// matchType says how precisely a media range from an Accept header matches a content type, 0 is no match
+func matchType(mediaRange, contentType string) int {
+	contentType, _, _ = strings.Cut(contentType, ";")
+	typ, _, _ := strings.Cut(contentType, "/")
+	switch mediaRange {
+	case contentType:
+		return 3
+	case typ + "/*":
+		return 2
+	case "*/*":
+		return 1
+	}
+	return 0
+}
+
+// WithMoney is r writing prices in the given currency and locale. Renderers start with the zero money.Format,
+// plain numbers with two decimals. JSON has numbers either way, each item and line says its currency
+func WithMoney(r Renderer, f money.Format) Renderer {
+	switch r := r.(type) {
+	case Text:
+		r.Money = f
+		return r
+	case Markdown:
+		r.Money = f
+		return r
+	case HTML:
+		r.Money = f
+		return r
+	}
+	return r
+}
+
+// MARK: Helpers
+
+// sizeName is what a size is called in the language the item was localized to, or its own name
+func sizeName(s menu.Size) string {
+	return cmp.Or(s.Label, s.Name)
+}
+
+// calories is blank when we don't have the facts
+func calories(n *menu.Nutrition) string {
+	if n == nil {
+		return ""
+	}
+	return strconv.FormatFloat(n.Calories, 'f', 0, 64)
+}
+
+// tenderName is how a payment shows on a receipt, like Card •••• 4242
+func tenderName(t order.Tender) string {
+	name := strings.ToUpper(t.Provider[:1]) + t.Provider[1:]
+	if t.Card != "" {
+		name += " " + t.Card
+	}
+	return name
+}
+
+// describe is the item and size, with the modifiers, on one line
+func describe(l order.Line) string {
+	s := l.Item + " (" + l.Size + ")"
+	if len(l.Modifiers) > 0 {
+		s += " + " + strings.Join(l.Modifiers, ", ")
+	}
+	return s
+}
@@ -0,0 +1,115 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	menu "demo/coffeeshop/menu"
+	"demo/coffeeshop/order"
+)
+
+// Names with everything that needs escaping in them
+var (
+	sneakyItem = menu.Item{ID: "item-1", Name: `<script>alert("hi")</script> & Co`, Description: "Tom's <b>best</b>",
+		Sizes:     []menu.Size{{Name: "small", Price: 3, Nutrition: &menu.Nutrition{Calories: 120, Protein: 3}}, {Name: "<large>", Price: 3.5}},
+		Allergens: menu.Milk | menu.Nuts, Diet: menu.Vegetarian}
+	sneakyOrder = order.Order{ID: "ABC123", Placed: time.Date(2026, 10, 19, 8, 30, 0, 0, time.UTC), Lines: []order.Line{
+		{Quote: menu.Quote{ItemID: "item-1", Item: sneakyItem.Name, Size: "small", Price: 3, Allergens: menu.Milk}, Modifiers: []string{"<oat> milk"}, Qty: 2},
+	}}
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept string
+		expect string // Content type, "" for nothing acceptable
+	}{
+		{"", "text/plain; charset=utf-8"},
+		{"application/json", "application/json"},
+		{"text/html;q=0.9, application/json", "application/json"},
+		{"text/*;q=0.5, text/html", "text/html; charset=utf-8"},
+		{"*/*", "text/plain; charset=utf-8"},
+		{"text/markdown, */*;q=0.1", "text/markdown; charset=utf-8"},
+		{"application/json;q=0, text/plain", "text/plain; charset=utf-8"},
+		{"image/png", ""},
+	}
+
+	for _, tt := range tests {
+		r, ok := Negotiate(tt.accept)
+		got := ""
+		if ok {
+			got = r.ContentType()
+		}
+		if got != tt.expect {
+			t.Errorf("Accept %q: got %q, expected %q", tt.accept, got, tt.expect)
+		}
+	}
+}
+
+func TestHTMLEscapes(t *testing.T) {
+	tests := []struct {
+		name   string
+		render func(w *bytes.Buffer) error
+	}{
+		{"menu", func(w *bytes.Buffer) error { return HTML{}.Menu(w, []menu.Item{sneakyItem}) }},
+		{"item", func(w *bytes.Buffer) error { return HTML{}.Item(w, sneakyItem) }},
+		{"order", func(w *bytes.Buffer) error { return HTML{}.Order(w, sneakyOrder) }},
+		{"receipt", func(w *bytes.Buffer) error { return HTML{}.Receipt(w, sneakyOrder) }},
+	}
+	for _, test := range tests {
+		var b bytes.Buffer
+		if err := test.render(&b); err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+		for _, raw := range []string{"<script>", "<b>", "<large>", "<oat>"} {
+			if strings.Contains(b.String(), raw) {
+				t.Errorf("%v: %q wasn't escaped in\n%v", test.name, raw, b.String())
+			}
+		}
+		if !strings.Contains(b.String(), "&lt;script&gt;alert(&#34;hi&#34;)&lt;/script&gt; &amp; Co") {
+			t.Errorf("%v: the item name is missing from\n%v", test.name, b.String())
+		}
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	var b bytes.Buffer
+	if err := (JSON{}).Menu(&b, []menu.Item{sneakyItem}); err != nil {
+		t.Fatal(err)
+	}
+	var m struct{ Items []menu.Item }
+	if err := json.Unmarshal(b.Bytes(), &m); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m.Items, []menu.Item{sneakyItem}) {
+		t.Errorf("Menu: got %+v back, expected %+v", m.Items, sneakyItem)
+	}
+
+	b.Reset()
+	if err := (JSON{}).Order(&b, sneakyOrder); err != nil {
+		t.Fatal(err)
+	}
+	var o order.Order
+	if err := json.Unmarshal(b.Bytes(), &o); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(o, sneakyOrder) {
+		t.Errorf("Order: got %+v back, expected %+v", o, sneakyOrder)
+	}
+
+	// A receipt is the order with the sums done, the order itself comes back out of it
+	b.Reset()
+	if err := (JSON{}).Receipt(&b, sneakyOrder); err != nil {
+		t.Fatal(err)
+	}
+	var r receipt
+	if err := json.Unmarshal(b.Bytes(), &r); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(r.Order, sneakyOrder) || r.Total != 6 || r.Allergens != menu.Milk {
+		t.Errorf("Receipt: got %+v back", r)
+	}
+}
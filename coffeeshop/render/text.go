@@ -0,0 +1,102 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+
+	menu "demo/coffeeshop/menu"
+	"demo/coffeeshop/money"
+	"demo/coffeeshop/order"
+)
+
+// Text is plain text with the columns lined up, for the terminal and for curl
+type Text struct {
+	Money money.Format
+}
+
+func (Text) ContentType() string { return "text/plain; charset=utf-8" }
+
+func (t Text) Menu(w io.Writer, items []menu.Item) error {
+	for i, item := range items {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		if err := t.Item(w, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t Text) Item(w io.Writer, item menu.Item) error {
+	title := item.Name
+	if item.SoldOut {
+		title += " (sold out)"
+	}
+	fmt.Fprintln(w, title)
+	fmt.Fprintln(w, strings.Repeat("-", utf8.RuneCountInString(title))) // Not len, café and カフェ are more bytes than letters
+	if item.Description != "" {
+		fmt.Fprintln(w, item.Description)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight) // Right aligned so the decimal points line up
+	fmt.Fprintln(tw, "\tsize\tprice\tkcal\t")
+	for _, s := range item.Sizes {
+		fmt.Fprintf(tw, "\t%v\t%v\t%v\t\n", sizeName(s), t.Money.Amount(s.Price), calories(s.Nutrition))
+	}
+	tw.Flush()
+
+	fmt.Fprintf(w, "  Contains: %v\n", item.Allergens)
+	if d := item.Diet.String(); d != "" {
+		fmt.Fprintf(w, "  Suitable for: %v\n", d)
+	}
+	return nil
+}
+
+func (Text) Order(w io.Writer, o order.Order) error {
+	for _, l := range o.Lines {
+		fmt.Fprintf(w, "%2d x %v\n", l.Qty, describe(l))
+	}
+	return nil
+}
+
+func (t Text) Receipt(w io.Writer, o order.Order) error {
+	fmt.Fprintln(w, strings.Repeat("=", 40))
+	for _, l := range o.Lines {
+		fmt.Fprintf(w, "%-30v%10v\n", fmt.Sprintf("%2d x %v (%v)", l.Qty, l.Item, l.Size), t.Money.Amount(l.Total()))
+		for _, m := range l.Modifiers {
+			fmt.Fprintf(w, "       + %v\n", m)
+		}
+	}
+	fmt.Fprintln(w, strings.Repeat("-", 40))
+	if o.Discount > 0 {
+		fmt.Fprintf(w, "%-30v%10v\n", "Discount", t.Money.Amount(-o.Discount))
+	}
+	fmt.Fprintf(w, "%-30v%10v\n", "Total", t.Money.Amount(o.Total()))
+	if o.Tax > 0 {
+		fmt.Fprintf(w, "%-30v%10v\n", "  incl. tax", t.Money.Amount(o.Tax))
+	}
+	for _, p := range o.Tenders {
+		fmt.Fprintf(w, "%-30v%10v\n", tenderName(p), t.Money.Amount(p.Amount))
+		if p.Change > 0 {
+			fmt.Fprintf(w, "%-30v%10v\n", "  Tendered", t.Money.Amount(p.Tendered))
+			fmt.Fprintf(w, "%-30v%10v\n", "  Change", t.Money.Amount(p.Change))
+		}
+	}
+	if r := o.Refunded(); r > 0 {
+		fmt.Fprintf(w, "%-30v%10v\n", "Refunded", t.Money.Amount(-r))
+	}
+	if len(o.Tenders) > 0 && o.Due() > 0 {
+		fmt.Fprintf(w, "%-30v%10v\n", "Due", t.Money.Amount(o.Due()))
+	}
+	fmt.Fprintln(w, strings.Repeat("=", 40))
+
+	fmt.Fprintf(w, "Contains: %v\n", o.Allergens())
+	if n, complete := o.Nutrition(); complete {
+		fmt.Fprintln(w, n)
+	}
+	return nil
+}
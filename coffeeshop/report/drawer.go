@@ -0,0 +1,135 @@
+package report
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"demo/coffeeshop/money"
+	"demo/coffeeshop/payment"
+)
+
+// MARK: Drawer variance
+
+// Variance is a closed drawer session added up: what should have been in the drawer against what was counted
+type Variance struct {
+	Session  string          `json:"session"`
+	Store    string          `json:"store"`
+	Currency string          `json:"currency,omitempty"`
+	OpenedAt time.Time       `json:"openedAt"`
+	OpenedBy string          `json:"openedBy,omitempty"`
+	ClosedAt time.Time       `json:"closedAt"`
+	ClosedBy string          `json:"closedBy,omitempty"`
+	Float    float64         `json:"float"`
+	Sales    float64         `json:"sales"`   // Cash tenders
+	Refunds  float64         `json:"refunds"` // Cash given back, negative like the rest of the money out
+	PayIns   float64         `json:"payIns"`
+	PayOuts  float64         `json:"payOuts"`
+	Expected float64         `json:"expected"`
+	Counted  float64         `json:"counted"`
+	Variance float64         `json:"variance"` // Over is positive, short is negative
+	Count    []payment.Piece `json:"count"`
+	Notes    []payment.Move  `json:"notes,omitempty"` // The pay-ins and pay-outs, with what they were for
+}
+
+// Drawer adds up a closed session. One that's still open is added up as it stands, with nothing counted
+func Drawer(s payment.Session) Variance {
+	v := Variance{Session: s.ID, Store: s.Store, Currency: s.Currency, OpenedAt: s.OpenedAt, OpenedBy: s.OpenedBy,
+		ClosedBy: s.ClosedBy, Float: s.Float, Sales: s.Total("sale"), Refunds: s.Total("refund"), PayIns: s.Total("pay-in"),
+		PayOuts: s.Total("pay-out"), Expected: s.Expected(), Counted: s.CountedTotal(), Variance: s.Variance(),
+		Count: append([]payment.Piece{}, s.Counted...)}
+	if s.ClosedAt != nil {
+		v.ClosedAt = *s.ClosedAt
+	}
+	for _, m := range s.Moves {
+		if m.Kind == "pay-in" || m.Kind == "pay-out" {
+			v.Notes = append(v.Notes, m)
+		}
+	}
+	return v
+}
+
+// Status is how the count came out: over, short or balanced
+func (v Variance) Status() string {
+	switch {
+	case v.Variance > 0:
+		return "over"
+	case v.Variance < 0:
+		return "short"
+	}
+	return "balanced"
+}
+
+// WriteText prints the report 40 characters wide, to fit the receipt printer. name is the store's name
+func (v Variance) WriteText(w io.Writer, name string) error {
+	f, _ := money.New(v.Currency, "") // Unknown codes get plain numbers
+	line := func(label string, amount float64) {
+		fmt.Fprintf(w, "%-28v%12v\n", label, f.Amount(amount))
+	}
+	rule := func(c string) { fmt.Fprintln(w, strings.Repeat(c, 40)) }
+
+	rule("=")
+	fmt.Fprintf(w, "%v\n", centre("DRAWER COUNT", 40))
+	fmt.Fprintf(w, "%v\n", centre(name, 40))
+	rule("=")
+	fmt.Fprintf(w, "Opened %v %v\n", v.OpenedAt.Format("2006-01-02 15:04"), v.OpenedBy)
+	if !v.ClosedAt.IsZero() {
+		fmt.Fprintf(w, "Closed %v %v\n", v.ClosedAt.Format("2006-01-02 15:04"), v.ClosedBy)
+	}
+	rule("-")
+	line("Float", v.Float)
+	line("Cash sales", v.Sales)
+	line("Cash refunds", v.Refunds)
+	line("Pay-ins", v.PayIns)
+	line("Pay-outs", v.PayOuts)
+	rule("-")
+	line("Expected", v.Expected)
+	if v.ClosedAt.IsZero() {
+		rule("=")
+		fmt.Fprintln(w, "Still open, nothing counted yet")
+		return nil
+	}
+	rule("-")
+	for _, p := range v.Count {
+		line(fmt.Sprintf("  %4d x %v", p.Count, f.Amount(p.Value)), p.Value*float64(p.Count))
+	}
+	line("Counted", v.Counted)
+	rule("=")
+	line(strings.ToUpper(v.Status()), v.Variance)
+	rule("=")
+	for _, m := range v.Notes {
+		fmt.Fprintf(w, "%v %v %v: %v\n", m.At.Format("15:04"), m.Kind, f.Amount(m.Amount), m.Reason)
+	}
+	return nil
+}
+
+// centre pads s to sit in the middle of width
+func centre(s string, width int) string {
+	if n := len([]rune(s)); n < width {
+		return strings.Repeat(" ", (width-n)/2) + s
+	}
+	return s
+}
+
+// WriteDrawersCSV writes one row per session, for a spreadsheet of every close
+func WriteDrawersCSV(w io.Writer, vs []Variance) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"session", "store", "currency", "opened", "opened_by", "closed", "closed_by", "float", "sales",
+		"refunds", "pay_ins", "pay_outs", "expected", "counted", "variance", "status"})
+	for _, v := range vs {
+		cur, _ := money.LookupCurrency(v.Currency) // The zero Currency's two places for one we don't know
+		amount := func(x float64) string { return strconv.FormatFloat(x, 'f', cur.Places(), 64) }
+		closed := ""
+		if !v.ClosedAt.IsZero() {
+			closed = v.ClosedAt.Format(time.RFC3339)
+		}
+		cw.Write([]string{v.Session, v.Store, v.Currency, v.OpenedAt.Format(time.RFC3339), v.OpenedBy, closed, v.ClosedBy,
+			amount(v.Float), amount(v.Sales), amount(v.Refunds), amount(v.PayIns), amount(v.PayOuts), amount(v.Expected),
+			amount(v.Counted), amount(v.Variance), v.Status()})
+	}
+	cw.Flush()
+	return cw.Error()
+}
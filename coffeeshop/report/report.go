@@ -0,0 +1,105 @@
+package report
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"demo/coffeeshop/money"
+	"demo/coffeeshop/order"
+)
+
+// MARK: Reports
+
+// Totals are the sales for one store, or for every store together
+type Totals struct {
+	Store    string  `json:"store"`              // "" for orders from before there were stores, or for all of them in All
+	Currency string  `json:"currency,omitempty"` // Revenue is in it, "" when the shop hadn't said
+	Orders   int     `json:"orders"`
+	Items    int     `json:"items"`   // Drinks, counting quantities
+	Revenue  float64 `json:"revenue"` // Less anything refunded since
+}
+
+// Average is the average order
+func (t Totals) Average() float64 {
+	if t.Orders == 0 {
+		return 0
+	}
+	return t.Revenue / float64(t.Orders)
+}
+
+func (t *Totals) add(o order.Order) {
+	t.Orders++
+	cur, _ := money.LookupCurrency(t.Currency)                  // The zero Currency rounds to cents, fine for "" and anything we've stopped knowing
+	t.Revenue = cur.Round(t.Revenue + o.Total() - o.Refunded()) // So 3.35 doesn't come out as 3.3499999999999996
+	for _, l := range o.Lines {
+		t.Items += l.Qty
+	}
+}
+
+// ByStore is the sales at each store, by store ID, and for all of them together. Yen and dollars don't add up,
+// so All has a total for each currency
+type ByStore struct {
+	Stores []Totals `json:"stores"`
+	All    []Totals `json:"all"`
+}
+
+// Stores adds up orders by the store that took them. Only orders from stores in only are counted, or every
+// order when only is empty. Like Sales, only completed orders count. A store that changed currency gets a row
+// for each
+func Stores(orders []order.Order, only ...string) ByStore {
+	r := ByStore{Stores: []Totals{}, All: []Totals{}} // So the JSON is [] rather than null
+	for _, o := range orders {
+		if len(only) > 0 && !slices.Contains(only, o.Store) || !o.Completed() {
+			continue
+		}
+		addTo(&r.Stores, Totals{Store: o.Store, Currency: o.Currency()}, o)
+		addTo(&r.All, Totals{Currency: o.Currency()}, o)
+	}
+	slices.SortFunc(r.Stores, func(a, b Totals) int {
+		return cmp.Or(cmp.Compare(a.Store, b.Store), cmp.Compare(a.Currency, b.Currency))
+	})
+	slices.SortFunc(r.All, func(a, b Totals) int { return cmp.Compare(a.Currency, b.Currency) })
+	return r
+}
+
+// addTo adds o to the row in list for the same store and currency as key, starting one if there isn't one
+func addTo(list *[]Totals, key Totals, o order.Order) {
+	i := slices.IndexFunc(*list, func(t Totals) bool { return t.Store == key.Store && t.Currency == key.Currency })
+	if i < 0 {
+		*list = append(*list, key)
+		i = len(*list) - 1
+	}
+	(*list)[i].add(o)
+}
+
+// WriteText prints the report as a table, with the totals for every store at the bottom. names gives the
+// stores' names by ID. Amounts are written the way their currency usually is
+func (r ByStore) WriteText(w io.Writer, names map[string]string) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "store\torders\titems\trevenue\taverage\t")
+	row := func(name string, t Totals) {
+		f, _ := money.New(t.Currency, "") // Unknown codes get plain numbers
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\t\n", name, t.Orders, t.Items, f.Amount(t.Revenue), f.Amount(t.Average()))
+	}
+	for _, t := range r.Stores {
+		name := names[t.Store]
+		switch {
+		case t.Store == "":
+			name = "(no store)"
+		case name == "":
+			name = t.Store // Closed since, or from another menu file
+		}
+		row(name, t)
+	}
+	for _, t := range r.All {
+		row(strings.TrimSpace("All stores "+t.Currency), t)
+	}
+	if len(r.All) == 0 {
+		row("All stores", Totals{})
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,187 @@
+package report
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	menu "demo/coffeeshop/menu"
+	"demo/coffeeshop/order"
+	"demo/coffeeshop/payment"
+)
+
+func TestStores(t *testing.T) {
+	line := func(price float64, qty int) order.Line {
+		return order.Line{Quote: menu.Quote{Price: price}, Qty: qty}
+	}
+	paid := func(store string, lines ...order.Line) order.Order {
+		o := order.Order{Store: store, Lines: lines}
+		o.Tenders = []order.Tender{{Provider: "card", Amount: o.Total()}}
+		return o
+	}
+	orders := []order.Order{
+		paid("high-st", line(3, 2)),
+		paid("dock", line(2.5, 1), line(4, 1)),
+		paid("high-st", line(3, 1)),
+		paid("", line(1, 1)), // From before there were stores
+	}
+
+	r := Stores(orders)
+	if len(r.Stores) != 3 || r.Stores[0].Store != "" || r.Stores[1].Store != "dock" {
+		t.Fatalf("Got %+v", r.Stores)
+	}
+	if hs := r.Stores[2]; hs.Orders != 2 || hs.Items != 3 || hs.Revenue != 9 || hs.Average() != 4.5 {
+		t.Errorf("High Street: got %+v", hs)
+	}
+	if len(r.All) != 1 || r.All[0].Orders != 4 || r.All[0].Items != 6 || r.All[0].Revenue != 16.5 {
+		t.Errorf("All stores: got %+v", r.All)
+	}
+
+	if r := Stores(orders, "dock"); len(r.Stores) != 1 || r.All[0].Revenue != 6.5 {
+		t.Errorf("Only the dock: got %+v", r)
+	}
+	refunded := paid("dock", line(4, 1))
+	refunded.Refunds = []order.Refund{{Amount: 1.5}}
+	if r := Stores(append(orders, refunded), "dock"); r.Stores[0].Orders != 2 || r.Stores[0].Revenue != 9 {
+		t.Errorf("With a refund: got %+v", r.Stores)
+	}
+
+	// Priced and never paid for, or all given back, isn't a sale
+	given := paid("dock", line(4, 1))
+	given.Refunds = []order.Refund{{Amount: 4}}
+	unpaid := order.Order{Store: "dock", Lines: []order.Line{line(10, 1)}}
+	if r := Stores(append(orders, given, unpaid), "dock"); r.Stores[0].Orders != 1 || r.Stores[0].Revenue != 6.5 || r.Stores[0].Average() != 6.5 {
+		t.Errorf("Unpaid and refunded: got %+v", r.Stores)
+	}
+	if r := Stores(nil); r.Stores == nil || r.All == nil {
+		t.Errorf("No orders: got %+v", r)
+	}
+
+	// Yen don't add up with the rest
+	yen := order.Line{Quote: menu.Quote{Price: 480, Currency: "JPY"}, Qty: 2}
+	orders = append(orders, paid("shibuya", yen))
+	r = Stores(orders)
+	if len(r.All) != 2 || r.All[0].Revenue != 16.5 || r.All[1].Currency != "JPY" || r.All[1].Revenue != 960 {
+		t.Errorf("All stores in two currencies: got %+v", r.All)
+	}
+
+	var b strings.Builder
+	r.WriteText(&b, map[string]string{"high-st": "High Street"})
+	for _, expect := range []string{"(no store)", "High Street", "dock", "All stores JPY", "¥960"} {
+		if !strings.Contains(b.String(), expect) {
+			t.Errorf("Missing %q in\n%v", expect, b.String())
+		}
+	}
+}
+
+func TestDrawer(t *testing.T) {
+	closed := time.Date(2026, 3, 2, 17, 30, 0, 0, time.UTC)
+	s := payment.Session{ID: "cash_session_0001", Store: "dock", Currency: "USD", Float: 50, ClosedAt: &closed,
+		Moves: []payment.Move{
+			{Kind: "sale", Amount: 12.5},
+			{Kind: "refund", Amount: -2},
+			{Kind: "pay-out", Amount: -3, Reason: "milk"},
+		},
+		Counted: []payment.Piece{{Value: 20, Count: 2}, {Value: 10, Count: 1}, {Value: 5, Count: 1}, {Value: 0.25, Count: 1}},
+	}
+	v := Drawer(s)
+	if v.Expected != 57.5 || v.Counted != 55.25 || v.Variance != -2.25 || v.Status() != "short" {
+		t.Errorf("Got %+v", v)
+	}
+
+	var b strings.Builder
+	v.WriteText(&b, "The Dock")
+	for _, want := range []string{"The Dock", "SHORT", "-$2.25", "pay-out -$3.00: milk"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("%q isn't in\n%v", want, b.String())
+		}
+	}
+	b.Reset()
+	WriteDrawersCSV(&b, []Variance{v})
+	if !strings.Contains(b.String(), ",50.00,12.50,-2.00,0.00,-3.00,57.50,55.25,-2.25,short\n") {
+		t.Errorf("Got %v", b.String())
+	}
+}
+
+func TestSales(t *testing.T) {
+	monday := time.Date(2026, 10, 19, 8, 15, 0, 0, time.UTC)
+	line := func(item, size string, price float64, qty int, modifiers ...string) order.Line {
+		return order.Line{Quote: menu.Quote{Item: item, Size: size, Price: price}, Modifiers: modifiers, Qty: qty}
+	}
+	paid := func(amount float64) []order.Tender { return []order.Tender{{Provider: "card", Amount: amount}} }
+	orders := []order.Order{
+		{Placed: monday, Tax: 0.5, Lines: []order.Line{line("Coffee", "large", 2, 2, "oat milk"), line("Espresso", "double", 2.25, 1)},
+			Tenders: paid(6.25)},
+		{Placed: monday.Add(30 * time.Minute), Discount: 1, Lines: []order.Line{line("Coffee", "small", 1.65, 1, "oat milk", "extra shot")},
+			Tenders: paid(0.65)},
+		{Placed: monday.Add(26 * time.Hour), Store: "dock", Lines: []order.Line{line("Cake", "slice", 3, 1)},
+			Tenders: paid(3), Refunds: []order.Refund{{Amount: 1}}},
+
+		// Priced and never paid for, and paid for then all given back: neither sold anything
+		{Placed: monday, Lines: []order.Line{line("Coffee", "large", 2, 5)}},
+		{Placed: monday, Lines: []order.Line{line("Espresso", "double", 2.25, 4)}, Tenders: paid(9), Refunds: []order.Refund{{Amount: 9}}},
+	}
+
+	r := Sales(orders, Filter{Location: time.UTC})
+	if len(r.Currencies) != 1 {
+		t.Fatalf("Got %+v", r)
+	}
+	s := r.Currencies[0]
+	if s.Orders != 3 || s.Items != 5 || s.Gross != 10.9 || s.Discounts != 1 || s.Refunds != 1 || s.Net != 8.9 || s.Tax != 0.5 || s.AverageTicket != 3.3 {
+		t.Errorf("Summary: got %+v", s)
+	}
+	if len(s.ByItem) != 3 || s.ByItem[0] != (Row{"Coffee", 2, 3, 5.65}) {
+		t.Errorf("By item: got %+v", s.ByItem)
+	}
+	if len(s.ByCategory) != 2 || s.ByCategory[0] != (Row{"coffee", 2, 4, 7.9}) || s.ByCategory[1].Name != "(off the menu)" {
+		t.Errorf("By category: got %+v", s.ByCategory)
+	}
+	if len(s.ByHour) != 2 || s.ByHour[0] != (Row{"08:00", 2, 4, 6.9}) || s.ByHour[1].Name != "10:00" {
+		t.Errorf("By hour: got %+v", s.ByHour)
+	}
+	if len(s.ByWeekday) != 2 || s.ByWeekday[0].Name != "Monday" || s.ByWeekday[1].Name != "Tuesday" {
+		t.Errorf("By weekday: got %+v", s.ByWeekday)
+	}
+	if len(s.TopModifiers) != 2 || s.TopModifiers[0] != (Row{Name: "oat milk", Orders: 2, Qty: 3}) {
+		t.Errorf("Modifiers: got %+v", s.TopModifiers)
+	}
+
+	// Just the Monday
+	var f Filter
+	f.Location = time.UTC
+	if err := f.Days("2026-10-19", "2026-10-19"); err != nil {
+		t.Fatal(err)
+	}
+	r = Sales(orders, f)
+	if r.Currencies[0].Orders != 2 || r.period() != "for 2026-10-19" {
+		t.Errorf("Monday: got %+v, %q", r.Currencies[0], r.period())
+	}
+	if err := f.Days("2026-10-20", "2026-10-19"); err == nil {
+		t.Error("Expected an error for a backwards range")
+	}
+
+	var b strings.Builder
+	r.WriteCSV(&b)
+	for _, expect := range []string{"currency,table,name,orders,qty,revenue\n", ",summary,discounts,,,1.00\n", ",hour,08:00,2,4,6.90\n", ",modifier,extra shot,1,1,\n"} {
+		if !strings.Contains(b.String(), expect) {
+			t.Errorf("Missing %q in\n%v", expect, b.String())
+		}
+	}
+}
+
+func TestNextClose(t *testing.T) {
+	at, err := ParseClock("18:30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	morning := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	if next := NextClose(morning, at); !next.Equal(time.Date(2026, 10, 19, 18, 30, 0, 0, time.UTC)) {
+		t.Errorf("From the morning: got %v", next)
+	}
+	if next := NextClose(time.Date(2026, 10, 19, 18, 30, 0, 0, time.UTC), at); !next.Equal(time.Date(2026, 10, 20, 18, 30, 0, 0, time.UTC)) {
+		t.Errorf("At closing: got %v", next)
+	}
+	if _, err := ParseClock("6pm"); err == nil {
+		t.Error("Expected an error for 6pm")
+	}
+}
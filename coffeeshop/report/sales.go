@@ -0,0 +1,359 @@
+package report
+
+import (
+	"cmp"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	menu "demo/coffeeshop/menu"
+	"demo/coffeeshop/money"
+	"demo/coffeeshop/order"
+)
+
+// MARK: Sales
+
+// Filter picks the orders a sales report covers. The zero Filter is every order there is
+type Filter struct {
+	Stores   []string       // Only orders from these, or every store when empty
+	From, To time.Time      // Placed at or after From and before To, zero for no limit
+	Location *time.Location // For days and hours of the day, time.Local when nil
+}
+
+// Days sets From and To from dates written 2006-01-02, to taking in the whole of that day. Either can be ""
+func (f *Filter) Days(from, to string) error {
+	if from != "" {
+		t, err := time.ParseInLocation(time.DateOnly, from, f.location())
+		if err != nil {
+			return fmt.Errorf("from %q should look like 2006-01-02", from)
+		}
+		f.From = t
+	}
+	if to != "" {
+		t, err := time.ParseInLocation(time.DateOnly, to, f.location())
+		if err != nil {
+			return fmt.Errorf("to %q should look like 2006-01-02", to)
+		}
+		f.To = t.AddDate(0, 0, 1)
+	}
+	if !f.From.IsZero() && !f.To.IsZero() && !f.From.Before(f.To) {
+		return fmt.Errorf("from %v is after to %v", from, to)
+	}
+	return nil
+}
+
+// Day is a Filter for the whole of the day t is in
+func Day(t time.Time, stores ...string) Filter {
+	y, m, d := t.Date()
+	from := time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+	return Filter{Stores: stores, From: from, To: from.AddDate(0, 0, 1), Location: t.Location()}
+}
+
+func (f Filter) location() *time.Location {
+	if f.Location == nil {
+		return time.Local
+	}
+	return f.Location
+}
+
+func (f Filter) match(o order.Order) bool {
+	if len(f.Stores) > 0 && !slices.Contains(f.Stores, o.Store) {
+		return false
+	}
+	if !f.From.IsZero() && o.Placed.Before(f.From) {
+		return false
+	}
+	return f.To.IsZero() || o.Placed.Before(f.To)
+}
+
+// Row is one line of a sales table, like one item or one hour of the day
+type Row struct {
+	Name    string  `json:"name"`
+	Orders  int     `json:"orders"`  // Orders it was on
+	Qty     int     `json:"qty"`     // Drinks, counting quantities
+	Revenue float64 `json:"revenue"` // Left out of the modifiers, their price is in the drink's
+}
+
+// CurrencySales is what sold in one currency. Item, size and category revenue is what the lines came to before
+// the order's discount, the hours and days are what the orders came to after it
+type CurrencySales struct {
+	Currency      string  `json:"currency,omitempty"`
+	Orders        int     `json:"orders"`
+	Items         int     `json:"items"`     // Drinks, counting quantities
+	Gross         float64 `json:"gross"`     // What the lines came to
+	Discounts     float64 `json:"discounts"` // Taken off orders
+	Refunds       float64 `json:"refunds"`   // Given back since, on the orders in the report
+	Net           float64 `json:"net"`       // Gross less discounts and refunds
+	Tax           float64 `json:"tax"`       // Collected, it's included in the prices
+	AverageTicket float64 `json:"averageTicket"`
+
+	ByItem       []Row `json:"byItem"`
+	BySize       []Row `json:"bySize"`
+	ByCategory   []Row `json:"byCategory"`
+	ByHour       []Row `json:"byHour"`    // Only the hours something sold, like 07:00
+	ByWeekday    []Row `json:"byWeekday"` // Monday first
+	TopModifiers []Row `json:"topModifiers"`
+}
+
+// SalesReport is the sales for the orders a Filter picked, one CurrencySales per currency since yen and dollars
+// don't add up
+type SalesReport struct {
+	From       *time.Time      `json:"from,omitempty"`
+	To         *time.Time      `json:"to,omitempty"` // Up to but not including
+	Stores     []string        `json:"stores,omitempty"`
+	Currencies []CurrencySales `json:"currencies"`
+}
+
+// topModifiers is how many modifiers a report lists
+const topModifiers = 10
+
+// Sales works out what sold in the orders f picks. Only completed orders count, the ledger also has orders that
+// were priced and never paid for. Categories come from the menu as it is now, orders don't keep them, so an
+// item that's since come off the menu is "(off the menu)"
+func Sales(orders []order.Order, f Filter) SalesReport {
+	r := SalesReport{Stores: f.Stores, Currencies: []CurrencySales{}} // So the JSON is [] rather than null
+	if !f.From.IsZero() {
+		r.From = &f.From
+	}
+	if !f.To.IsZero() {
+		r.To = &f.To
+	}
+	type tally struct {
+		CurrencySales
+		item, size, category, hour, weekday, modifier map[string]*Row
+		names                                         map[string]string // Item names by ID, the newest order's
+	}
+	byCurrency := map[string]*tally{}
+	for _, o := range orders {
+		if !f.match(o) || !o.Completed() {
+			continue
+		}
+		t := byCurrency[o.Currency()]
+		if t == nil {
+			t = &tally{CurrencySales: CurrencySales{Currency: o.Currency()}, item: map[string]*Row{}, size: map[string]*Row{},
+				category: map[string]*Row{}, hour: map[string]*Row{}, weekday: map[string]*Row{}, modifier: map[string]*Row{},
+				names: map[string]string{}}
+			byCurrency[o.Currency()] = t
+		}
+		cur, _ := money.LookupCurrency(t.Currency) // The zero Currency rounds to cents, like Totals
+		t.Orders++
+		t.Gross = cur.Round(t.Gross + o.Subtotal())
+		t.Discounts = cur.Round(t.Discounts + o.Discount)
+		t.Refunds = cur.Round(t.Refunds + o.Refunded())
+		t.Tax = cur.Round(t.Tax + o.Tax)
+
+		// Each row counts the order once, however many of its lines it's on
+		seen := map[*Row]bool{}
+		count := func(rows map[string]*Row, key string, qty int, revenue float64) {
+			row := rows[key]
+			if row == nil {
+				row = &Row{Name: key}
+				rows[key] = row
+			}
+			if !seen[row] {
+				seen[row] = true
+				row.Orders++
+			}
+			row.Qty += qty
+			row.Revenue = cur.Round(row.Revenue + revenue)
+		}
+		placed := o.Placed.In(f.location())
+		qty := 0
+		for _, l := range o.Lines {
+			qty += l.Qty
+			id := cmp.Or(l.ItemID, l.Item)
+			t.names[id] = l.Item // The ledger's oldest first, so this ends up the newest name
+			count(t.item, id, l.Qty, l.Total())
+			count(t.size, l.Size, l.Qty, l.Total())
+			count(t.category, category(id), l.Qty, l.Total())
+			for _, m := range l.Modifiers {
+				count(t.modifier, strings.ToLower(strings.TrimSpace(m)), l.Qty, 0)
+			}
+		}
+		t.Items += qty
+		count(t.hour, placed.Format("15:00"), qty, o.Total())
+		count(t.weekday, placed.Weekday().String(), qty, o.Total())
+	}
+
+	for _, t := range byCurrency {
+		s := t.CurrencySales
+		cur, _ := money.LookupCurrency(s.Currency)
+		s.Net = cur.Round(s.Gross - s.Discounts - s.Refunds)
+		if s.Orders > 0 {
+			s.AverageTicket = cur.Round((s.Gross - s.Discounts) / float64(s.Orders))
+		}
+		for id, row := range t.item {
+			row.Name = t.names[id]
+		}
+		s.ByItem = byRevenue(t.item)
+		s.BySize = byRevenue(t.size)
+		s.ByCategory = byRevenue(t.category)
+		s.ByHour = sortedBy(t.hour, func(a, b Row) int { return cmp.Compare(a.Name, b.Name) })
+		s.ByWeekday = sortedBy(t.weekday, func(a, b Row) int { return cmp.Compare(weekday(a.Name), weekday(b.Name)) })
+		s.TopModifiers = sortedBy(t.modifier, func(a, b Row) int { return cmp.Or(cmp.Compare(b.Qty, a.Qty), cmp.Compare(a.Name, b.Name)) })
+		if len(s.TopModifiers) > topModifiers {
+			s.TopModifiers = s.TopModifiers[:topModifiers]
+		}
+		r.Currencies = append(r.Currencies, s)
+	}
+	slices.SortFunc(r.Currencies, func(a, b CurrencySales) int { return cmp.Compare(a.Currency, b.Currency) })
+	return r
+}
+
+// category is the item's category on the menu now
+func category(id string) string {
+	item, err := menu.Lookup(id)
+	if err != nil {
+		return "(off the menu)"
+	}
+	return cmp.Or(item.Category, "(none)")
+}
+
+// weekday is where a day goes in the week, Monday first the way the shop's rota is
+func weekday(name string) int {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if d.String() == name {
+			return (int(d) + 6) % 7
+		}
+	}
+	return 7
+}
+
+// byRevenue is the rows best selling first
+func byRevenue(rows map[string]*Row) []Row {
+	return sortedBy(rows, func(a, b Row) int {
+		return cmp.Or(cmp.Compare(b.Revenue, a.Revenue), cmp.Compare(b.Qty, a.Qty), cmp.Compare(a.Name, b.Name))
+	})
+}
+
+func sortedBy(rows map[string]*Row, compare func(a, b Row) int) []Row {
+	out := make([]Row, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, *row)
+	}
+	slices.SortFunc(out, compare)
+	return out
+}
+
+// MARK: Writing
+
+// WriteText prints the report as tables, one set for each currency. Amounts are written the way their currency
+// usually is
+func (r SalesReport) WriteText(w io.Writer) error {
+	fmt.Fprintf(w, "Sales %v\n", r.period())
+	if len(r.Currencies) == 0 {
+		fmt.Fprintln(w, "\nNo orders")
+	}
+	for _, s := range r.Currencies {
+		f, _ := money.New(s.Currency, "") // Unknown codes get plain numbers
+		if len(r.Currencies) > 1 {
+			fmt.Fprintf(w, "\nIn %v\n", s.Currency)
+		}
+		fmt.Fprintln(w)
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+		for _, line := range [][2]string{
+			{"orders", strconv.Itoa(s.Orders)},
+			{"items", strconv.Itoa(s.Items)},
+			{"gross", f.Amount(s.Gross)},
+			{"discounts", f.Amount(-s.Discounts)},
+			{"refunds", f.Amount(-s.Refunds)},
+			{"net", f.Amount(s.Net)},
+			{"tax collected", f.Amount(s.Tax)},
+			{"average ticket", f.Amount(s.AverageTicket)},
+		} {
+			fmt.Fprintf(tw, "%v\t%v\t\n", line[0], line[1])
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+
+		for _, table := range s.tables() {
+			fmt.Fprintln(w)
+			tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+			if table.name == "modifier" {
+				fmt.Fprintf(tw, "%v\torders\tqty\t\n", table.name)
+			} else {
+				fmt.Fprintf(tw, "%v\torders\tqty\trevenue\t\n", table.name)
+			}
+			for _, row := range table.rows {
+				if table.name == "modifier" {
+					fmt.Fprintf(tw, "%v\t%v\t%v\t\n", row.Name, row.Orders, row.Qty)
+				} else {
+					fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t\n", row.Name, row.Orders, row.Qty, f.Amount(row.Revenue))
+				}
+			}
+			if err := tw.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// WriteCSV writes every table as rows of one sheet, with the figures above them as "summary" rows. Amounts are
+// plain numbers to the currency's places, so a spreadsheet can add them up
+func (r SalesReport) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"currency", "table", "name", "orders", "qty", "revenue"})
+	for _, s := range r.Currencies {
+		cur, _ := money.LookupCurrency(s.Currency)
+		amount := func(v float64) string { return strconv.FormatFloat(cur.Round(v), 'f', cur.Places(), 64) }
+		cw.Write([]string{s.Currency, "summary", "total", strconv.Itoa(s.Orders), strconv.Itoa(s.Items), amount(s.Net)})
+		for _, line := range []struct {
+			name string
+			v    float64
+		}{{"gross", s.Gross}, {"discounts", s.Discounts}, {"refunds", s.Refunds}, {"net", s.Net}, {"tax", s.Tax}, {"average ticket", s.AverageTicket}} {
+			cw.Write([]string{s.Currency, "summary", line.name, "", "", amount(line.v)})
+		}
+		for _, table := range s.tables() {
+			for _, row := range table.rows {
+				revenue := amount(row.Revenue)
+				if table.name == "modifier" {
+					revenue = ""
+				}
+				cw.Write([]string{s.Currency, table.name, row.Name, strconv.Itoa(row.Orders), strconv.Itoa(row.Qty), revenue})
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+type salesTable struct {
+	name string
+	rows []Row
+}
+
+// tables are the breakdowns in the order they're printed
+func (s CurrencySales) tables() []salesTable {
+	return []salesTable{
+		{"item", s.ByItem}, {"size", s.BySize}, {"category", s.ByCategory},
+		{"hour", s.ByHour}, {"weekday", s.ByWeekday}, {"modifier", s.TopModifiers},
+	}
+}
+
+// period describes what the report covers, like "for 2026-10-19 at downtown"
+func (r SalesReport) period() string {
+	var s string
+	switch {
+	case r.From != nil && r.To != nil && r.To.Equal(r.From.AddDate(0, 0, 1)):
+		s = "for " + r.From.Format(time.DateOnly)
+	case r.From != nil && r.To != nil:
+		s = "from " + r.From.Format(time.DateOnly) + " to " + r.To.AddDate(0, 0, -1).Format(time.DateOnly)
+	case r.From != nil:
+		s = "since " + r.From.Format(time.DateOnly)
+	case r.To != nil:
+		s = "up to " + r.To.AddDate(0, 0, -1).Format(time.DateOnly)
+	default:
+		s = "for every order"
+	}
+	if len(r.Stores) > 0 {
+		s += " at " + strings.Join(r.Stores, ", ")
+	}
+	return s
+}
@@ -0,0 +1,73 @@
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// MARK: Closing time
+
+// ParseClock reads a time of day written 15:04, as how long after midnight it is
+func ParseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%q should be a time of day like 18:30", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// NextClose is the first time after now that the clock says at, today or tomorrow
+func NextClose(now time.Time, at time.Duration) time.Time {
+	y, m, d := now.Date()
+	next := time.Date(y, m, d, 0, 0, 0, 0, now.Location()).Add(at)
+	if !next.After(now) {
+		next = time.Date(y, m, d+1, 0, 0, 0, 0, now.Location()).Add(at) // Not Add(24h), some days are 23 or 25 hours
+	}
+	return next
+}
+
+// Daily calls do at closing time every day until ctx is done, with the time it was meant to run. It runs in
+// the caller's goroutine, so start it with go
+func Daily(ctx context.Context, at time.Duration, do func(close time.Time)) {
+	for {
+		next := NextClose(time.Now(), at)
+		t := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+			do(next)
+		}
+	}
+}
+
+// WriteFiles puts the report in dir three times over: name.txt to read, name.json for other programs and
+// name.csv for a spreadsheet. dir is made if it isn't there
+func (r SalesReport) WriteFiles(dir, name string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	var errs []error
+	write := func(ext string, do func(f *os.File) error) {
+		f, err := os.Create(filepath.Join(dir, name+ext))
+		if err != nil {
+			errs = append(errs, err)
+			return
+		}
+		errs = append(errs, do(f), f.Close())
+	}
+	write(".txt", func(f *os.File) error { return r.WriteText(f) })
+	write(".json", func(f *os.File) error { _, err := f.Write(append(b, '\n')); return err })
+	write(".csv", func(f *os.File) error { return r.WriteCSV(f) })
+	return errors.Join(errs...)
+}
@@ -0,0 +1,103 @@
+package coffeeshop
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	menu "demo/coffeeshop/menu"
+	"demo/coffeeshop/order"
+	"demo/coffeeshop/report"
+)
+
+// MARK: Sales
+
+func runSales(args []string) error {
+	fs := newFlags("report sales", "[flags]", "Shows what sold: by item, size, category, hour and day of the week, with the discounts,\n"+
+		"tax and top modifiers. Only orders that were paid for count. Dates are like 2026-10-19, --to takes in the whole day.")
+	file := menuFile(fs)
+	orders := ordersFile(fs)
+	var only listFlag
+	fs.Var(&only, "store", "only this store (repeatable)")
+	from := fs.String("from", "", "first day to count (default the first order)")
+	to := fs.String("to", "", "last day to count (default the last order)")
+	today := fs.Bool("today", false, "just today, the same as --from and --to today")
+	format := fs.String("format", "text", "text, json or csv")
+	out := fs.String("out", "", "write it to this directory as .txt, .json and .csv instead, for a cron job at closing")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return errUsage
+	}
+	if *format != "text" && *format != "json" && *format != "csv" {
+		fmt.Fprintf(fs.Output(), "Unknown format %q, expected text, json or csv\n", *format)
+		return errUsage
+	}
+	f := report.Filter{Stores: only}
+	if *today {
+		f = report.Day(time.Now(), only...)
+	}
+	if err := f.Days(*from, *to); err != nil {
+		fmt.Fprintln(fs.Output(), err)
+		return errUsage
+	}
+	if err := menu.Load(*file); err != nil {
+		return err
+	}
+	placed, err := order.LoadLedger(*orders)
+	if err != nil {
+		return err
+	}
+
+	r := report.Sales(placed, f)
+	switch {
+	case *out != "":
+		name := salesFileName(f.From, f.To, only)
+		if err := r.WriteFiles(*out, name); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Wrote %v.txt, .json and .csv in %v\n", name, *out)
+		return nil
+	case *format == "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case *format == "csv":
+		return r.WriteCSV(os.Stdout)
+	}
+	return r.WriteText(os.Stdout)
+}
+
+// salesFileName is what a sales report's files are called, like sales-2026-10-19 for a day or
+// sales-2026-10-01-to-2026-10-19-downtown for longer at one store
+func salesFileName(from, to time.Time, stores []string) string {
+	name := "sales"
+	last := to.AddDate(0, 0, -1) // to is the start of the day after
+	switch {
+	case !from.IsZero() && !to.IsZero() && last.Equal(from):
+		name += "-" + from.Format(time.DateOnly)
+	case !from.IsZero() && !to.IsZero():
+		name += "-" + from.Format(time.DateOnly) + "-to-" + last.Format(time.DateOnly)
+	case !from.IsZero():
+		name += "-from-" + from.Format(time.DateOnly)
+	case !to.IsZero():
+		name += "-to-" + last.Format(time.DateOnly)
+	}
+	for _, s := range stores {
+		name += "-" + s
+	}
+	return name
+}
+
+// closeDay writes the day's sales into dir, for serve to call at closing time
+func closeDay(ordersFile, dir string, close time.Time) error {
+	placed, err := order.LoadLedger(ordersFile)
+	if err != nil {
+		return err
+	}
+	f := report.Day(close)
+	return report.Sales(placed, f).WriteFiles(dir, salesFileName(f.From, f.To, nil))
+}
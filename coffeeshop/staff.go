@@ -0,0 +1,117 @@
+package coffeeshop
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/user"
+	"strings"
+	"text/tabwriter"
+
+	"demo/coffeeshop/audit"
+	"demo/coffeeshop/auth"
+)
+
+// MARK: Staff
+
+// Every command that changes something shares these
+func usersFile(fs *flag.FlagSet) *string {
+	return fs.String("users", "users.json", "staff accounts file")
+}
+
+func auditFile(fs *flag.FlagSet) *string {
+	return fs.String("audit", "audit.log", "audit trail to append changes to")
+}
+
+// sessionKey signs the HTTP sessions. Set DEMO_SESSION_KEY so sign ins survive a restart, otherwise every run
+// makes up its own key
+func sessionKey() []byte {
+	return []byte(os.Getenv("DEMO_SESSION_KEY"))
+}
+
+// localUser is whoever is running the command. Anyone at this terminal can edit menu.json directly anyway, so
+// there's no point asking for a password, but the audit trail still says who it was
+func localUser() auth.User {
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	return auth.User{Username: "local:" + name, Role: auth.Manager}
+}
+
+// recordChange puts a change made from the command line in the audit trail
+func recordChange(path, action, detail string) error {
+	closeAudit, err := audit.Open(path)
+	if err != nil {
+		return fmt.Errorf("opening the audit trail: %w", err)
+	}
+	return errors.Join(audit.Record(audit.Entry{User: localUser(), Action: action, Detail: detail}), closeAudit())
+}
+
+func runStaff(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: demo staff <list|add> [flags]")
+		return errUsage
+	}
+
+	switch args[0] {
+	case "list":
+		fs := newFlags("staff list", "[flags]", "Lists the staff accounts.")
+		file := usersFile(fs)
+		if err := parse(fs, args[1:]); err != nil {
+			return err
+		}
+		store, err := auth.Open(*file, nil)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tUSERNAME\tROLE")
+		for _, u := range store.Users() {
+			fmt.Fprintf(w, "%v\t%v\t%v\n", u.ID, u.Username, u.Role)
+		}
+		return w.Flush()
+
+	case "add":
+		fs := newFlags("staff add", "--username NAME --role ROLE",
+			"Adds a staff account. The password is read from the first line of stdin so it doesn't end up in your\n"+
+				"shell history.")
+		file := usersFile(fs)
+		trail := auditFile(fs)
+		username := fs.String("username", "", "name to sign in with (required)")
+		role := auth.Barista
+		fs.Func("role", "barista, shift-lead or manager (default barista)", func(s string) (err error) {
+			role, err = auth.ParseRole(s)
+			return err
+		})
+		if err := parse(fs, args[1:]); err != nil {
+			return err
+		}
+		if strings.TrimSpace(*username) == "" {
+			fmt.Fprintln(fs.Output(), "--username is required")
+			fs.Usage()
+			return errUsage
+		}
+		store, err := auth.Open(*file, nil)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(os.Stderr, "Password for %v: ", *username)
+		password, err := in.ReadString('\n')
+		if err != nil && password == "" {
+			return fmt.Errorf("reading password: %w", err)
+		}
+		u, err := store.Add(*username, strings.TrimRight(password, "\r\n"), role)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "\nAdded %v as a %v\n", u, u.Role)
+		return recordChange(*trail, "staff.add", fmt.Sprintf("%v as %v", u, u.Role))
+
+	default:
+		fmt.Fprintf(os.Stderr, "demo staff: unknown command %q\n", args[0])
+		return errUsage
+	}
+}
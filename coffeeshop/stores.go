@@ -0,0 +1,224 @@
+package coffeeshop
+
+import (
+	"cmp"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	menu "demo/coffeeshop/menu"
+	"demo/coffeeshop/money"
+	"demo/coffeeshop/order"
+	"demo/coffeeshop/render"
+	"demo/coffeeshop/report"
+)
+
+// MARK: Stores
+
+// storeFlag picks the store a command works as. Set DEMO_STORE on a shop's till so it doesn't need saying every time
+func storeFlag(fs *flag.FlagSet) *string {
+	return fs.String("store", os.Getenv("DEMO_STORE"), "store to work as, for its prices and what it sells (default $DEMO_STORE, or the base menu)")
+}
+
+// ordersFile is the ledger orders are kept in for the reports
+func ordersFile(fs *flag.FlagSet) *string {
+	return fs.String("orders", "orders.jsonl", "ledger file placed orders are added to")
+}
+
+// inStore is r writing prices the way a store does, once the menu is loaded. It fails for a store we don't have
+func inStore(r render.Renderer, store string) (render.Renderer, error) {
+	f, err := menu.Money(store)
+	return render.WithMoney(r, f), err
+}
+
+// checkStore makes sure the store from --store exists, once the menu is loaded
+func checkStore(id string) error {
+	_, err := menu.LookupStore(id)
+	return err
+}
+
+func runStore(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: demo store <list|add|price|currency|sell|stop-selling|sold-out|back-in> [flags]")
+		return errUsage
+	}
+
+	// Every change is the same shape: load, change, save, audit
+	reset := false // store price --reset and store currency --reset
+	var locale string
+	var rate float64 // store currency --locale and --rate
+	edit := func(name, usage, about string, nargs int, change func(fs *flag.FlagSet) (string, error)) error {
+		fs := newFlags("store "+name, usage, about)
+		file := menuFile(fs)
+		trail := auditFile(fs)
+		switch name {
+		case "price":
+			fs.BoolVar(&reset, "reset", false, "go back to the base price, with PRICE left out")
+		case "currency":
+			fs.BoolVar(&reset, "reset", false, "go back to the base menu's currency, with CURRENCY left out")
+			fs.StringVar(&locale, "locale", "", "how prices are written, one of "+strings.Join(money.Locales(), ", ")+" (default the currency's usual)")
+			fs.Float64Var(&rate, "rate", 0, "how much of CURRENCY one of the base currency is, for converting base prices")
+		}
+		if err := parse(fs, args[1:]); err != nil {
+			return err
+		}
+		if reset {
+			nargs--
+		}
+		if fs.NArg() != nargs {
+			fs.Usage()
+			return errUsage
+		}
+		if err := menu.Load(*file); err != nil {
+			return err
+		}
+		detail, err := change(fs)
+		if err != nil {
+			return err
+		}
+		fmt.Println(detail)
+		if err := menu.Save(*file); err != nil {
+			return err
+		}
+		return recordChange(*trail, "store."+name, detail)
+	}
+
+	switch args[0] {
+	case "list":
+		fs := newFlags("store list", "[flags]", "Lists the stores and how their menus differ from the base menu.")
+		file := menuFile(fs)
+		if err := parse(fs, args[1:]); err != nil {
+			return err
+		}
+		if err := menu.Load(*file); err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tCURRENCY\tPRICES\tNOT SOLD\tSOLD OUT")
+		for _, s := range menu.Stores() {
+			var prices, notSold, soldOut int
+			for _, o := range s.Items {
+				prices += len(o.Prices)
+				notSold += boolCount(o.NotSold)
+				soldOut += boolCount(o.SoldOut)
+			}
+			f, _ := menu.Money(s.ID)
+			cur := cmp.Or(f.String(), "-")
+			if s.Rate > 0 {
+				cur += fmt.Sprintf(" at %v", s.Rate)
+			}
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\n", s.ID, s.Name, cur, prices, notSold, soldOut)
+		}
+		return w.Flush()
+
+	case "add":
+		return edit("add", "[flags] ID NAME", "Opens a store. It sells the whole base menu at base prices to start with.", 2,
+			func(fs *flag.FlagSet) (string, error) {
+				return fmt.Sprintf("%v (%v)", fs.Arg(1), fs.Arg(0)), menu.AddStore(fs.Arg(0), fs.Arg(1))
+			})
+
+	case "price":
+		return edit("price", "[flags] STORE ITEM SIZE [PRICE]", "Sets what a size of an item costs at one store.", 4,
+			func(fs *flag.FlagSet) (string, error) {
+				store, item, size := fs.Arg(0), fs.Arg(1), fs.Arg(2)
+				if reset {
+					return fmt.Sprintf("%v: %v %v back to the base price", store, item, size), menu.ClearStorePrice(store, item, size)
+				}
+				price, err := strconv.ParseFloat(fs.Arg(3), 64)
+				if err != nil {
+					fmt.Fprintf(fs.Output(), "%q is not a price\n", fs.Arg(3))
+					return "", errUsage
+				}
+				old, err := menu.SetStorePrice(store, item, size, price)
+				f, _ := menu.Money(store)
+				return fmt.Sprintf("%v: %v %v: %v -> %v", store, item, size, f.Amount(old), f.Amount(price)), err
+			})
+
+	case "currency":
+		return edit("currency", "[flags] STORE CURRENCY",
+			"Sets what a store prices in and how it writes prices. Base prices are converted at --rate, the store's\n"+
+				"own prices have to be put back first. Currencies: "+strings.Join(money.Currencies(), ", "), 2,
+			func(fs *flag.FlagSet) (string, error) {
+				store, code := fs.Arg(0), fs.Arg(1)
+				if err := menu.SetStoreCurrency(store, code, locale, rate); err != nil {
+					return "", err
+				}
+				f, _ := menu.Money(store)
+				return fmt.Sprintf("%v: prices in %v, like %v", store, cmp.Or(f.String(), "plain numbers"), f.Amount(1234.5)), nil
+			})
+
+	case "sell", "stop-selling", "sold-out", "back-in":
+		about := map[string]string{
+			"sell":         "Puts an item back on one store's menu.",
+			"stop-selling": "Takes an item off one store's menu, the other stores still sell it.",
+			"sold-out":     "Marks an item as run out at one store.",
+			"back-in":      "Marks an item as back in stock at one store.",
+		}[args[0]]
+		return edit(args[0], "[flags] STORE ITEM", about, 2, func(fs *flag.FlagSet) (string, error) {
+			store, item := fs.Arg(0), fs.Arg(1)
+			detail := fmt.Sprintf("%v: %v", store, item)
+			switch args[0] {
+			case "sell", "stop-selling":
+				return detail, menu.SetSoldAt(store, item, args[0] == "sell")
+			default:
+				return detail, menu.SetSoldOutAt(store, item, args[0] == "sold-out")
+			}
+		})
+
+	default:
+		fmt.Fprintf(os.Stderr, "demo store: unknown command %q\n", args[0])
+		return errUsage
+	}
+}
+
+func boolCount(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// MARK: Reports
+
+func runReport(args []string) error {
+	if len(args) > 0 && args[0] == "sales" {
+		return runSales(args[1:])
+	}
+	fs := newFlags("report", "[flags]", "Adds up the paid orders in the ledger, for each store and for all of them. demo report sales\n"+
+		"has what sold in more detail.")
+	file := menuFile(fs)
+	orders := ordersFile(fs)
+	var only listFlag
+	fs.Var(&only, "store", "only this store (repeatable)")
+	format := fs.String("format", "text", "text or json")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(fs.Output(), "Unknown format %q, expected text or json\n", *format)
+		return errUsage
+	}
+	if err := menu.Load(*file); err != nil {
+		return err
+	}
+	placed, err := order.LoadLedger(*orders)
+	if err != nil {
+		return err
+	}
+
+	r := report.Stores(placed, only...)
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	}
+	names := map[string]string{}
+	for _, s := range menu.Stores() {
+		names[s.ID] = s.Name
+	}
+	return r.WriteText(os.Stdout, names)
+}
@@ -0,0 +1,118 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"demo/coffeeshop/audit"
+	"demo/coffeeshop/auth"
+)
+
+// MARK: Sign In
+
+const sessionCookie = "session"
+
+type ctxKey int
+
+const userKey ctxKey = iota
+
+// CurrentUser is who signed the request, set by require
+func CurrentUser(ctx context.Context) (auth.User, bool) {
+	u, ok := ctx.Value(userKey).(auth.User)
+	return u, ok
+}
+
+// login swaps a username and password for a session. The token comes back in the body for API clients, who send it
+// as "Authorization: Bearer ...", and as a cookie for browsers
+func (s *server) login(w http.ResponseWriter, r *http.Request) {
+	var creds struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4<<10)).Decode(&creds); err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Errorf("reading credentials: %w", err), RequestID(r.Context()))
+		return
+	}
+	u, err := s.users.Login(creds.Username, creds.Password)
+	if err != nil {
+		if !errors.Is(err, auth.ErrBadLogin) {
+			s.logger.ErrorContext(r.Context(), "login failed", "error", err)
+		}
+		writeJSONError(w, http.StatusUnauthorized, auth.ErrBadLogin, RequestID(r.Context()))
+		return
+	}
+
+	token, expires := s.users.Issue(u, s.sessionTTL)
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,                    // Scripts on the page can't read it
+		Secure:   r.TLS != nil,            // Only over HTTPS, when we're serving HTTPS
+		SameSite: http.SameSiteStrictMode, // Other sites can't make a signed in browser post to us
+	})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Token   string    `json:"token"`
+		Expires time.Time `json:"expires"`
+		User    auth.User `json:"user"`
+	}{token, expires, u})
+}
+
+// logout clears the cookie. Tokens aren't kept anywhere, so a copied token still works until it expires
+func (s *server) logout(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Path: "/", MaxAge: -1, HttpOnly: true, SameSite: http.SameSiteStrictMode})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// require only lets the request through if it's signed by someone whose role allows p
+func (s *server) require(p auth.Permission, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r, ok := s.allowed(w, r, p); ok {
+			next(w, r)
+		}
+	}
+}
+
+// allowed is require for a handler that only sometimes needs p, like an order with a discount. It answers 401 or
+// 403 itself when the user can't, or gives back r with the user in its context
+func (s *server) allowed(w http.ResponseWriter, r *http.Request, p auth.Permission) (*http.Request, bool) {
+	id := RequestID(r.Context())
+	u, err := s.users.Verify(sessionToken(r))
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="coffeeshop"`)
+		writeJSONError(w, http.StatusUnauthorized, err, id)
+		return r, false
+	}
+	if !u.Can(p) {
+		writeJSONError(w, http.StatusForbidden, fmt.Errorf("a %v can't %v", u.Role, p), id)
+		return r, false
+	}
+	return r.WithContext(context.WithValue(r.Context(), userKey, u)), true
+}
+
+// sessionToken takes the token from the Authorization header, or the cookie if there isn't one
+func sessionToken(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	if c, err := r.Cookie(sessionCookie); err == nil {
+		return c.Value
+	}
+	return ""
+}
+
+// record puts a change made over HTTP in the audit trail
+func (s *server) record(r *http.Request, action, detail string) {
+	u, _ := CurrentUser(r.Context())
+	e := audit.Entry{User: u, Action: action, Detail: detail, RequestID: RequestID(r.Context())}
+	if err := audit.Record(e); err != nil {
+		s.logger.ErrorContext(r.Context(), "writing the audit trail", "action", action, "error", err)
+	}
+}
@@ -0,0 +1,244 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"demo/coffeeshop/audit"
+	"demo/coffeeshop/auth"
+	"demo/coffeeshop/order"
+	"demo/coffeeshop/payment"
+)
+
+func TestEditsNeedRole(t *testing.T) {
+	users := auth.NewStore(nil)
+	users.Add("bea", "barista pass", auth.Barista)
+	users.Add("max", "manager pass", auth.Manager)
+	var trail bytes.Buffer
+	audit.SetOutput(&trail)
+	defer audit.SetOutput(io.Discard)
+
+	s := &server{logger: slog.New(slog.NewTextHandler(io.Discard, nil)), done: make(chan struct{}), users: users, sessionTTL: time.Hour}
+	h := s.handler()
+	do := func(method, url, token, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	login := func(username, password string) string {
+		t.Helper()
+		rec := do("POST", "/login", "", `{"username":"`+username+`","password":"`+password+`"}`)
+		var body struct{ Token string }
+		json.NewDecoder(rec.Body).Decode(&body)
+		if rec.Code != http.StatusOK || body.Token == "" {
+			t.Fatalf("Login as %v: got %v", username, rec.Code)
+		}
+		return body.Token
+	}
+
+	item := `{"name":"Audit Brew","prices":{"small":2.5},"category":"coffee"}`
+	if rec := do("POST", "/items", "", item); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Nobody signed in: got %v, expected 401", rec.Code)
+	}
+	if rec := do("POST", "/login", "", `{"username":"bea","password":"nope"}`); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Wrong password: got %v, expected 401", rec.Code)
+	}
+	if rec := do("POST", "/items", login("bea", "barista pass"), item); rec.Code != http.StatusForbidden {
+		t.Errorf("Barista: got %v, expected 403", rec.Code)
+	}
+
+	manager := login("max", "manager pass")
+	if rec := do("POST", "/items", manager, item); rec.Code != http.StatusCreated {
+		t.Errorf("Manager: got %v, expected 201: %v", rec.Code, rec.Body)
+	}
+	if rec := do("PUT", "/items/Audit%20Brew/prices/small", manager, `{"price":2.75}`); rec.Code != http.StatusOK {
+		t.Errorf("Price change: got %v, expected 200: %v", rec.Code, rec.Body)
+	}
+
+	var entries []audit.Entry
+	for _, line := range strings.Split(strings.TrimSpace(trail.String()), "\n") {
+		var e audit.Entry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, e)
+	}
+	if len(entries) != 2 || entries[0].Action != "menu.add" || entries[1].Action != "menu.price" {
+		t.Fatalf("Got audit trail %v, expected an add and a price change", trail.String())
+	}
+	if entries[1].User.Username != "max" || entries[1].Detail != "Audit Brew small: 2.50 -> 2.75" {
+		t.Errorf("Got %+v", entries[1])
+	}
+
+	// A CSV upload changes prices too, and a preview says so without doing it
+	upload := "name,size,price\nAudit Brew,small,3.00\nAudit Brew,large,oops\n"
+	rec := do("POST", "/menu.csv?dry_run=true", manager, upload)
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), `"line":3`) {
+		t.Errorf("Bad CSV: got %v %v, expected 422 with the line", rec.Code, rec.Body)
+	}
+	rec = do("POST", "/menu.csv?dry_run=true", manager, upload[:strings.LastIndex(upload, "Audit")])
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"applied":false`) || !strings.Contains(rec.Body.String(), "2.75 -> 3.00") {
+		t.Errorf("Dry run: got %v %v", rec.Code, rec.Body)
+	}
+}
+
+func TestDrawerRoutes(t *testing.T) {
+	users := auth.NewStore(nil)
+	users.Add("bea", "barista pass", auth.Barista)
+	users.Add("lee", "lead pass", auth.ShiftLead)
+	till, _ := payment.OpenTill("")
+	s := &server{logger: slog.New(slog.NewTextHandler(io.Discard, nil)), done: make(chan struct{}), users: users,
+		sessionTTL: time.Hour, till: till}
+	h := s.handler()
+	do := func(method, url, username, password, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		u, err := users.Login(username, password)
+		if err != nil {
+			t.Fatal(err)
+		}
+		token, _ := users.Issue(u, time.Hour)
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do("POST", "/drawer/open", "bea", "barista pass", `{"float":20}`); rec.Code != http.StatusForbidden {
+		t.Errorf("Barista: got %v, expected 403", rec.Code)
+	}
+	if rec := do("POST", "/drawer/open", "lee", "lead pass", `{"float":20}`); rec.Code != http.StatusOK {
+		t.Errorf("Open: got %v %v", rec.Code, rec.Body)
+	}
+	if rec := do("POST", "/drawer/pay-outs", "lee", "lead pass", `{"amount":2.5}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Pay-out without a reason: got %v %v", rec.Code, rec.Body)
+	}
+	do("POST", "/drawer/pay-outs", "lee", "lead pass", `{"amount":2.5,"reason":"milk"}`)
+	rec := do("POST", "/drawer/close?format=text", "lee", "lead pass", `{"counted":[{"value":10,"count":1},{"value":5,"count":1},{"value":1,"count":2},{"value":0.25,"count":2}]}`)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "BALANCED") {
+		t.Errorf("Close: got %v\n%v", rec.Code, rec.Body)
+	}
+	if rec := do("GET", "/reports/drawers?format=csv", "lee", "lead pass", ""); !strings.Contains(rec.Body.String(), ",17.50,17.50,0.00,balanced") {
+		t.Errorf("Report: got %v", rec.Body)
+	}
+}
+
+func TestDiscountsAndSales(t *testing.T) {
+	ledger := filepath.Join(t.TempDir(), "orders.jsonl")
+	closeLedger, err := order.OpenLedger(ledger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeLedger()
+	users := auth.NewStore(nil)
+	users.Add("bea", "barista pass", auth.Barista)
+	users.Add("lee", "lead pass", auth.ShiftLead)
+	till, _ := payment.OpenTill("")
+	s := &server{logger: slog.New(slog.NewTextHandler(io.Discard, nil)), done: make(chan struct{}), users: users,
+		sessionTTL: time.Hour, ordersFile: ledger, till: till}
+	h := s.handler()
+	do := func(method, url, username, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Accept", "application/json")
+		if username != "" {
+			u, _ := users.Login(username, map[string]string{"bea": "barista pass", "lee": "lead pass"}[username])
+			token, _ := users.Issue(u, time.Hour)
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	card := `"payments":[{"method":"card","card":"` + payment.CardApproved + `"}]`
+	discounted := `{"lines":[{"item":"Coffee","size":"large","qty":2}],"discount":0.9,` + card + `}`
+	if rec := do("POST", "/orders", "", discounted); rec.Code != http.StatusUnauthorized {
+		t.Errorf("No one: got %v", rec.Code)
+	}
+	if rec := do("POST", "/orders", "bea", discounted); rec.Code != http.StatusForbidden {
+		t.Errorf("Barista: got %v", rec.Code)
+	}
+	if rec := do("POST", "/orders", "lee", `{"lines":[{"item":"Coffee","size":"large"}],"discount":5}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("More than the order: got %v %v", rec.Code, rec.Body)
+	}
+	if rec := do("POST", "/orders", "lee", discounted); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"total": 3,`) {
+		t.Errorf("Shift lead: got %v %v", rec.Code, rec.Body)
+	}
+	do("POST", "/orders", "", `{"lines":[{"item":"Espresso","size":"double","modifiers":["extra shot"]}],`+card+`}`)
+	do("POST", "/orders", "", `{"lines":[{"item":"Espresso","size":"triple","qty":9}]}`) // Never paid for, so not a sale
+
+	if rec := do("GET", "/reports/sales", "bea", ""); rec.Code != http.StatusForbidden {
+		t.Errorf("Barista's report: got %v", rec.Code)
+	}
+	rec := do("GET", "/reports/sales", "lee", "")
+	var sales struct {
+		Currencies []struct {
+			Orders    int
+			Discounts float64
+			Net       float64
+			ByItem    []struct{ Name string }
+		}
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &sales); err != nil || len(sales.Currencies) != 1 {
+		t.Fatalf("Got %v %v", rec.Code, rec.Body)
+	}
+	if s := sales.Currencies[0]; s.Orders != 2 || s.Discounts != 0.9 || s.Net != 6 || len(s.ByItem) != 2 || s.ByItem[0].Name != "Coffee" {
+		t.Errorf("Got %+v", s)
+	}
+	if rec := do("GET", "/reports/sales?format=csv&from=2000-01-01&to=2000-01-01", "lee", ""); strings.Count(rec.Body.String(), "\n") != 1 {
+		t.Errorf("Nothing sold in 2000: got %v", rec.Body)
+	}
+	if rec := do("GET", "/reports/sales?from=yesterday", "lee", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("Bad date: got %v", rec.Code)
+	}
+}
+
+func TestOrderReady(t *testing.T) {
+	users := auth.NewStore(nil)
+	users.Add("bea", "barista pass", auth.Barista)
+	s := &server{logger: slog.New(slog.NewTextHandler(io.Discard, nil)), done: make(chan struct{}), users: users, sessionTTL: time.Hour}
+	h := s.handler()
+	do := func(method, url, token, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	var login struct{ Token string }
+	json.NewDecoder(do("POST", "/login", "", `{"username":"bea","password":"barista pass"}`).Body).Decode(&login)
+	token := login.Token
+
+	placed := do("POST", "/orders", "", `{"lines":[{"item":"Coffee","size":"large"}]}`)
+	ready := "/orders/" + placed.Header().Get("X-Order-ID") + "/ready"
+	if rec := do("POST", ready, "", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Nobody signed in: got %v, expected 401", rec.Code)
+	}
+	if rec := do("POST", ready, token, ""); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"prepSeconds"`) {
+		t.Errorf("Ready: got %v %v", rec.Code, rec.Body)
+	}
+	if rec := do("POST", ready, token, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Ready twice: got %v, expected 404", rec.Code)
+	}
+	if rec := do("GET", "/metrics", "", ""); !strings.Contains(rec.Body.String(), "coffeeshop_prep_seconds_count{store=\"\"} ") ||
+		!strings.Contains(rec.Body.String(), "# TYPE coffeeshop_queue_depth gauge") {
+		t.Errorf("Metrics without the queue: %v", rec.Body)
+	}
+}
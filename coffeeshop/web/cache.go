@@ -0,0 +1,70 @@
+package web
+
+import (
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	menu "demo/coffeeshop/menu"
+)
+
+// MARK: Caching
+
+// The menu doesn't change often, but when it does we want clients to notice within a minute
+const cacheControl = "public, max-age=60"
+
+// notModified sets the caching headers for a response built from the current menu in the given content type and
+// language. If the client's copy is still current it answers 304 and returns true, and the caller has nothing
+// left to do
+func notModified(w http.ResponseWriter, r *http.Request, contentType, lang string) bool {
+	version, modified := menu.Version()
+	tag := etag(version, contentType+"; lang="+lang)
+
+	h := w.Header()
+	h.Set("ETag", tag)
+	h.Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	h.Set("Cache-Control", cacheControl)
+	h.Set("Content-Language", lang)
+	h.Add("Vary", "Accept, Accept-Language") // The same URL gives different bodies depending on those headers
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	// If-None-Match wins when both are sent (RFC 9110 section 13.2.2)
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if !matchesETag(inm, tag) {
+			return false
+		}
+	} else if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		t, err := http.ParseTime(ims)
+		if err != nil || modified.Truncate(time.Second).After(t) { // The header only has whole seconds
+			return false
+		}
+	} else {
+		return false
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// etag is a strong validator: the menu version plus the format and language, since JSON and HTML (or English and
+// French) of the same menu are different bytes
+func etag(version, variant string) string {
+	h := fnv.New32a()
+	h.Write([]byte(variant))
+	return `"` + version + "-" + strconv.FormatUint(uint64(h.Sum32()), 36) + `"`
+}
+
+// matchesETag checks an If-None-Match list. That comparison is the weak one, so W/ prefixes are ignored
+func matchesETag(header, tag string) bool {
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || strings.TrimPrefix(t, "W/") == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	menu "demo/coffeeshop/menu"
+)
+
+// MARK: CSV
+
+// exportCSV downloads the whole menu as a spreadsheet, GET /menu.csv
+func exportCSV(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="menu.csv"`)
+	if err := menu.WriteCSV(w); err != nil {
+		slog.ErrorContext(r.Context(), "writing the menu CSV", "error", err)
+	}
+}
+
+// importResult is the answer to an upload. Errors has the line each problem is on, so a spreadsheet can be
+// fixed without guessing
+type importResult struct {
+	Applied bool          `json:"applied"`
+	Changes []menu.Change `json:"changes"`
+	Errors  []lineError   `json:"errors,omitempty"`
+}
+
+type lineError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// importCSV uploads a CSV file, POST /menu.csv with the file as the body. With ?dry_run=true it only says what
+// would change. Nothing is applied unless every line is right
+func (s *server) importCSV(w http.ResponseWriter, r *http.Request) {
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+	imp, err := menu.ReadCSV(http.MaxBytesReader(w, r.Body, 1<<20))
+	var ie *menu.ImportError
+	if errors.As(err, &ie) {
+		result := importResult{Changes: []menu.Change{}}
+		for _, l := range ie.Lines {
+			result.Errors = append(result.Errors, lineError{l.Line, l.Err.Error()})
+		}
+		writeJSON(w, http.StatusUnprocessableEntity, result)
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	result := importResult{Changes: imp.Changes}
+	if result.Changes == nil {
+		result.Changes = []menu.Change{}
+	}
+	if !dryRun && len(imp.Changes) > 0 {
+		if err := imp.Apply(); err != nil {
+			writeError(w, r, err)
+			return
+		}
+		result.Applied = true
+		if err := s.save(); err != nil {
+			s.logger.ErrorContext(r.Context(), "saving the menu", "error", err)
+		}
+		s.record(r, "menu.import", fmt.Sprintf("%v items changed from a CSV upload", len(imp.Changes)))
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false) // The changes have arrows in them
+	enc.Encode(v)
+}
@@ -0,0 +1,146 @@
+package web
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	menu "demo/coffeeshop/menu"
+	"demo/coffeeshop/payment"
+	"demo/coffeeshop/report"
+)
+
+// MARK: Drawer
+
+// The drawer routes all take ?store=, the base menu's drawer without it. They're here as well as on the command
+// line because the server keeps the till in memory, a change to the file while it's running would be lost
+
+// drawer is the store's cash drawer, or an answer saying why there isn't one
+func (s *server) drawer(w http.ResponseWriter, r *http.Request) (*payment.Cash, string, bool) {
+	if s.till == nil {
+		writeJSONError(w, http.StatusNotFound, fmt.Errorf("this server doesn't take payments"), RequestID(r.Context()))
+		return nil, "", false
+	}
+	store := r.URL.Query().Get("store")
+	f, err := menu.Money(store)
+	if err != nil {
+		writeError(w, r, err)
+		return nil, "", false
+	}
+	return s.till.Cash(store, f.Currency), store, true
+}
+
+// drawerStatus is what should be in the drawer now, GET /drawer
+func (s *server) drawerStatus(w http.ResponseWriter, r *http.Request) {
+	cash, _, ok := s.drawer(w, r)
+	if !ok {
+		return
+	}
+	session, open := cash.Session()
+	if !open {
+		writeError(w, r, payment.ErrDrawerClosed)
+		return
+	}
+	writeJSON(w, http.StatusOK, report.Drawer(session))
+}
+
+// drawerMove opens the drawer or pays in or out of it: POST /drawer/open with {"float": 100}, and
+// POST /drawer/pay-ins or /drawer/pay-outs with {"amount": 4.5, "reason": "milk"}
+func (s *server) drawerMove(w http.ResponseWriter, r *http.Request) {
+	cash, store, ok := s.drawer(w, r)
+	if !ok {
+		return
+	}
+	var body struct {
+		Float  float64 `json:"float"`
+		Amount float64 `json:"amount"`
+		Reason string  `json:"reason"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4<<10)).Decode(&body); err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Errorf("reading body: %w", err), RequestID(r.Context()))
+		return
+	}
+	u, _ := CurrentUser(r.Context())
+	var err error
+	var action, detail string
+	switch r.PathValue("move") {
+	case "open":
+		action, detail = "drawer.open", fmt.Sprintf("%v with %v", store, body.Float)
+		_, err = cash.Open(body.Float, u.Username)
+	case "pay-ins":
+		action, detail = "drawer.pay-in", fmt.Sprintf("%v %v: %v", store, body.Amount, body.Reason)
+		err = cash.PayIn(body.Amount, body.Reason, u.Username)
+	case "pay-outs":
+		action, detail = "drawer.pay-out", fmt.Sprintf("%v %v: %v", store, body.Amount, body.Reason)
+		err = cash.PayOut(body.Amount, body.Reason, u.Username)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	s.saveTill(r)
+	s.record(r, action, detail)
+	s.drawerStatus(w, r)
+}
+
+// closeDrawer counts the drawer, POST /drawer/close with {"counted": [{"value": 20, "count": 3}, ...]}. It
+// answers with the variance, or ?format=text for the receipt printer
+func (s *server) closeDrawer(w http.ResponseWriter, r *http.Request) {
+	cash, store, ok := s.drawer(w, r)
+	if !ok {
+		return
+	}
+	var body struct {
+		Counted []payment.Piece `json:"counted"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 16<<10)).Decode(&body); err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Errorf("reading the count: %w", err), RequestID(r.Context()))
+		return
+	}
+	u, _ := CurrentUser(r.Context())
+	session, err := cash.Close(body.Counted, u.Username)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	s.saveTill(r)
+	v := report.Drawer(session)
+	s.record(r, "drawer.close", fmt.Sprintf("%v %v counted %v, %v %v", store, session.ID, v.Counted, v.Status(), v.Variance))
+	if r.URL.Query().Get("format") == "text" {
+		st, _ := menu.LookupStore(store)
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		v.WriteText(w, cmp.Or(st.Name, "(no store)"))
+		return
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+// drawersReport is the variance of every closed drawer session at a store, GET /reports/drawers.
+// ?format=csv gives a spreadsheet instead of JSON
+func (s *server) drawersReport(w http.ResponseWriter, r *http.Request) {
+	cash, _, ok := s.drawer(w, r)
+	if !ok {
+		return
+	}
+	vs := []report.Variance{}
+	for _, session := range cash.History() {
+		vs = append(vs, report.Drawer(session))
+	}
+	if r.URL.Query().Get("format") == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		report.WriteDrawersCSV(w, vs)
+		return
+	}
+	writeJSON(w, http.StatusOK, vs)
+}
+
+// saveTill keeps the till's file up to date. The change has happened either way, so a failure is only logged
+func (s *server) saveTill(r *http.Request) {
+	if err := s.till.Save(); err != nil {
+		s.logger.ErrorContext(r.Context(), "saving the till", "error", err)
+	}
+}
@@ -0,0 +1,114 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	menu "demo/coffeeshop/menu"
+)
+
+// MARK: Menu Changes
+
+// newItem is the body of POST /items, the same shape as an item in menu.json
+type newItem struct {
+	Name      string                    `json:"name"`
+	Prices    map[string]float64        `json:"prices"`
+	Category  string                    `json:"category"`
+	Tags      []string                  `json:"tags"`
+	SoldOut   bool                      `json:"soldOut"`
+	Allergens menu.Allergen             `json:"allergens"`
+	Diet      menu.Diet                 `json:"diet"`
+	Nutrition map[string]menu.Nutrition `json:"nutrition"`
+}
+
+// addItem puts a new item on the menu, POST /items
+func (s *server) addItem(w http.ResponseWriter, r *http.Request) {
+	id := RequestID(r.Context())
+	var body newItem
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&body); err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Errorf("reading item: %w", err), id)
+		return
+	}
+	d := menu.Details{
+		Category:  body.Category,
+		Tags:      body.Tags,
+		SoldOut:   body.SoldOut,
+		Allergens: body.Allergens,
+		Diet:      body.Diet,
+		Nutrition: body.Nutrition,
+	}
+	if err := menu.Add(body.Name, body.Prices, d); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := s.save(); err != nil {
+		s.logger.ErrorContext(r.Context(), "saving the menu", "error", err)
+	}
+	item, _ := menu.Lookup(body.Name)
+	s.record(r, "menu.add", fmt.Sprintf("%v (%v)", item.Name, item.ID))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/items/"+item.ID)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(item)
+}
+
+// setPrice changes the price of one size, PUT /items/{item}/prices/{size} with {"price": 3.5}
+func (s *server) setPrice(w http.ResponseWriter, r *http.Request) {
+	id := RequestID(r.Context())
+	var body struct {
+		Price float64 `json:"price"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4<<10)).Decode(&body); err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Errorf("reading price: %w", err), id)
+		return
+	}
+	name, size := r.PathValue("item"), r.PathValue("size")
+	old, err := menu.SetPrice(name, size, body.Price)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := s.save(); err != nil {
+		s.logger.ErrorContext(r.Context(), "saving the menu", "error", err)
+	}
+	s.record(r, "menu.price", fmt.Sprintf("%v %v: %.2f -> %.2f", name, size, old, body.Price))
+
+	item, _ := menu.Lookup(name)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(item)
+}
+
+// rename changes an item's name, PUT /items/{item}/name with {"name": "Flat White"}. The ID doesn't change, so
+// links and past orders still find it
+func (s *server) rename(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4<<10)).Decode(&body); err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Errorf("reading name: %w", err), RequestID(r.Context()))
+		return
+	}
+	old, err := menu.Rename(r.PathValue("item"), body.Name)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := s.save(); err != nil {
+		s.logger.ErrorContext(r.Context(), "saving the menu", "error", err)
+	}
+	item, _ := menu.Lookup(body.Name)
+	s.record(r, "menu.rename", fmt.Sprintf("%v: %v -> %v", item.ID, old, item.Name))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(item)
+}
+
+// save writes the menu back to its file, if we were given one
+func (s *server) save() error {
+	if s.menuFile == "" {
+		return nil
+	}
+	return menu.Save(s.menuFile)
+}
@@ -0,0 +1,54 @@
+package web
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	menu "demo/coffeeshop/menu"
+	"demo/coffeeshop/order"
+	"demo/coffeeshop/payment"
+)
+
+// MARK: Errors
+
+// statusFor picks the HTTP status for an error from the menu or a payment. Anything it doesn't know is our fault
+func statusFor(err error) int {
+	var ve *menu.ValidationError
+	var ie *menu.ImportError
+	switch { // The sentinels first, a duplicate name is a conflict even when it comes inside a ValidationError
+	case errors.As(err, &ie): // Except in a file, where whatever's inside it is something to fix in the file
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, menu.ErrItemNotFound), errors.Is(err, menu.ErrStoreNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, menu.ErrDuplicateItem), errors.Is(err, menu.ErrUnavailable), errors.Is(err, menu.ErrMenuChanged):
+		return http.StatusConflict
+	case errors.As(err, &ve):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, payment.ErrDeclined):
+		return http.StatusPaymentRequired
+	case errors.Is(err, payment.ErrTimeout):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, payment.ErrKeyReused), errors.Is(err, payment.ErrNoChange), errors.Is(err, payment.ErrDrawerClosed),
+		errors.Is(err, payment.ErrDrawerOpen):
+		return http.StatusConflict
+	case errors.Is(err, payment.ErrInvalidCard), errors.Is(err, payment.ErrUnknownMethod), errors.Is(err, payment.ErrNotEnough),
+		errors.Is(err, payment.ErrOverRefund), errors.Is(err, payment.ErrBadAmount), errors.Is(err, payment.ErrNoReason),
+		errors.Is(err, payment.ErrNotCash), errors.Is(err, order.ErrOverpaid), errors.Is(err, order.ErrUnderpaid),
+		errors.Is(err, order.ErrBadDiscount):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeError answers with the status that fits err. The client only gets the message for errors they can do
+// something about, for the rest it goes in the log and they get the request ID to quote
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	status := statusFor(err)
+	if status == http.StatusInternalServerError {
+		slog.ErrorContext(r.Context(), "request failed", "method", r.Method, "path", r.URL.Path, "error", err)
+		err = errors.New("internal server error")
+	}
+	writeJSONError(w, status, err, RequestID(r.Context()))
+}
@@ -0,0 +1,61 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	menu "demo/coffeeshop/menu"
+)
+
+// MARK: Events
+
+// How often an event stream checks for a new menu, and how often it says something anyway so proxies
+// don't think the connection is dead
+const (
+	eventPoll      = time.Second
+	eventHeartbeat = 15 * time.Second
+)
+
+// events is a server-sent event stream that sends a "menu" event with the new version whenever the menu
+// changes. Screens in the shop use it to know when to fetch the menu again
+func (s *server) events(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{}) // The server's WriteTimeout would cut the stream off, it's meant to stay open
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	last, _ := menu.Version()
+	fmt.Fprintf(w, "event: menu\ndata: %v\n\n", last)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	poll := time.NewTicker(eventPoll)
+	defer poll.Stop()
+	quiet := time.Now()
+	for {
+		select {
+		case <-r.Context().Done():
+			return // Client went away
+		case <-s.done:
+			fmt.Fprint(w, "event: shutdown\ndata: bye\n\n") // Tell the client to reconnect somewhere else
+			rc.Flush()
+			return
+		case <-poll.C:
+		}
+
+		if v, _ := menu.Version(); v != last {
+			last = v
+			fmt.Fprintf(w, "event: menu\ndata: %v\n\n", v)
+		} else if time.Since(quiet) < eventHeartbeat {
+			continue
+		} else {
+			fmt.Fprint(w, ": still here\n\n") // Lines starting with : are comments, clients ignore them
+		}
+		quiet = time.Now()
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package web
+
+import (
+	"net/http"
+	"runtime"
+	"strconv"
+	"time"
+
+	menu "demo/coffeeshop/menu"
+	"demo/coffeeshop/metrics"
+)
+
+// MARK: Metrics
+
+var (
+	httpRequests = metrics.NewCounter("coffeeshop_http_requests_total",
+		"HTTP requests answered, by route and status code.", "method", "route", "code")
+	httpDuration = metrics.NewHistogram("coffeeshop_http_request_duration_seconds",
+		"How long HTTP requests took to answer.", metrics.DefBuckets, "method", "route")
+	httpInFlight = metrics.NewGauge("coffeeshop_http_requests_in_flight",
+		"HTTP requests being answered right now, event streams included.")
+	rateLimited = metrics.NewCounter("coffeeshop_http_rate_limited_total",
+		"Requests turned away with a 429, by route group.", "group")
+)
+
+func init() {
+	metrics.NewGaugeFunc("coffeeshop_menu_items", "Items on the menu.", func() float64 {
+		return float64(len(menu.Items()))
+	})
+	metrics.NewGaugeFunc("go_goroutines", "Goroutines that currently exist.", func() float64 {
+		return float64(runtime.NumGoroutine())
+	})
+}
+
+// instrument counts and times every request. The route is the mux pattern it matched (/items/{item}), not the
+// path, otherwise every item would be its own series
+func instrument(mux *http.ServeMux) middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, route := mux.Handler(r)
+			if route == "" {
+				route = "unmatched"
+			}
+			rec, ok := w.(*recorder)
+			if !ok {
+				rec = &recorder{ResponseWriter: w}
+			}
+
+			httpInFlight.Inc()
+			start := time.Now()
+			defer func() {
+				httpInFlight.Dec()
+				if rec.status == 0 {
+					rec.status = http.StatusOK
+				}
+				httpRequests.Inc(r.Method, route, strconv.Itoa(rec.status))
+				httpDuration.Observe(time.Since(start).Seconds(), r.Method, route)
+			}()
+			next.ServeHTTP(rec, r)
+		})
+	}
+}
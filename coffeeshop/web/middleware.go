@@ -0,0 +1,178 @@
+package web
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"demo/coffeeshop/logging"
+	menu "demo/coffeeshop/menu"
+)
+
+// MARK: Middleware
+
+// middleware wraps a handler with something that should happen on every request
+type middleware func(http.Handler) http.Handler
+
+// chain applies the middleware so the first one listed is the outermost, the first to see the request
+func chain(h http.Handler, m ...middleware) http.Handler {
+	for i := len(m) - 1; i >= 0; i-- {
+		h = m[i](h)
+	}
+	return h
+}
+
+// RequestID is the ID of the request ctx belongs to, or "" outside of a request. It lives in the logging package so
+// every log line made with the request's context carries it
+func RequestID(ctx context.Context) string {
+	return logging.RequestID(ctx)
+}
+
+// withRequestID keeps the X-Request-ID a proxy in front of us gave the request, or makes one up, and sends it back
+// so a customer's complaint can be matched to our logs
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set("X-Request-ID", id)
+		next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), id)))
+	})
+}
+
+// validRequestID only lets through IDs that are safe to put in a log line and a header
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// recorder remembers the status and size of the response for the access log
+type recorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (rec *recorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *recorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the real writer, the event stream needs its Flush
+func (rec *recorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// accessLog writes one line per request once it's done
+func accessLog(logger *slog.Logger) middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &recorder{ResponseWriter: w}
+			next.ServeHTTP(rec, r)
+			if rec.status == 0 {
+				rec.status = http.StatusOK // Nothing was written, which net/http sends as an empty 200
+			}
+
+			level := slog.LevelInfo
+			if rec.status >= 500 {
+				level = slog.LevelError
+			}
+			logger.LogAttrs(r.Context(), level, "request", // request_id comes from the context
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", rec.status),
+				slog.Int("bytes", rec.bytes),
+				slog.Duration("duration", time.Since(start)),
+				slog.String("remote", r.RemoteAddr),
+			)
+		})
+	}
+}
+
+// safely runs f and turns a panic into an error, the same recover-into-a-named-return trick as divPanic in main.go
+func safely(f func()) (err error) {
+	defer func() {
+		if msg := recover(); msg != nil {
+			if msg == http.ErrAbortHandler {
+				panic(msg) // net/http uses this panic on purpose to drop the connection, let it through
+			}
+			err = fmt.Errorf("panic: %v\n%s", msg, debug.Stack())
+		}
+	}()
+	f()
+	return nil
+}
+
+// recoverPanic stops a panicking handler from taking the response down with it. The client gets a JSON 500 and the
+// details go to the log
+func recoverPanic(logger *slog.Logger) middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec, ok := w.(*recorder)
+			if !ok {
+				rec = &recorder{ResponseWriter: w}
+			}
+			err := safely(func() { next.ServeHTTP(rec, r) })
+			if err == nil {
+				return
+			}
+
+			id := RequestID(r.Context())
+			logger.ErrorContext(r.Context(), "handler panicked", "method", r.Method, "path", r.URL.Path, "error", err)
+			if rec.status != 0 {
+				return // Too late to change the status, the client will see a cut off response
+			}
+			writeJSONError(rec, http.StatusInternalServerError, errors.New("internal server error"), id)
+		})
+	}
+}
+
+// writeJSONError is the body every JSON error response uses. Validation errors list each problem as well
+func writeJSONError(w http.ResponseWriter, status int, err error, requestID string) {
+	var fields []menu.FieldError
+	var ve *menu.ValidationError
+	if errors.As(err, &ve) {
+		fields = ve.Problems
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(struct {
+		Error     string            `json:"error"`
+		Fields    []menu.FieldError `json:"fields,omitempty"`
+		RequestID string            `json:"requestId,omitempty"`
+	}{err.Error(), fields, requestID})
+}
@@ -0,0 +1,48 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+	h := chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/boom" {
+			panic("out of milk")
+		}
+		w.Write([]byte(RequestID(r.Context())))
+	}), withRequestID, accessLog(logger), recoverPanic(logger))
+
+	// An ID from upstream is kept and handed to the handler
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-ID", "abc-123")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Body.String() != "abc-123" || rec.Header().Get("X-Request-ID") != "abc-123" {
+		t.Errorf("Got body %q and header %q, expected the request ID to be passed along", rec.Body, rec.Header().Get("X-Request-ID"))
+	}
+
+	// A panic becomes a JSON 500 and the handler's caller carries on
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/boom", nil))
+	var body struct {
+		Error     string `json:"error"`
+		RequestID string `json:"requestId"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || rec.Code != http.StatusInternalServerError {
+		t.Fatalf("Got %v %q, expected a JSON 500", rec.Code, rec.Body)
+	}
+	if body.RequestID == "" || body.RequestID != rec.Header().Get("X-Request-ID") {
+		t.Errorf("Got request ID %q in the body, expected a new one matching the header", body.RequestID)
+	}
+	if !strings.Contains(logs.String(), "out of milk") || !strings.Contains(logs.String(), `"status":500`) {
+		t.Errorf("Expected the panic and a 500 access log line, got %v", logs.String())
+	}
+}
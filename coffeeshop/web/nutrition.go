@@ -0,0 +1,78 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	menu "demo/coffeeshop/menu"
+)
+
+// MARK: Nutrition
+
+// The panel looks like the nutrition label on a packet
+var panel = template.Must(template.New("panel").Funcs(template.FuncMap{"join": strings.Join}).Parse(`<section class="nutrition-panel">
+<h2>Nutrition Facts</h2>
+<p>{{.Item}} ({{.Size}}){{range .Modifiers}} + {{.}}{{end}}</p>
+<table>
+<tr><th>Calories</th><td>{{printf "%.0f" .Nutrition.Calories}}</td></tr>
+<tr><th>Fat</th><td>{{printf "%.1f" .Nutrition.Fat}}g</td></tr>
+<tr><th>Carbohydrate</th><td>{{printf "%.1f" .Nutrition.Carbs}}g</td></tr>
+<tr><th>&nbsp;&nbsp;Sugars</th><td>{{printf "%.1f" .Nutrition.Sugar}}g</td></tr>
+<tr><th>Protein</th><td>{{printf "%.1f" .Nutrition.Protein}}g</td></tr>
+</table>
+<p>Contains: {{with .Allergens}}{{join . ", "}}{{else}}none{{end}}</p>
+</section>
+`))
+
+type nutritionResponse struct {
+	Item      string         `json:"item"`
+	Size      string         `json:"size"`
+	Modifiers []string       `json:"modifiers,omitempty"`
+	Nutrition menu.Nutrition `json:"nutrition"`
+	Allergens []string       `json:"allergens"`
+	Diet      []string       `json:"diet"`
+}
+
+// NutritionHandler answers /nutrition?item=Latte&size=small&with=oat+milk with the facts for that order line, as JSON
+// or as an HTML panel (format=html or an Accept header asking for text/html). With store= it's the item as that
+// store sells it
+func NutritionHandler(w http.ResponseWriter, r *http.Request) {
+	v := r.URL.Query()
+	var mods []string
+	for _, m := range v["with"] {
+		mods = append(mods, strings.Split(m, ",")...)
+	}
+	q, err := menu.QuoteAt(v.Get("store"), v.Get("item"), v.Get("size"), mods)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if !q.HasFacts {
+		writeJSONError(w, http.StatusNotFound, fmt.Errorf("no nutrition facts for %v (%v)", q.Item, q.Size), RequestID(r.Context()))
+		return
+	}
+
+	res := nutritionResponse{
+		Item:      q.Item,
+		Size:      q.Size,
+		Modifiers: mods,
+		Nutrition: q.Nutrition,
+		Allergens: q.Allergens.Names(),
+		Diet:      q.Diet.Names(),
+	}
+	if v.Get("format") == "html" || strings.Contains(r.Header.Get("Accept"), "text/html") {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := panel.Execute(w, res); err != nil {
+			slog.ErrorContext(r.Context(), "writing the nutrition panel", "error", err)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		slog.ErrorContext(r.Context(), "writing nutrition facts", "error", err)
+	}
+}
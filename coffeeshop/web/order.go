@@ -0,0 +1,218 @@
+package web
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+
+	"demo/coffeeshop/auth"
+	menu "demo/coffeeshop/menu"
+	"demo/coffeeshop/order"
+	"demo/coffeeshop/payment"
+	"demo/coffeeshop/render"
+)
+
+// MARK: Orders
+
+// orderRequest is the body of POST /orders, the lines the customer picked
+type orderRequest struct {
+	Lines []struct {
+		Item      string   `json:"item"`
+		Size      string   `json:"size"`
+		Modifiers []string `json:"modifiers"`
+		Qty       int      `json:"qty"`
+	} `json:"lines"`
+	Payments []struct {
+		Method   string  `json:"method"`   // cash or card
+		Amount   float64 `json:"amount"`   // Left out for what's due
+		Tendered float64 `json:"tendered"` // Cash handed over
+		Card     string  `json:"card"`
+	} `json:"payments"`
+	Discount float64 `json:"discount"` // Off the whole order, only staff who can give discounts can send one
+}
+
+// OrderHandler prices an order and sends back its receipt, in whatever format the Accept header asks for. Under
+// /stores/{store}/orders it's at that store's prices. It doesn't take payments or discounts, the server's own
+// handler does
+func OrderHandler(w http.ResponseWriter, r *http.Request) {
+	takeOrder(w, r, nil)
+}
+
+// order is OrderHandler with the till, so "payments" in the body are taken before the order is placed. Sending
+// an Idempotency-Key header makes a retried request give back the same order instead of paying twice
+func (s *server) order(w http.ResponseWriter, r *http.Request) {
+	takeOrder(w, r, s)
+}
+
+// takeOrder is both handlers, s is nil for OrderHandler
+func takeOrder(w http.ResponseWriter, r *http.Request, s *server) {
+	rr, ok := renderer(w, r)
+	if !ok {
+		return
+	}
+	var req orderRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Errorf("reading order: %w", err), RequestID(r.Context()))
+		return
+	}
+	if len(req.Lines) == 0 {
+		writeJSONError(w, http.StatusUnprocessableEntity, fmt.Errorf("the order is empty"), RequestID(r.Context()))
+		return
+	}
+	var till *payment.Till
+	if s != nil {
+		till = s.till
+	}
+	if len(req.Payments) > 0 && till == nil {
+		writeJSONError(w, http.StatusUnprocessableEntity, fmt.Errorf("this server doesn't take payments"), RequestID(r.Context()))
+		return
+	}
+	if req.Discount != 0 {
+		if s == nil {
+			writeJSONError(w, http.StatusUnprocessableEntity, fmt.Errorf("this server doesn't give discounts"), RequestID(r.Context()))
+			return
+		}
+		var ok bool
+		if r, ok = s.allowed(w, r, auth.GiveDiscounts); !ok {
+			return
+		}
+	}
+
+	o := order.Order{Store: r.PathValue("store")}
+	f, err := menu.Money(o.Store)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	rr = render.WithMoney(rr, f)
+	for i, l := range req.Lines {
+		if l.Qty == 0 {
+			l.Qty = 1
+		}
+		if err := o.Add(l.Item, l.Size, l.Modifiers, l.Qty); err != nil {
+			writeError(w, r, fmt.Errorf("line %v: %w", i+1, err))
+			return
+		}
+	}
+	if req.Discount != 0 {
+		if err := o.SetDiscount(req.Discount); err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
+	if len(req.Payments) > 0 {
+		key := r.Header.Get("Idempotency-Key")
+		if key != "" {
+			o.ID = orderID(key) // The same order again, the ledger keeps the newest line for an ID
+		}
+		payments := make([]order.Payment, len(req.Payments))
+		for i, p := range req.Payments {
+			payments[i] = order.Payment{Provider: p.Method, Request: payment.Request{Amount: p.Amount, Tendered: p.Tendered, Card: p.Card, Key: key}}
+		}
+		err := o.PayInFull(r.Context(), payments, func(name string) (payment.PaymentProvider, error) {
+			return till.Provider(name, o.Store, f.Currency)
+		})
+		if serr := till.Save(); serr != nil {
+			slog.ErrorContext(r.Context(), "saving the till", "error", serr)
+		}
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
+	again := order.AlreadyPlaced(o.ID) // A retry with the same Idempotency-Key, its discount was recorded the first time
+	ctx := o.Place(r.Context())
+	if o.Discount > 0 && !again {
+		s.record(r, "order.discount", fmt.Sprintf("%v: %v off %v", o.ID, f.Amount(o.Discount), f.Amount(o.Subtotal())))
+	}
+	w.Header().Set("X-Order-ID", o.ID)
+	w.Header().Set("Content-Type", rr.ContentType())
+	if err := rr.Receipt(w, o); err != nil {
+		slog.ErrorContext(ctx, "writing the receipt", "error", err)
+	}
+}
+
+// orderID is the order ID for an idempotency key, so the same key always comes back as the same order
+func orderID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return strings.ToUpper(hex.EncodeToString(sum[:5]))
+}
+
+// ready takes an order off the barista's queue once it's made, POST /orders/{id}/ready. How long it took goes
+// in the prep time metric
+func (s *server) ready(w http.ResponseWriter, r *http.Request) {
+	o, took, err := order.Ready(strings.ToUpper(r.PathValue("id")))
+	if err != nil {
+		writeJSONError(w, http.StatusNotFound, err, RequestID(r.Context()))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"id": o.ID, "store": o.Store, "prepSeconds": math.Round(took.Seconds())})
+}
+
+// MARK: Refunds
+
+// refund gives some of an order back, POST /orders/{id}/refunds with {"amount": 2.5}. It comes off the last
+// payment first. An Idempotency-Key header makes a retry safe, without one every request is a new refund
+func (s *server) refund(w http.ResponseWriter, r *http.Request) {
+	if s.ordersFile == "" || s.till == nil {
+		writeJSONError(w, http.StatusNotFound, fmt.Errorf("this server doesn't keep an order ledger"), RequestID(r.Context()))
+		return
+	}
+	var body struct {
+		Amount float64 `json:"amount"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4<<10)).Decode(&body); err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Errorf("reading refund: %w", err), RequestID(r.Context()))
+		return
+	}
+
+	// One at a time, so two refunds for the same order can't both read it before either writes it back
+	s.refundMu.Lock()
+	defer s.refundMu.Unlock()
+	orders, err := order.LoadLedger(s.ordersFile)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	i := slices.IndexFunc(orders, func(o order.Order) bool { return strings.EqualFold(o.ID, r.PathValue("id")) })
+	if i < 0 {
+		writeJSONError(w, http.StatusNotFound, fmt.Errorf("no order %v", r.PathValue("id")), RequestID(r.Context()))
+		return
+	}
+	o := orders[i]
+	f, err := menu.Money(o.Store)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	key := r.Header.Get("Idempotency-Key")
+	if key == "" {
+		key = fmt.Sprintf("%v-refund-%v", o.ID, len(o.Refunds)+1)
+	}
+
+	before := len(o.Refunds)
+	err = o.Refund(r.Context(), body.Amount, key, func(name string) (payment.PaymentProvider, error) {
+		return s.till.Provider(name, o.Store, f.Currency)
+	})
+	if len(o.Refunds) > before {
+		// Whatever went back has to be kept, even if the rest didn't
+		if serr := errors.Join(s.till.Save(), order.Record(o)); serr != nil {
+			s.logger.ErrorContext(r.Context(), "keeping a refund", "order", o.ID, "error", serr)
+		}
+		s.record(r, "order.refund", fmt.Sprintf("%v: %v", o.ID, f.Amount(o.Refunded())))
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	render.JSON{}.Receipt(w, o)
+}
@@ -0,0 +1,137 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MARK: Rate Limiting
+
+// Rate is how fast one client can call a group of routes. They get Burst requests straight away, then one more
+// every 1/PerSecond seconds. A PerSecond of 0 turns the limit off
+type Rate struct {
+	PerSecond float64 `json:"perSecond"`
+	Burst     int     `json:"burst"`
+}
+
+// The route groups that can be limited
+const (
+	groupMenu   = "menu"   // Reading the menu, nutrition and the event stream
+	groupOrders = "orders" // Pricing orders
+	groupStaff  = "staff"  // Signing in and changing the menu, kept tight so passwords can't be guessed quickly
+)
+
+func defaultRates() map[string]Rate {
+	return map[string]Rate{
+		groupMenu:   {PerSecond: 10, Burst: 20},
+		groupOrders: {PerSecond: 2, Burst: 10},
+		groupStaff:  {PerSecond: 0.2, Burst: 5}, // One every 5 seconds
+	}
+}
+
+// bucket is one client's tokens. It's refilled lazily when the client next shows up, rather than on a timer
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// limiter is a token bucket per client for one route group
+type limiter struct {
+	rate    Rate
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+func newLimiter(rate Rate) *limiter {
+	return &limiter{rate: rate, buckets: make(map[string]*bucket)}
+}
+
+// allow takes a token from the client's bucket. If there isn't one it says how long until there will be
+func (l *limiter) allow(key string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	burst := float64(max(l.rate.Burst, 1))
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = min(burst, b.tokens+now.Sub(b.last).Seconds()*l.rate.PerSecond)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := (1 - b.tokens) / l.rate.PerSecond
+	return false, time.Duration(wait * float64(time.Second))
+}
+
+// evict drops the buckets that would be full by now. A full bucket is the same as no bucket, so nobody notices
+func (l *limiter) evict(now time.Time) {
+	full := time.Duration(float64(max(l.rate.Burst, 1)) / l.rate.PerSecond * float64(time.Second))
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= full {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// sweep evicts idle buckets every so often until ctx is done, so a scraper cycling through addresses can't
+// fill up our memory
+func (s *server) sweep(ctx context.Context, every time.Duration) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-t.C:
+			for _, l := range s.limits {
+				l.evict(now)
+			}
+		}
+	}
+}
+
+var errTooManyRequests = errors.New("too many requests, slow down")
+
+// limit puts the group's rate limit in front of next. Groups without a limit go straight through
+func (s *server) limit(group string, next http.HandlerFunc) http.HandlerFunc {
+	l, ok := s.limits[group]
+	if !ok {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		ok, wait := l.allow(s.clientKey(r), time.Now())
+		if !ok {
+			rateLimited.Inc(group)
+			// Retry-After is whole seconds, round up so they don't come back a moment too soon
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			writeJSONError(w, http.StatusTooManyRequests, errTooManyRequests, RequestID(r.Context()))
+			return
+		}
+		next(w, r)
+	}
+}
+
+// clientKey is who the request counts against: the API key if it's one we gave out, otherwise the address it came
+// from. Made up keys count as the address, or a scraper could just send a new key every time
+func (s *server) clientKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" && slices.Contains(s.apiKeys, key) {
+		return "key:" + key
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
@@ -0,0 +1,72 @@
+package web
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	l := newLimiter(Rate{PerSecond: 2, Burst: 3})
+	now := time.Unix(0, 0)
+	for i := range 3 {
+		if ok, _ := l.allow("a", now); !ok {
+			t.Fatalf("Request %v of the burst was refused", i+1)
+		}
+	}
+	ok, wait := l.allow("a", now)
+	if ok || wait != 500*time.Millisecond {
+		t.Errorf("Over the burst: got %v, %v, expected false, 500ms", ok, wait)
+	}
+	if ok, _ := l.allow("b", now); !ok {
+		t.Error("Another client should have its own bucket")
+	}
+	if ok, _ := l.allow("a", now.Add(500*time.Millisecond)); !ok {
+		t.Error("A token should have come back after 500ms")
+	}
+
+	// Refilling 3 tokens takes 1.5s, after that the buckets are as good as new and can go
+	l.evict(now.Add(time.Second))
+	if len(l.buckets) != 2 {
+		t.Errorf("Evicted too early, %v buckets left", len(l.buckets))
+	}
+	l.evict(now.Add(2 * time.Second))
+	if len(l.buckets) != 0 {
+		t.Errorf("Got %v buckets left, expected 0", len(l.buckets))
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	s := &server{logger: slog.New(slog.NewTextHandler(io.Discard, nil)), done: make(chan struct{}), apiKeys: []string{"app-123"},
+		limits: map[string]*limiter{groupMenu: newLimiter(Rate{PerSecond: 0.5, Burst: 1})}}
+	h := s.handler()
+	do := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do(""); rec.Code != http.StatusOK {
+		t.Fatalf("First request: got %v", rec.Code)
+	}
+	rec := do("")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "2" {
+		t.Errorf("Got %v with Retry-After %q, expected 429 with 2", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if rec := do("made-up"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Unknown API key: got %v, expected it to share the address's limit", rec.Code)
+	}
+	if rec := do("app-123"); rec.Code != http.StatusOK {
+		t.Errorf("Known API key: got %v, expected its own limit", rec.Code)
+	}
+	if rec := do(""); rec.Code == http.StatusOK {
+		t.Error("The address should still be over its limit")
+	}
+}
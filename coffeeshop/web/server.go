@@ -0,0 +1,266 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"demo/coffeeshop/auth"
+	"demo/coffeeshop/logging"
+	menu "demo/coffeeshop/menu"
+	"demo/coffeeshop/metrics"
+	"demo/coffeeshop/payment"
+)
+
+// MARK: Server
+
+// Config is how the web service runs. Start from DefaultConfig and change what you need
+type Config struct {
+	Addr              string
+	ReadTimeout       time.Duration // Whole request, body included
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration // Event streams switch this off for themselves
+	IdleTimeout       time.Duration // Keep-alive connections with nothing happening
+	MaxHeaderBytes    int
+	ShutdownTimeout   time.Duration   // How long in-flight requests get to finish once we're asked to stop
+	Logger            *slog.Logger    // Access logs and panics go here
+	MenuFile          string          // Where changes made over HTTP are saved, empty to keep them in memory
+	Users             *auth.Store     // Staff who can sign in to change things, nil means nobody can
+	SessionTTL        time.Duration   // How long a sign in lasts
+	RateLimits        map[string]Rate // Per route group: menu, orders and staff
+	APIKeys           []string        // Keys given to apps, each gets its own rate limit instead of sharing its address's
+	OrdersFile        string          // The order ledger the reports read, empty for no reports
+	Till              *payment.Till   // Takes the payments sent with orders, nil to not take any
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Addr:              "localhost:3000",
+		ReadTimeout:       10 * time.Second,
+		ReadHeaderTimeout: 5 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+		MaxHeaderBytes:    64 << 10, // 64KB, plenty for a menu API
+		ShutdownTimeout:   20 * time.Second,
+		Logger:            slog.Default(),
+		SessionTTL:        12 * time.Hour, // A long shift
+		RateLimits:        defaultRates(),
+	}
+}
+
+// fileConfig is the JSON config file. Durations are written like "10s" and anything left out keeps its value
+type fileConfig struct {
+	Addr              *string         `json:"addr"`
+	ReadTimeout       *string         `json:"readTimeout"`
+	ReadHeaderTimeout *string         `json:"readHeaderTimeout"`
+	WriteTimeout      *string         `json:"writeTimeout"`
+	IdleTimeout       *string         `json:"idleTimeout"`
+	MaxHeaderBytes    *int            `json:"maxHeaderBytes"`
+	ShutdownTimeout   *string         `json:"shutdownTimeout"`
+	SessionTTL        *string         `json:"sessionTTL"`
+	RateLimits        map[string]Rate `json:"rateLimits"` // Only the groups given are changed
+	APIKeys           *[]string       `json:"apiKeys"`
+}
+
+// LoadConfig reads a JSON config file over the top of c
+func LoadConfig(path string, c *Config) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var f fileConfig
+	if err := json.Unmarshal(b, &f); err != nil {
+		return fmt.Errorf("reading %v: %w", path, err)
+	}
+
+	if f.Addr != nil {
+		c.Addr = *f.Addr
+	}
+	if f.MaxHeaderBytes != nil {
+		c.MaxHeaderBytes = *f.MaxHeaderBytes
+	}
+	if f.APIKeys != nil {
+		c.APIKeys = *f.APIKeys
+	}
+	if len(f.RateLimits) > 0 {
+		rates := maps.Clone(c.RateLimits)
+		if rates == nil {
+			rates = make(map[string]Rate)
+		}
+		for group, rate := range f.RateLimits {
+			if _, ok := defaultRates()[group]; !ok {
+				return fmt.Errorf("reading %v: rateLimits: unknown route group %q", path, group)
+			}
+			if rate.PerSecond < 0 || rate.Burst < 0 {
+				return fmt.Errorf("reading %v: rateLimits: %v can't be negative", path, group)
+			}
+			rates[group] = rate
+		}
+		c.RateLimits = rates
+	}
+	durations := []struct {
+		name string
+		from *string
+		to   *time.Duration
+	}{
+		{"readTimeout", f.ReadTimeout, &c.ReadTimeout},
+		{"readHeaderTimeout", f.ReadHeaderTimeout, &c.ReadHeaderTimeout},
+		{"writeTimeout", f.WriteTimeout, &c.WriteTimeout},
+		{"idleTimeout", f.IdleTimeout, &c.IdleTimeout},
+		{"shutdownTimeout", f.ShutdownTimeout, &c.ShutdownTimeout},
+		{"sessionTTL", f.SessionTTL, &c.SessionTTL},
+	}
+	for _, d := range durations {
+		if d.from == nil {
+			continue
+		}
+		v, err := time.ParseDuration(*d.from)
+		if err != nil {
+			return fmt.Errorf("reading %v: %v: %w", path, d.name, err)
+		}
+		*d.to = v
+	}
+	return nil
+}
+
+// server is the state shared by the handlers that care about the server's life cycle
+type server struct {
+	logger     *slog.Logger
+	draining   atomic.Bool   // Set once shutdown starts, so load balancers stop sending us traffic
+	done       chan struct{} // Closed once shutdown starts, event streams watch it so they can finish
+	users      *auth.Store
+	sessionTTL time.Duration
+	menuFile   string
+	limits     map[string]*limiter // Only the groups that are limited
+	apiKeys    []string
+	ordersFile string
+	till       *payment.Till // nil when the server doesn't take payments
+	refundMu   sync.Mutex
+}
+
+// handler is every route with the middleware every request goes through
+func (s *server) handler() http.Handler {
+	mux := s.routes()
+	return chain(mux,
+		withRequestID,
+		accessLog(s.logger),
+		instrument(mux),
+		recoverPanic(s.logger),
+	)
+}
+
+func (s *server) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.limit(groupMenu, Handler))
+	mux.HandleFunc("GET /items/{item}", s.limit(groupMenu, ItemHandler))
+	mux.HandleFunc("/nutrition", s.limit(groupMenu, NutritionHandler))
+	mux.HandleFunc("GET /menu.csv", s.limit(groupMenu, exportCSV))
+	mux.HandleFunc("GET /events", s.limit(groupMenu, s.events))
+	mux.HandleFunc("POST /orders", s.limit(groupOrders, s.order))
+	mux.HandleFunc("GET /stores", s.limit(groupMenu, listStores))
+	mux.HandleFunc("GET /stores/{store}/menu", s.limit(groupMenu, Handler))
+	mux.HandleFunc("GET /stores/{store}/items/{item}", s.limit(groupMenu, ItemHandler))
+	mux.HandleFunc("POST /stores/{store}/orders", s.limit(groupOrders, s.order))
+	mux.HandleFunc("GET /healthz", s.healthz)
+	mux.HandleFunc("GET /readyz", s.readyz)
+	mux.Handle("GET /metrics", metrics.Handler()) // Left open like the health checks, Prometheus doesn't sign in
+
+	// Everything that changes the menu needs someone signed in with the right role
+	mux.HandleFunc("POST /login", s.limit(groupStaff, s.login))
+	mux.HandleFunc("POST /logout", s.logout)
+	mux.HandleFunc("POST /items", s.limit(groupStaff, s.require(auth.EditMenu, s.addItem)))
+	mux.HandleFunc("PUT /items/{item}/prices/{size}", s.limit(groupStaff, s.require(auth.ChangePrices, s.setPrice)))
+	mux.HandleFunc("PUT /items/{item}/name", s.limit(groupStaff, s.require(auth.EditMenu, s.rename)))
+	mux.HandleFunc("POST /menu.csv", s.limit(groupStaff, s.require(auth.EditMenu, s.require(auth.ChangePrices, s.importCSV)))) // It can do both
+	mux.HandleFunc("PUT /stores/{store}/items/{item}/prices/{size}", s.limit(groupStaff, s.require(auth.ChangePrices, s.setStorePrice)))
+	mux.HandleFunc("DELETE /stores/{store}/items/{item}/prices/{size}", s.limit(groupStaff, s.require(auth.ChangePrices, s.setStorePrice)))
+	mux.HandleFunc("POST /orders/{id}/refunds", s.limit(groupStaff, s.require(auth.Refund, s.refund)))
+	mux.HandleFunc("GET /drawer", s.limit(groupStaff, s.require(auth.CountDrawer, s.drawerStatus)))
+	mux.HandleFunc("POST /drawer/close", s.limit(groupStaff, s.require(auth.CountDrawer, s.closeDrawer)))
+	mux.HandleFunc("POST /drawer/{move}", s.limit(groupStaff, s.require(auth.CountDrawer, s.drawerMove)))
+	mux.HandleFunc("GET /reports/drawers", s.limit(groupStaff, s.require(auth.ViewReports, s.drawersReport)))
+	mux.HandleFunc("GET /reports/stores", s.limit(groupStaff, s.require(auth.ViewReports, s.storesReport)))
+	mux.HandleFunc("GET /reports/sales", s.limit(groupStaff, s.require(auth.ViewReports, s.salesReport)))
+	mux.HandleFunc("POST /orders/{id}/ready", s.limit(groupStaff, s.require(auth.MakeDrinks, s.ready)))
+	return mux
+}
+
+// healthz says the process is up and answering, that's all
+func (s *server) healthz(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprintln(w, "ok")
+}
+
+// readyz says whether we should be sent traffic: the menu is loaded and we're not shutting down
+func (s *server) readyz(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case s.draining.Load():
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+	case !menu.Loaded():
+		http.Error(w, "menu not loaded", http.StatusServiceUnavailable)
+	default:
+		fmt.Fprintln(w, "ok")
+	}
+}
+
+// Serve runs the web service until ctx is done, then stops taking new connections and gives the requests
+// and event streams in flight up to ShutdownTimeout to finish
+func Serve(ctx context.Context, cfg Config) error {
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	cfg.Logger = slog.New(logging.Correlate(cfg.Logger.Handler())) // So every line about a request has its ID
+	if cfg.Users == nil {
+		cfg.Users = auth.NewStore(nil)
+	}
+	s := &server{logger: cfg.Logger, done: make(chan struct{}), users: cfg.Users, sessionTTL: cfg.SessionTTL, menuFile: cfg.MenuFile,
+		limits: make(map[string]*limiter), apiKeys: cfg.APIKeys, ordersFile: cfg.OrdersFile, till: cfg.Till}
+	for group, rate := range cfg.RateLimits {
+		if rate.PerSecond > 0 {
+			s.limits[group] = newLimiter(rate)
+		}
+	}
+	go s.sweep(ctx, time.Minute)
+	srv := &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           s.handler(),
+		ErrorLog:          slog.NewLogLogger(cfg.Logger.Handler(), slog.LevelWarn), // net/http's own complaints (bad TLS, header too big...)
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+	}
+	// Shutdown doesn't wait for long lived responses by itself, so tell the event streams to wrap up
+	srv.RegisterOnShutdown(func() { close(s.done) })
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errc:
+		return err // Couldn't start, the address is probably in use
+	case <-ctx.Done():
+	}
+
+	s.draining.Store(true)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		srv.Close() // Out of time, cut off whatever is left
+		return fmt.Errorf("shutting down: %w", err)
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	menu "demo/coffeeshop/menu"
+	"demo/coffeeshop/order"
+	"demo/coffeeshop/report"
+)
+
+// MARK: Stores
+
+// listStores sends back every store, GET /stores. Their menus are at /stores/{store}/menu
+func listStores(w http.ResponseWriter, r *http.Request) {
+	type store struct {
+		ID       string `json:"id"`
+		Name     string `json:"name"`
+		Currency string `json:"currency,omitempty"`
+		Locale   string `json:"locale,omitempty"`
+		Menu     string `json:"menu"`
+	}
+	out := []store{}
+	for _, s := range menu.Stores() {
+		f, _ := menu.Money(s.ID)
+		out = append(out, store{s.ID, s.Name, f.Currency.Code, f.Locale.Tag, "/stores/" + s.ID + "/menu"})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// setStorePrice changes what a size costs at one store, PUT /stores/{store}/items/{item}/prices/{size} with
+// {"price": 3.5}. DELETE puts it back to the base price
+func (s *server) setStorePrice(w http.ResponseWriter, r *http.Request) {
+	store, name, size := r.PathValue("store"), r.PathValue("item"), r.PathValue("size")
+	var detail string
+	if r.Method == http.MethodDelete {
+		if err := menu.ClearStorePrice(store, name, size); err != nil {
+			writeError(w, r, err)
+			return
+		}
+		detail = fmt.Sprintf("%v: %v %v back to the base price", store, name, size)
+	} else {
+		var body struct {
+			Price float64 `json:"price"`
+		}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4<<10)).Decode(&body); err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Errorf("reading price: %w", err), RequestID(r.Context()))
+			return
+		}
+		old, err := menu.SetStorePrice(store, name, size, body.Price)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		f, _ := menu.Money(store)
+		detail = fmt.Sprintf("%v: %v %v: %v -> %v", store, name, size, f.Amount(old), f.Amount(body.Price))
+	}
+	if err := s.save(); err != nil {
+		s.logger.ErrorContext(r.Context(), "saving the menu", "error", err)
+	}
+	s.record(r, "store.price", detail)
+
+	item, err := menu.LookupAt(store, name)
+	if err != nil {
+		writeError(w, r, err) // Only if it isn't sold there, the price is still saved
+		return
+	}
+	writeJSON(w, http.StatusOK, item)
+}
+
+// storesReport adds up the orders in the ledger for each store and all of them, GET /reports/stores.
+// ?store= (repeatable) narrows it down
+func (s *server) storesReport(w http.ResponseWriter, r *http.Request) {
+	if s.ordersFile == "" {
+		writeJSONError(w, http.StatusNotFound, fmt.Errorf("this server doesn't keep an order ledger"), RequestID(r.Context()))
+		return
+	}
+	orders, err := order.LoadLedger(s.ordersFile)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report.Stores(orders, r.URL.Query()["store"]...))
+}
+
+// salesReport is what sold, GET /reports/sales. ?store= (repeatable), ?from= and ?to= (days like 2026-10-19, to
+// taking in the whole day) narrow it down, and ?format=csv gives a spreadsheet instead of JSON
+func (s *server) salesReport(w http.ResponseWriter, r *http.Request) {
+	if s.ordersFile == "" {
+		writeJSONError(w, http.StatusNotFound, fmt.Errorf("this server doesn't keep an order ledger"), RequestID(r.Context()))
+		return
+	}
+	q := r.URL.Query()
+	f := report.Filter{Stores: q["store"]}
+	if err := f.Days(q.Get("from"), q.Get("to")); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err, RequestID(r.Context()))
+		return
+	}
+	orders, err := order.LoadLedger(s.ordersFile)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	sales := report.Sales(orders, f)
+	if q.Get("format") == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		sales.WriteCSV(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, sales)
+}
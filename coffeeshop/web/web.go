@@ -0,0 +1,137 @@
+package web
+
+import (
+	"cmp"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"demo/coffeeshop/i18n"
+	menu "demo/coffeeshop/menu"
+	"demo/coffeeshop/render"
+)
+
+// MARK: Coffee Shop Web Service
+
+// Handler sends back the current menu, or one store's menu under /stores/{store}/menu. Query parameters narrow
+// it down: q (search text), min and max (price), size, available=true, tag (repeatable) and exclude (allergens).
+// The format comes from the Accept header, or format= to override it
+func Handler(w http.ResponseWriter, r *http.Request) {
+	rr, ok := renderer(w, r)
+	if !ok {
+		return
+	}
+	q, err := parseQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q.Store = r.PathValue("store") // "" on routes without one, the base menu
+	f, err := menu.Money(q.Store)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	rr = render.WithMoney(rr, f)
+	lang := language(r)
+	if notModified(w, r, rr.ContentType(), lang) {
+		return
+	}
+	w.Header().Set("Content-Type", rr.ContentType())
+	rr.Menu(w, menu.Localize(menu.Search(q), lang))
+}
+
+// ItemHandler sends back a single item, /items/{item} where item is its ID or name. Under /stores/{store} it's
+// the item as that store sells it
+func ItemHandler(w http.ResponseWriter, r *http.Request) {
+	rr, ok := renderer(w, r)
+	if !ok {
+		return
+	}
+	item, err := menu.LookupAt(r.PathValue("store"), r.PathValue("item"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	f, _ := menu.Money(r.PathValue("store")) // LookupAt found the store
+	rr = render.WithMoney(rr, f)
+	lang := language(r)
+	if notModified(w, r, rr.ContentType(), lang) {
+		return
+	}
+	w.Header().Set("Content-Type", rr.ContentType())
+	rr.Item(w, menu.Localize([]menu.Item{item}, lang)[0])
+}
+
+// language is what the menu should be in: the best the menu has of ?lang= if it's given, or else of what the
+// Accept-Language header asks for. Anything the menu isn't translated into gets the shop's own language
+func language(r *http.Request) string {
+	want := r.URL.Query().Get("lang")
+	if want == "" {
+		want = r.Header.Get("Accept-Language")
+	}
+	return cmp.Or(i18n.Match(want, menu.Languages()), i18n.Default)
+}
+
+// renderer picks the output format for the request. If there isn't one it has already answered the request
+func renderer(w http.ResponseWriter, r *http.Request) (render.Renderer, bool) {
+	if f := r.URL.Query().Get("format"); f != "" {
+		rr, err := render.Format(f)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return nil, false
+		}
+		return rr, true
+	}
+	rr, ok := render.Negotiate(r.Header.Get("Accept"))
+	if !ok {
+		http.Error(w, "can't produce any of "+r.Header.Get("Accept"), http.StatusNotAcceptable)
+		return nil, false
+	}
+	return rr, true
+}
+
+func parseQuery(v url.Values) (menu.Query, error) {
+	q := menu.Query{
+		Text: v.Get("q"),
+		Size: v.Get("size"),
+		Tags: v["tag"],
+	}
+	var err error
+	if s := v.Get("min"); s != "" {
+		if q.MinPrice, err = strconv.ParseFloat(s, 64); err != nil {
+			return q, &paramError{"min", s}
+		}
+	}
+	if s := v.Get("max"); s != "" {
+		if q.MaxPrice, err = strconv.ParseFloat(s, 64); err != nil {
+			return q, &paramError{"max", s}
+		}
+	}
+	if s := v.Get("available"); s != "" {
+		if q.AvailableOnly, err = strconv.ParseBool(s); err != nil {
+			return q, &paramError{"available", s}
+		}
+	}
+	if s := v.Get("exclude"); s != "" {
+		if q.Exclude, err = menu.ParseAllergens(s); err != nil {
+			return q, &paramError{"exclude", s}
+		}
+	}
+	// Allow tag=vegan,gluten-free as well as repeating the parameter
+	var tags []string
+	for _, t := range q.Tags {
+		tags = append(tags, strings.Split(t, ",")...)
+	}
+	q.Tags = tags
+	return q, nil
+}
+
+type paramError struct {
+	name, value string
+}
+
+func (e *paramError) Error() string {
+	return "invalid value " + strconv.Quote(e.value) + " for " + e.name
+}
@@ -0,0 +1,247 @@
+package web
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	menu "demo/coffeeshop/menu"
+	"demo/coffeeshop/money"
+	"demo/coffeeshop/payment"
+)
+
+func get(t *testing.T, url string, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	Handler(rec, req)
+	return rec
+}
+
+func TestHandlerNegotiates(t *testing.T) {
+	tests := map[string]string{
+		"":                 "text/plain; charset=utf-8",
+		"application/json": "application/json",
+		"text/html":        "text/html; charset=utf-8",
+	}
+	for accept, expect := range tests {
+		rec := get(t, "/", map[string]string{"Accept": accept})
+		if got := rec.Header().Get("Content-Type"); got != expect {
+			t.Errorf("Accept %q: got %q, expected %q", accept, got, expect)
+		}
+	}
+
+	if rec := get(t, "/", map[string]string{"Accept": "image/png"}); rec.Code != http.StatusNotAcceptable {
+		t.Errorf("Got %v, expected 406", rec.Code)
+	}
+}
+
+func TestHandlerConditional(t *testing.T) {
+	first := get(t, "/", nil)
+	tag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || tag == "" {
+		t.Fatalf("Got %v with ETag %q, expected 200 with an ETag", first.Code, tag)
+	}
+
+	if rec := get(t, "/", map[string]string{"If-None-Match": tag}); rec.Code != http.StatusNotModified {
+		t.Errorf("If-None-Match: got %v, expected 304", rec.Code)
+	}
+	if rec := get(t, "/", map[string]string{"If-Modified-Since": first.Header().Get("Last-Modified")}); rec.Code != http.StatusNotModified {
+		t.Errorf("If-Modified-Since: got %v, expected 304", rec.Code)
+	}
+
+	// A different format is a different body, so the ETag can't match
+	if rec := get(t, "/", map[string]string{"If-None-Match": tag, "Accept": "application/json"}); rec.Code != http.StatusOK {
+		t.Errorf("Other format: got %v, expected 200", rec.Code)
+	}
+}
+
+func TestHandlerLanguage(t *testing.T) {
+	if err := menu.SetTranslation("Coffee", "es", "Café solo", ""); err != nil {
+		t.Fatal(err)
+	}
+	defer menu.SetTranslation("Coffee", "es", "", "")
+
+	tests := []struct {
+		url, accept, expect, lang string
+	}{
+		{"/", "", "Coffee", "en"},
+		{"/", "es-MX, en;q=0.5", "Café solo", "es"},
+		{"/", "de, en;q=0.5", "Coffee", "en"},
+		{"/?lang=es", "en", "Café solo", "es"}, // The parameter wins
+	}
+	tags := map[string]string{}
+	for _, tt := range tests {
+		rec := get(t, tt.url, map[string]string{"Accept-Language": tt.accept})
+		if !strings.Contains(rec.Body.String(), tt.expect) || rec.Header().Get("Content-Language") != tt.lang {
+			t.Errorf("%v %q: got %v in\n%v", tt.url, tt.accept, rec.Header().Get("Content-Language"), rec.Body)
+		}
+		tags[tt.lang] = rec.Header().Get("ETag")
+	}
+	if tags["en"] == tags["es"] {
+		t.Error("Expected the languages to have different ETags")
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	s := &server{done: make(chan struct{})}
+	check := func(expect int) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		s.readyz(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		if rec.Code != expect {
+			t.Errorf("Got %v, expected %v", rec.Code, expect)
+		}
+	}
+
+	if err := menu.Load(filepath.Join(t.TempDir(), "menu.json")); err != nil { // No file yet, so the built in menu
+		t.Fatal(err)
+	}
+	check(http.StatusOK)
+	s.draining.Store(true)
+	check(http.StatusServiceUnavailable)
+}
+
+func TestMetrics(t *testing.T) {
+	s := &server{logger: slog.New(slog.NewTextHandler(io.Discard, nil)), done: make(chan struct{})}
+	h := s.handler()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items/Coffee", nil))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+	for _, expect := range []string{
+		`coffeeshop_http_requests_total{method="GET",route="GET /items/{item}",code="200"} `,
+		`coffeeshop_http_request_duration_seconds_bucket{method="GET",route="GET /items/{item}",le="+Inf"} `,
+		"# TYPE coffeeshop_menu_items gauge",
+	} {
+		if !strings.Contains(body, expect) {
+			t.Errorf("Missing %q in\n%v", expect, body)
+		}
+	}
+}
+
+func TestNutrition(t *testing.T) {
+	tests := []struct {
+		url         string
+		contentType string
+		expect      []string
+	}{
+		{"/nutrition?item=Coffee&size=small&with=whole+milk", "application/json", []string{`"calories":39`, `"modifiers":["whole milk"]`, `"allergens":["milk"]`}},
+		{"/nutrition?item=Coffee&size=small&with=whole+milk&format=html", "text/html; charset=utf-8",
+			[]string{"<h2>Nutrition Facts</h2>", "<p>Coffee (small) + whole milk</p>", "<th>Calories</th><td>39</td>", "Contains: milk"}},
+	}
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		NutritionHandler(rec, httptest.NewRequest(http.MethodGet, test.url, nil))
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != test.contentType {
+			t.Errorf("%v: got %v %v", test.url, rec.Code, rec.Header().Get("Content-Type"))
+		}
+		for _, expect := range test.expect {
+			if !strings.Contains(rec.Body.String(), expect) {
+				t.Errorf("%v: missing %q in\n%v", test.url, expect, rec.Body)
+			}
+		}
+	}
+}
+
+func TestErrorStatus(t *testing.T) {
+	if err := menu.Add("Status Test Brew", map[string]float64{"small": 2}, menu.Details{SoldOut: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := menu.AddStore("status-test", "Status Test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := menu.SetSoldAt("status-test", "Coffee", false); err != nil {
+		t.Fatal(err)
+	}
+	s := &server{logger: slog.New(slog.NewTextHandler(io.Discard, nil)), done: make(chan struct{})}
+	h := s.handler()
+	tests := []struct {
+		method, url, body string
+		expect            int
+	}{
+		{"GET", "/items/Flat%20White", "", http.StatusNotFound},
+		{"GET", "/nutrition?item=Coffee&size=huge", "", http.StatusUnprocessableEntity},
+		{"GET", "/nutrition?item=Status+Test+Brew&size=small", "", http.StatusNotFound}, // No facts
+		{"GET", "/nutrition?item=Coffee&size=small&store=status-test", "", http.StatusNotFound},
+		{"POST", "/orders", `{"lines":[{"item":"Status Test Brew","size":"small"}]}`, http.StatusConflict},
+		{"POST", "/orders", `{"lines":[{"item":"Coffee","size":"small"}]}`, http.StatusOK},
+		{"GET", "/stores/nowhere/menu", "", http.StatusNotFound},
+		{"POST", "/stores/nowhere/orders", `{"lines":[{"item":"Coffee","size":"small"}]}`, http.StatusNotFound},
+		{"GET", "/stores/status-test/menu", "", http.StatusOK},
+		{"GET", "/stores/status-test/items/Coffee", "", http.StatusNotFound}, // Not sold there
+	}
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(test.method, test.url, strings.NewReader(test.body)))
+		if rec.Code != test.expect {
+			t.Errorf("%v %v: got %v, expected %v: %v", test.method, test.url, rec.Code, test.expect, rec.Body)
+		}
+		if rec.Code >= 400 && !strings.HasPrefix(rec.Body.String(), `{"error":`) {
+			t.Errorf("%v %v: got %q, expected a JSON error", test.method, test.url, rec.Body)
+		}
+	}
+}
+
+func TestOrderPayments(t *testing.T) {
+	till, _ := payment.OpenTill("")
+	till.Cash("", money.Currency{}).Open(0, "")
+	s := &server{logger: slog.New(slog.NewTextHandler(io.Discard, nil)), done: make(chan struct{}), till: till}
+	h := s.handler()
+	post := func(body, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+		req.Header.Set("Accept", "application/json")
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	declined := `{"lines":[{"item":"Coffee","size":"small"}],"payments":[{"method":"card","card":"` + payment.CardDeclined + `"}]}`
+	if rec := post(declined, ""); rec.Code != http.StatusPaymentRequired {
+		t.Errorf("Declined: got %v %v", rec.Code, rec.Body)
+	}
+
+	// A dollar in cash and the rest on a card, sent twice with the same key: one order, paid and counted once
+	counted := func() string {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		var lines []string
+		for _, l := range strings.Split(rec.Body.String(), "\n") {
+			if strings.HasPrefix(l, "coffeeshop_orders_total") || strings.HasPrefix(l, "coffeeshop_order_value_count") ||
+				strings.HasPrefix(l, `coffeeshop_items_ordered_total{store="",item="Coffee",size="small"}`) {
+				lines = append(lines, l)
+			}
+		}
+		return strings.Join(lines, "\n")
+	}
+	split := `{"lines":[{"item":"Coffee","size":"small"}],"payments":[{"method":"cash","tendered":1},{"method":"card","card":"` + payment.CardApproved + `"}]}`
+	first := post(split, "till-1-0042")
+	before := counted()
+	again := post(split, "till-1-0042")
+	if first.Code != http.StatusOK || again.Header().Get("X-Order-ID") != first.Header().Get("X-Order-ID") {
+		t.Errorf("Got %v %v, then order %v", first.Code, first.Body, again.Header().Get("X-Order-ID"))
+	}
+	if again.Body.String() != first.Body.String() {
+		t.Errorf("The retry's receipt is different:\n%v\nthen\n%v", first.Body, again.Body)
+	}
+	if after := counted(); after != before || before == "" {
+		t.Errorf("The retry was counted again:\n%v\nthen\n%v", before, after)
+	}
+	if d := till.Cash("", money.Currency{}).Drawer(); d != 1 {
+		t.Errorf("Drawer: got %v, expected 1", d)
+	}
+	if !strings.Contains(first.Body.String(), `"tenders"`) {
+		t.Errorf("Receipt without the tenders: %v", first.Body)
+	}
+}
@@ -10,25 +10,27 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
+	"os/signal"
 	"slices"
 	"strings"
 	"sync"
 
 	// Adding my own package
 	"demo/coffeeshop"
+	"demo/coffeeshop/web"
 )
 
 // MARK: Main
 // Entry point is determined by a function called main
+// The greetings now live in the shell command (coffeeshop/commands.go): println is a built in print function, but isn't what we usually
+// are going to use (good for keeping less dependencies, debugging), fmt.Println is the imported version we typically want to use
 func main() {
-	println("Hello, Gophers!")     // This is a built in print function, but isn't what we usually are going to use (good for keeping less dependencies, debugging)
-	fmt.Println("Hello, Gophers!") // This is the imported version we typically want to use, for formatted strings and such
-	coffeeshop.Operate()
+	os.Exit(coffeeshop.Run(os.Args[1:])) // Run picks the subcommand, with no arguments it starts the interactive loop like before
 }
 
 // MARK: Simple Data Types
@@ -126,15 +128,24 @@ func mod4() {
 	fmt.Println(st + "!")
 
 	// Module 4 Web Service
-	http.HandleFunc("/", Handler)              // Register the function as the back controller
-	http.ListenAndServe("localhost:3000", nil) // Start the web service to be listening, normally you need to give the IP and the port (local host can be assumed). Nil is the second arg because Go will provide the front handler for us
+	// The first version registered Handler as the back controller with http.HandleFunc("/", Handler) and started listening with
+	// http.ListenAndServe("localhost:3000", nil). Normally you need to give the IP and the port (local host can be assumed), and nil
+	// meant Go provided the front handler for us. That server had no timeouts and no way to stop it nicely, so now we use the
+	// coffee shop's server, which stops when we press Ctrl-C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := web.Serve(ctx, web.DefaultConfig()); err != nil {
+		fmt.Println(err)
+	}
 
 }
 
 // Module 4 Web Service (this is the back controller)
+// It used to copy menu.txt straight into the response: f, _ := os.Open("./menu.txt") opens a file for reading (again 2 returned
+// variables, file object and error) and io.Copy(w, f) copies from a read source (like a file) to a write source.
+// Now it hands over to the coffee shop's menu handler, which picks text, HTML or JSON and sets the caching headers
 func Handler(w http.ResponseWriter, r *http.Request) {
-	f, _ := os.Open("./menu.txt") // Open reads a file for reading, again 2 returned variables (file object and error)
-	io.Copy(w, f)                 // Copy lets us copy from a read source (like a file) to a write source
+	web.Handler(w, r)
 }
 
 // MARK: Aggregate Data Types